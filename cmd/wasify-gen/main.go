@@ -0,0 +1,124 @@
+// Command wasify-gen generates the host and guest code of a contract, so their signatures can't drift apart.
+//
+// The contract is a Go file declaring the interface of the host functions and the interface of the guest functions:
+//
+//	package contract
+//
+//	type Celsius float64
+//
+//	type Host interface {
+//		Greet(ctx context.Context, name string) (string, error)
+//	}
+//
+//	type Guest interface {
+//		Fahrenheit(c Celsius) (float64, error)
+//	}
+//
+// Methods take and return []byte, byte, uint32, uint64, float32, float64 and string, or types declared in the contract based on them,
+// and return an error as their last result. Methods of the host interface may also take a context.Context as their first param.
+//
+// The host code has HostFunctions, which turns an implementation of the host interface into wasify host functions,
+// and GuestClient, which invokes the guest functions. The guest code has HostClient, which implements the host interface
+// by calling the host functions, and ExportGuest, which exports an implementation of the guest interface.
+//
+// Usage:
+//
+//	//go:generate go run github.com/wasify-io/wasify-go/cmd/wasify-gen -namespace greeter -host ../host/wasify_gen.go -guest ../guest/wasify_gen.go contract.go
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/wasify-io/wasify-go/internal/codegen"
+)
+
+func main() {
+
+	var (
+		namespace      = flag.String("namespace", "", "namespace the guest imports the host functions from, the ModuleConfig.Namespace of the module (required)")
+		hostInterface  = flag.String("host-interface", "Host", "name of the interface of the host functions")
+		guestInterface = flag.String("guest-interface", "Guest", "name of the interface of the guest functions")
+		importPath     = flag.String("import", "", "import path of the contract package (default: resolved from go.mod)")
+		hostOut        = flag.String("host", "", "output file of the host code")
+		hostPackage    = flag.String("host-package", "", "package name of the host code (default: the name of the output directory)")
+		guestOut       = flag.String("guest", "", "output file of the guest code")
+		guestPackage   = flag.String("guest-package", "main", "package name of the guest code")
+	)
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: wasify-gen -namespace name [-host file] [-guest file] [flags] contract.go\n\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 1 || *namespace == "" || (*hostOut == "" && *guestOut == "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	err := run(flag.Arg(0), codegen.Config{
+		Namespace:      *namespace,
+		HostInterface:  *hostInterface,
+		GuestInterface: *guestInterface,
+		ImportPath:     *importPath,
+	}, *hostOut, *hostPackage, *guestOut, *guestPackage)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wasify-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(contractFile string, config codegen.Config, hostOut, hostPackage, guestOut, guestPackage string) error {
+
+	contract, err := codegen.ParseFile(contractFile, config)
+	if err != nil {
+		return err
+	}
+
+	if hostOut != "" {
+		if hostPackage == "" {
+			hostPackage, err = packageName(hostOut)
+			if err != nil {
+				return err
+			}
+		}
+
+		src, err := codegen.GenerateHost(contract, hostPackage)
+		if err != nil {
+			return errors.Join(errors.New("can't generate the host code"), err)
+		}
+
+		if err := os.WriteFile(hostOut, src, 0o644); err != nil {
+			return err
+		}
+	}
+
+	if guestOut != "" {
+		src, err := codegen.GenerateGuest(contract, guestPackage)
+		if err != nil {
+			return errors.Join(errors.New("can't generate the guest code"), err)
+		}
+
+		if err := os.WriteFile(guestOut, src, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// packageName returns the name of the directory of the file, as the package name.
+func packageName(file string) (string, error) {
+
+	dir, err := filepath.Abs(filepath.Dir(file))
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Base(dir), nil
+}
@@ -0,0 +1,105 @@
+package wasify_test
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go"
+	"github.com/wasify-io/wasify-go/testdata/wasm/codegen/contract"
+	"github.com/wasify-io/wasify-go/testdata/wasm/codegen/host"
+)
+
+//go:embed testdata/wasm/codegen/guest/main.wasm
+var wasm_codegen []byte
+
+// codegenHost implements the host interface of the codegen contract.
+type codegenHost struct {
+	recorded []byte
+}
+
+func (h *codegenHost) Greet(ctx context.Context, name string, times uint32) (string, error) {
+	if name == "" {
+		return "", errors.New("name is empty")
+	}
+
+	greeting := ""
+	for i := uint32(0); i < times; i++ {
+		greeting += "Hello, " + name + "! "
+	}
+
+	return greeting, nil
+}
+
+func (h *codegenHost) Record(data []byte) error {
+	h.recorded = append(h.recorded, data...)
+	return nil
+}
+
+func (h *codegenHost) UnixTime() (uint64, error) {
+	return 1700000000, nil
+}
+
+// TestCodegen runs a guest generated by wasify-gen with the generated host code.
+func TestCodegen(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+		Runtime:     wasify.RuntimeWazero,
+		LogSeverity: wasify.LogError,
+	})
+	assert.NoError(t, err)
+	defer runtime.Close(ctx)
+
+	impl := &codegenHost{}
+
+	module, err := runtime.NewModule(ctx, &wasify.ModuleConfig{
+		Namespace: host.Namespace,
+		Wasm: wasify.Wasm{
+			Binary: wasm_codegen,
+		},
+		HostFunctions: host.HostFunctions(impl),
+	})
+	assert.NoError(t, err)
+	defer module.Close(ctx)
+
+	guest := host.GuestClient{Module: module}
+
+	f, c, err := guest.Fahrenheit(ctx, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(212), f)
+	assert.Equal(t, contract.Celsius(100), c)
+
+	welcome, err := guest.Welcome(ctx, "Wasify")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello, Wasify! Hello, Wasify! (1700000000)", welcome)
+
+	assert.NoError(t, guest.Store(ctx, []byte("data")))
+	assert.Equal(t, []byte("data"), impl.recorded)
+
+	reversed, err := guest.Reverse(ctx, []byte{1, 2, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{3, 2, 1}, reversed)
+
+	t.Run("guest error", func(t *testing.T) {
+		_, err := guest.Reverse(ctx, nil)
+
+		var guestErr *wasify.GuestError
+		if assert.ErrorAs(t, err, &guestErr) {
+			assert.Equal(t, "no data", guestErr.Message)
+		}
+	})
+
+	t.Run("host error", func(t *testing.T) {
+		_, err := guest.Welcome(ctx, "")
+
+		var trap *wasify.Trap
+		if assert.ErrorAs(t, err, &trap) {
+			assert.Equal(t, "greet", trap.Function)
+			assert.EqualError(t, errors.Unwrap(trap.Err), "name is empty")
+		}
+	})
+}
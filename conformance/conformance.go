@@ -0,0 +1,346 @@
+// Package conformance tests that a guest implements the wasify wire protocol, see PROTOCOL.md.
+//
+// Every guest SDK builds a conformance fixture, a guest exporting the functions listed in the
+// Conformance section of PROTOCOL.md, and runs it on the host from a Go test:
+//
+//	func TestConformance(t *testing.T) {
+//		wasm, err := os.ReadFile("testdata/conformance.wasm")
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//
+//		conformance.Run(t, wasm)
+//	}
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/wasify-io/wasify-go"
+)
+
+// Exports are the functions a conformance fixture must export.
+var Exports = []wasify.FunctionDefinition{
+	{Name: "wasify_protocol_version", Results: []wasify.WasmValueType{i32}},
+	{Name: "wasify_malloc", Params: []wasify.WasmValueType{i32}, Results: []wasify.WasmValueType{i32}},
+	{Name: "wasify_free", Params: []wasify.WasmValueType{i32}},
+	{Name: "echo", Params: []wasify.WasmValueType{i64, i64, i64, i64, i64, i64, i64}, Results: []wasify.WasmValueType{i64}},
+	{Name: "read_string", Params: []wasify.WasmValueType{i64}, Results: []wasify.WasmValueType{i64}},
+	{Name: "log_message", Params: []wasify.WasmValueType{i64, i64}},
+}
+
+const (
+	i32 = wasify.WasmValueTypeI32
+	i64 = wasify.WasmValueTypeI64
+)
+
+// Run runs the conformance suite against the guest, each check is a subtest of t.
+func Run(t *testing.T, wasm []byte) {
+
+	t.Helper()
+
+	ctx := context.Background()
+
+	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+		Runtime:     wasify.RuntimeWazero,
+		LogSeverity: wasify.LogError,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer runtime.Close(ctx)
+
+	logs := &recorder{}
+
+	module, err := runtime.NewModule(ctx, &wasify.ModuleConfig{
+		Namespace:   "conformance",
+		Wasm:        wasify.Wasm{Binary: wasm},
+		LogSeverity: wasify.LogDebug,
+		LogHandler:  logs,
+
+		// The packs the guest writes must have the types the protocol specifies.
+		TypeValidation: wasify.TypeValidationStrict,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer module.Close(ctx)
+
+	s := &suite{ctx, module, logs}
+
+	// The other checks call the exports, they can't run without them.
+	if !t.Run("exports", s.exports) {
+		return
+	}
+
+	t.Run("protocol version", s.protocolVersion)
+	t.Run("allocator", s.allocator)
+	t.Run("echo", s.echo)
+	t.Run("type mismatch", s.typeMismatch)
+	t.Run("log", s.log)
+}
+
+type suite struct {
+	ctx    context.Context
+	module wasify.Module
+	logs   *recorder
+}
+
+func (s *suite) exports(t *testing.T) {
+
+	exported := make(map[string]wasify.FunctionDefinition)
+	for _, fn := range s.module.ExportedFunctions() {
+		exported[fn.Name] = fn
+	}
+
+	for _, expected := range Exports {
+		fn, ok := exported[expected.Name]
+		if !ok {
+			t.Errorf("%s is not exported", expected.Name)
+			continue
+		}
+
+		if fn.Signature() != expected.Signature() {
+			t.Errorf("%s has the signature %s, expected %s", fn.Name, fn.Signature(), expected.Signature())
+		}
+	}
+}
+
+func (s *suite) protocolVersion(t *testing.T) {
+
+	if v := s.module.ProtocolVersion(); v != wasify.ProtocolVersion {
+		t.Errorf("the guest implements protocol version %d, expected %d", v, wasify.ProtocolVersion)
+	}
+}
+
+// allocator checks the blocks returned by wasify_malloc are distinct, even for a zero size, and 8-byte aligned.
+func (s *suite) allocator(t *testing.T) {
+
+	memory := s.module.Memory()
+
+	var offsets []uint32
+
+	for _, size := range []uint32{0, 0, 1, 13, 64, 1 << 16} {
+		offset, err := memory.Malloc(size)
+		if err != nil {
+			t.Fatalf("can't allocate %d bytes: %v", size, err)
+		}
+
+		switch {
+		case offset == 0:
+			t.Errorf("allocating %d bytes returned offset 0", size)
+		case offset%8 != 0:
+			t.Errorf("allocating %d bytes returned offset %d, which isn't 8-byte aligned", size, offset)
+		case slices.Contains(offsets, offset):
+			t.Errorf("allocating %d bytes returned offset %d, which is already allocated", size, offset)
+		}
+
+		offsets = append(offsets, offset)
+	}
+
+	if err := memory.Free(offsets...); err != nil {
+		t.Errorf("can't free: %v", err)
+	}
+}
+
+// echo checks values of every ValueType are read and written back unchanged.
+func (s *suite) echo(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		params []any
+	}{
+		{
+			name:   "values",
+			params: []any{[]byte("bytes!"), byte(1), uint32(32), uint64(64), float32(32.5), float64(64.01), "Wasify"},
+		},
+		{
+			name:   "zero values",
+			params: []any{[]byte{}, byte(0), uint32(0), uint64(0), float32(0), float64(0), ""},
+		},
+		{
+			name: "limits",
+			params: []any{
+				bytes.Repeat([]byte{0xff}, 1<<16),
+				byte(math.MaxUint8),
+				uint32(math.MaxUint32),
+				uint64(math.MaxUint64),
+				float32(-math.MaxFloat32),
+				math.SmallestNonzeroFloat64,
+				"Wasify 🚀 ✓",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Repeat the call, so values written into memory freed by the previous call are checked too.
+			for i := 0; i < 3; i++ {
+				results := s.invoke(t, "echo", tt.params...)
+
+				if len(results) != len(tt.params) {
+					t.Fatalf("got %d results, expected %d", len(results), len(tt.params))
+				}
+
+				for j := range results {
+					if !equal(results[j], tt.params[j]) {
+						t.Errorf("result %d is %s, expected %s", j, format(results[j]), format(tt.params[j]))
+					}
+				}
+			}
+		})
+	}
+}
+
+// typeMismatch checks the guest detects a pack of an unexpected type, and reports it as the host does.
+func (s *suite) typeMismatch(t *testing.T) {
+
+	tests := []struct {
+		param    any
+		expected string
+	}{
+		{"Wasify", "Wasify"},
+		{[]byte("Wasify"), "expected ValueTypeString, got ValueTypeBytes"},
+		{byte(1), "expected ValueTypeString, got ValueTypeByte"},
+		{uint32(1), "expected ValueTypeString, got ValueTypeI32"},
+		{uint64(1), "expected ValueTypeString, got ValueTypeI64"},
+		{float32(1), "expected ValueTypeString, got ValueTypeF32"},
+		{float64(1), "expected ValueTypeString, got ValueTypeF64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%T", tt.param), func(t *testing.T) {
+			results := s.invoke(t, "read_string", tt.param)
+
+			if len(results) != 1 || results[0] != tt.expected {
+				t.Errorf("got %v, expected [%s]", results, tt.expected)
+			}
+		})
+	}
+}
+
+// log checks the guest logs through the wasify.log host function.
+func (s *suite) log(t *testing.T) {
+
+	levels := map[wasify.LogSeverity]slog.Level{
+		wasify.LogDebug:   slog.LevelDebug,
+		wasify.LogInfo:    slog.LevelInfo,
+		wasify.LogWarning: slog.LevelWarn,
+		wasify.LogError:   slog.LevelError,
+	}
+
+	for severity, level := range levels {
+		msg := fmt.Sprintf("conformance log %s", level)
+
+		// log_message has no results.
+		if _, err := s.module.GuestFunction(s.ctx, "log_message").Invoke(msg, byte(severity)); err != nil {
+			t.Fatalf("can't invoke log_message: %v", err)
+		}
+
+		if !s.logs.contains(level, msg) {
+			t.Errorf("%q hasn't been logged at level %s", msg, level)
+		}
+	}
+}
+
+// invoke invokes the guest function and returns the values of its results, which are freed.
+func (s *suite) invoke(t *testing.T, name string, params ...any) []any {
+
+	t.Helper()
+
+	res, err := s.module.GuestFunction(s.ctx, name).Invoke(params...)
+	if err != nil {
+		t.Fatalf("can't invoke %s: %v", name, err)
+	}
+
+	pds, err := res.ReadPacks()
+	if err != nil {
+		t.Fatalf("can't read the results of %s: %v", name, err)
+	}
+
+	memory := s.module.Memory()
+
+	values := make([]any, len(pds))
+	for i, pd := range pds {
+		values[i], _, _, err = memory.ReadAnyPack(pd)
+		if err != nil {
+			t.Fatalf("can't read result %d of %s: %v", i, name, err)
+		}
+	}
+
+	if err := memory.FreePack(pds...); err != nil {
+		t.Fatalf("can't free the results of %s: %v", name, err)
+	}
+
+	return values
+}
+
+// equal compares values bit for bit, so empty and nil bytes are equal but floats of different bits aren't, e.g. 0 and -0.
+func equal(a, b any) bool {
+
+	switch a := a.(type) {
+	case []byte:
+		b, ok := b.([]byte)
+		return ok && bytes.Equal(a, b)
+	case float32:
+		b, ok := b.(float32)
+		return ok && math.Float32bits(a) == math.Float32bits(b)
+	case float64:
+		b, ok := b.(float64)
+		return ok && math.Float64bits(a) == math.Float64bits(b)
+	}
+
+	return a == b
+}
+
+func format(v any) string {
+
+	if b, ok := v.([]byte); ok && len(b) > 16 {
+		return fmt.Sprintf("[]byte(%x...) of %d bytes", b[:16], len(b))
+	}
+
+	return fmt.Sprintf("%T(%v)", v, v)
+}
+
+// recorder is a slog.Handler keeping the records logged by the module.
+type recorder struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (r *recorder) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (r *recorder) Handle(_ context.Context, record slog.Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records = append(r.records, record)
+
+	return nil
+}
+
+func (r *recorder) WithAttrs([]slog.Attr) slog.Handler {
+	return r
+}
+
+func (r *recorder) WithGroup(string) slog.Handler {
+	return r
+}
+
+func (r *recorder) contains(level slog.Level, msg string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.ContainsFunc(r.records, func(record slog.Record) bool {
+		return record.Level == level && record.Message == msg
+	})
+}
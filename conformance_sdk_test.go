@@ -0,0 +1,17 @@
+//go:build conformance
+
+package wasify_test
+
+// The fixtures of the SDKs which aren't written in Go are built with their own toolchains,
+// see testdata/wasm/conformance/build.sh, and run once built with:
+//
+//	go test -tags conformance -run TestConformance .
+//
+// A fixture moves to conformanceFixtures once its main.wasm is committed.
+func init() {
+	conformanceFixtures = append(conformanceFixtures,
+		conformanceFixture{"rust", "Cargo.toml"},
+		conformanceFixture{"c", "main.c"},
+		conformanceFixture{"assemblyscript", "assembly/index.ts"},
+	)
+}
@@ -0,0 +1,41 @@
+package wasify_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wasify-io/wasify-go/conformance"
+)
+
+// conformanceFixture is the conformance fixture of a guest SDK, in testdata/wasm/conformance.
+// Its build instructions are in its source file.
+type conformanceFixture struct {
+	name   string
+	source string
+}
+
+// conformanceFixtures are the fixtures TestConformance runs. The Go fixture is built with the Go toolchain,
+// the fixtures of the other SDKs need their own toolchains and run with the conformance build tag, see conformance_sdk_test.go.
+var conformanceFixtures = []conformanceFixture{
+	{"go", "main.go"},
+}
+
+// TestConformance runs the conformance suite against the fixture of every guest SDK.
+// A fixture which isn't built fails the test.
+func TestConformance(t *testing.T) {
+
+	for _, fixture := range conformanceFixtures {
+		t.Run(fixture.name, func(t *testing.T) {
+
+			dir := filepath.Join("testdata", "wasm", "conformance", fixture.name)
+
+			wasm, err := os.ReadFile(filepath.Join(dir, "main.wasm"))
+			if err != nil {
+				t.Fatalf("can't read the fixture, build it as described in %s: %v", filepath.Join(dir, fixture.source), err)
+			}
+
+			conformance.Run(t, wasm)
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package wasify
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/wasify-io/wasify-go/internal/types"
+	"github.com/wasify-io/wasify-go/internal/utils"
+)
+
+// errCopiedMemoryReadOnly is returned by the write and allocation methods of a copiedMemory.
+var errCopiedMemoryReadOnly = errors.New("the results have been copied out of the module, their memory is read-only")
+
+// copiedMemory is a read-only Memory holding a copy of the results of a guest function call,
+// so they can be read after the module they come from has been closed, see ReloadableModule.
+//
+// Packs keep their ValueType and size, but their offsets point into the copy.
+// Freeing them does nothing, the copy is released by the garbage collector.
+type copiedMemory struct {
+	data []byte
+
+	typeValidation TypeValidation
+	typeWarnings   typeWarnings
+	log            *slog.Logger
+}
+
+// copyResult copies the results of res out of memory and frees them there.
+// The returned GuestFunctionResult reads them from a copiedMemory.
+func copyResult(res *GuestFunctionResult, typeValidation TypeValidation, log *slog.Logger) (*GuestFunctionResult, error) {
+
+	// Offset 0 is never used by a pack.
+	m := &copiedMemory{data: make([]byte, 8), typeValidation: typeValidation, log: log}
+
+	if res.multiPackedData == 0 {
+		return &GuestFunctionResult{memory: m}, nil
+	}
+
+	pds, err := res.ReadPacks()
+	if err != nil {
+		return nil, err
+	}
+	defer res.memory.FreePack(pds...)
+
+	copied := make([]uint64, len(pds))
+	for i, pd := range pds {
+		if pd == 0 {
+			continue
+		}
+
+		valueType, offset, size := utils.UnpackUI64(uint64(pd))
+
+		data, err := res.memory.ReadBytes(offset, size)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("can't copy result %d", i), err)
+		}
+
+		copied[i], err = utils.PackUI64(valueType, m.append(data), size)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	size := uint32(len(copied)) * 8
+	mpd, err := utils.PackUI64(types.ValueTypePack, m.append(utils.Uint64ArrayToBytes(copied)), size)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GuestFunctionResult{multiPackedData: mpd, memory: m}, nil
+}
+
+// append copies data at the end of the memory and returns its offset.
+func (m *copiedMemory) append(data []byte) uint32 {
+	offset := uint32(len(m.data))
+	m.data = append(m.data, data...)
+
+	return offset
+}
+
+// read returns size bytes at offset, they are the copy itself.
+func (m *copiedMemory) read(offset uint32, size uint32, name string) ([]byte, error) {
+	if uint64(offset)+uint64(size) > uint64(len(m.data)) {
+		err := fmt.Errorf("Memory.%s(%d, %d) out of range of memory size %d", name, offset, size, m.Size())
+		m.log.Error(err.Error())
+		return nil, err
+	}
+
+	return m.data[offset : offset+size], nil
+}
+
+// unpack unpacks pd and checks its ValueType against the expected one, see ModuleConfig.TypeValidation.
+func (m *copiedMemory) unpack(pd PackedData, expected ValueType) (uint32, uint32, error) {
+
+	valueType, offset, size := utils.UnpackUI64(uint64(pd))
+
+	err := m.typeWarnings.check(m.typeValidation, m.log, "", ValueType(valueType), expected)
+	if err != nil {
+		m.log.Error(err.Error())
+		return 0, 0, err
+	}
+
+	return offset, size, nil
+}
+
+func (m *copiedMemory) ReadBytes(offset uint32, size uint32) ([]byte, error) {
+	return m.read(offset, size, "ReadBytes")
+}
+
+func (m *copiedMemory) ReadByte(offset uint32) (byte, error) {
+	buf, err := m.read(offset, 1, "ReadByte")
+	if err != nil {
+		return 0, err
+	}
+
+	return buf[0], nil
+}
+
+func (m *copiedMemory) ReadUint32(offset uint32) (uint32, error) {
+	buf, err := m.read(offset, 4, "ReadUint32")
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint32(buf), nil
+}
+
+func (m *copiedMemory) ReadUint64(offset uint32) (uint64, error) {
+	buf, err := m.read(offset, 8, "ReadUint64")
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint64(buf), nil
+}
+
+func (m *copiedMemory) ReadFloat32(offset uint32) (float32, error) {
+	data, err := m.ReadUint32(offset)
+	if err != nil {
+		return 0, err
+	}
+
+	return math.Float32frombits(data), nil
+}
+
+func (m *copiedMemory) ReadFloat64(offset uint32) (float64, error) {
+	data, err := m.ReadUint64(offset)
+	if err != nil {
+		return 0, err
+	}
+
+	return math.Float64frombits(data), nil
+}
+
+func (m *copiedMemory) ReadString(offset uint32, size uint32) (string, error) {
+	buf, err := m.ReadBytes(offset, size)
+	if err != nil {
+		return "", err
+	}
+
+	return string(buf), nil
+}
+
+func (m *copiedMemory) ReadAnyPack(pd PackedData) (any, uint32, uint32, error) {
+
+	var err error
+	var data any
+
+	valueType, offset, size := utils.UnpackUI64(uint64(pd))
+
+	switch ValueType(valueType) {
+	case ValueTypeBytes:
+		data, err = m.ReadBytes(offset, size)
+	case ValueTypeByte:
+		data, err = m.ReadByte(offset)
+	case ValueTypeI32:
+		data, err = m.ReadUint32(offset)
+	case ValueTypeI64:
+		data, err = m.ReadUint64(offset)
+	case ValueTypeF32:
+		data, err = m.ReadFloat32(offset)
+	case ValueTypeF64:
+		data, err = m.ReadFloat64(offset)
+	case ValueTypeString:
+		data, err = m.ReadString(offset, size)
+	default:
+		err = fmt.Errorf("Unsupported read data type %s", valueType)
+	}
+
+	if err != nil {
+		m.log.Error(err.Error())
+		return nil, 0, 0, err
+	}
+
+	return data, offset, size, nil
+}
+
+func (m *copiedMemory) ReadBytesPack(pd PackedData) ([]byte, error) {
+	offset, size, err := m.unpack(pd, ValueTypeBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.ReadBytes(offset, size)
+}
+
+func (m *copiedMemory) ReadBytePack(pd PackedData) (byte, error) {
+	offset, _, err := m.unpack(pd, ValueTypeByte)
+	if err != nil {
+		return 0, err
+	}
+
+	return m.ReadByte(offset)
+}
+
+func (m *copiedMemory) ReadUint32Pack(pd PackedData) (uint32, error) {
+	offset, _, err := m.unpack(pd, ValueTypeI32)
+	if err != nil {
+		return 0, err
+	}
+
+	return m.ReadUint32(offset)
+}
+
+func (m *copiedMemory) ReadUint64Pack(pd PackedData) (uint64, error) {
+	offset, _, err := m.unpack(pd, ValueTypeI64)
+	if err != nil {
+		return 0, err
+	}
+
+	return m.ReadUint64(offset)
+}
+
+func (m *copiedMemory) ReadFloat32Pack(pd PackedData) (float32, error) {
+	offset, _, err := m.unpack(pd, ValueTypeF32)
+	if err != nil {
+		return 0, err
+	}
+
+	return m.ReadFloat32(offset)
+}
+
+func (m *copiedMemory) ReadFloat64Pack(pd PackedData) (float64, error) {
+	offset, _, err := m.unpack(pd, ValueTypeF64)
+	if err != nil {
+		return 0, err
+	}
+
+	return m.ReadFloat64(offset)
+}
+
+func (m *copiedMemory) ReadStringPack(pd PackedData) (string, error) {
+	offset, size, err := m.unpack(pd, ValueTypeString)
+	if err != nil {
+		return "", err
+	}
+
+	return m.ReadString(offset, size)
+}
+
+func (m *copiedMemory) WriteAny(offset uint32, v any) error         { return errCopiedMemoryReadOnly }
+func (m *copiedMemory) WriteBytes(offset uint32, v []byte) error    { return errCopiedMemoryReadOnly }
+func (m *copiedMemory) WriteByte(offset uint32, v byte) error       { return errCopiedMemoryReadOnly }
+func (m *copiedMemory) WriteUint32(offset uint32, v uint32) error   { return errCopiedMemoryReadOnly }
+func (m *copiedMemory) WriteUint64(offset uint32, v uint64) error   { return errCopiedMemoryReadOnly }
+func (m *copiedMemory) WriteFloat32(offset uint32, v float32) error { return errCopiedMemoryReadOnly }
+func (m *copiedMemory) WriteFloat64(offset uint32, v float64) error { return errCopiedMemoryReadOnly }
+func (m *copiedMemory) WriteString(offset uint32, v string) error   { return errCopiedMemoryReadOnly }
+
+// writePack logs that the pack can't be written and returns no data, like the Write*Pack methods of a module memory on errors.
+func (m *copiedMemory) writePack() PackedData {
+	m.log.Error(errCopiedMemoryReadOnly.Error())
+	return 0
+}
+
+func (m *copiedMemory) WriteBytesPack(v []byte) PackedData    { return m.writePack() }
+func (m *copiedMemory) WriteBytePack(v byte) PackedData       { return m.writePack() }
+func (m *copiedMemory) WriteUint32Pack(v uint32) PackedData   { return m.writePack() }
+func (m *copiedMemory) WriteUint64Pack(v uint64) PackedData   { return m.writePack() }
+func (m *copiedMemory) WriteFloat32Pack(v float32) PackedData { return m.writePack() }
+func (m *copiedMemory) WriteFloat64Pack(v float64) PackedData { return m.writePack() }
+func (m *copiedMemory) WriteStringPack(v string) PackedData   { return m.writePack() }
+
+func (m *copiedMemory) WriteMultiPack(...PackedData) MultiPackedData {
+	return MultiPackedData(m.writePack())
+}
+
+// FreePack does nothing, the copy is released by the garbage collector.
+func (m *copiedMemory) FreePack(...PackedData) error { return nil }
+
+// Free does nothing, the copy is released by the garbage collector.
+func (m *copiedMemory) Free(...uint32) error { return nil }
+
+func (m *copiedMemory) Size() uint32 {
+	return uint32(len(m.data))
+}
+
+func (m *copiedMemory) Malloc(size uint32) (uint32, error) {
+	return 0, errCopiedMemoryReadOnly
+}
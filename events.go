@@ -0,0 +1,304 @@
+package wasify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// eventHandlerName is the export the host delivers events to, see mdk.Subscribe.
+const eventHandlerName = "wasify_event"
+
+// defaultEventQueueSize is the number of events queued for a module if ModuleConfig.EventQueueSize isn't specified.
+const defaultEventQueueSize = 64
+
+// EventBus delivers the events the host publishes to the modules whose guest subscribed to them with mdk.Subscribe.
+// Set it as ModuleConfig.Events of the modules which receive events, one bus can serve many modules.
+//
+// Every module has its own queue of events and delivers them one at a time, in the order they've been published,
+// by invoking the handler the guest registered for the topic of the event. A module which is slow to handle its events
+// doesn't delay the other modules, until its queue is full: Publish then waits for the module to make room, see Publish.
+//
+// Delivering an event is a guest function call, so guest function calls of a module with an EventBus are serialized
+// with the delivery of its events. Host functions which invoke guest functions of their own module must use the ctx
+// they are called with.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers []*eventSubscriber
+}
+
+// NewEventBus returns an EventBus without modules.
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Publish queues the event for every module subscribed to topic. It doesn't wait for the event to be delivered.
+//
+// If the queue of a module is full, Publish blocks until the module delivered an event, or ctx is done.
+// In the latter case it returns the error of ctx, the event may have been queued for some modules already.
+// If ctx is the one of a call of the module, e.g. a host function called by its event handler publishes,
+// Publish fails right away instead, the module can't deliver events before the call returns.
+// payload is copied, so it can be reused once Publish returns.
+func (b *EventBus) Publish(ctx context.Context, topic string, payload []byte) error {
+
+	b.mu.RLock()
+	subscribers := slices.Clone(b.subscribers)
+	b.mu.RUnlock()
+
+	e := event{topic, bytes.Clone(payload)}
+
+	for _, s := range subscribers {
+		if !s.subscribed(topic) {
+			continue
+		}
+
+		// Waiting for a module from one of its own calls would deadlock.
+		if ctx.Value(eventsCallKey{s}) != nil {
+			select {
+			case s.queue <- e:
+				continue
+			default:
+				return fmt.Errorf("can't publish event %s to module %s from one of its calls, its queue is full", topic, s.namespace)
+			}
+		}
+
+		select {
+		case s.queue <- e:
+		case <-s.stop:
+			// The module has been closed meanwhile.
+		case <-ctx.Done():
+			return errors.Join(fmt.Errorf("can't publish event %s to module %s, its queue is full", topic, s.namespace), ctx.Err())
+		}
+	}
+
+	return nil
+}
+
+func (b *EventBus) attach(s *eventSubscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers = append(b.subscribers, s)
+}
+
+func (b *EventBus) detach(s *eventSubscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers = slices.DeleteFunc(b.subscribers, func(other *eventSubscriber) bool { return other == s })
+}
+
+type event struct {
+	topic   string
+	payload []byte
+}
+
+// eventSubscriber is the subscription of a module to an EventBus: the topics its guest subscribed to,
+// and the queue of events it delivers.
+type eventSubscriber struct {
+	bus       *EventBus
+	namespace string
+	log       *slog.Logger
+
+	mu     sync.Mutex
+	topics map[string]struct{}
+
+	queue chan event
+
+	// calls serializes the guest function calls of the module with the delivery of events, see enter.
+	calls sync.Mutex
+
+	// stop is closed when the module is closed, done once the last event has been delivered.
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	started  bool
+}
+
+func newEventSubscriber(moduleConfig *ModuleConfig) *eventSubscriber {
+
+	size := moduleConfig.EventQueueSize
+	if size <= 0 {
+		size = defaultEventQueueSize
+	}
+
+	return &eventSubscriber{
+		bus:       moduleConfig.Events,
+		namespace: moduleConfig.Namespace,
+		log:       moduleConfig.log,
+		topics:    make(map[string]struct{}),
+		queue:     make(chan event, size),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// eventsCallKey marks the context of a guest function call of the module of an eventSubscriber,
+// so the calls nested in it, e.g. by a host function, don't wait for it.
+type eventsCallKey struct {
+	s *eventSubscriber
+}
+
+// enter waits for the running guest function call of the module, unless ctx belongs to it, e.g. a host function calls the guest back.
+// It returns the context of the new call and the function ending it.
+// It doesn't wait without an EventBus, guest function calls aren't serialized then.
+func (s *eventSubscriber) enter(ctx context.Context) (context.Context, func()) {
+
+	if s == nil || ctx.Value(eventsCallKey{s}) != nil {
+		return ctx, func() {}
+	}
+
+	s.calls.Lock()
+
+	return context.WithValue(ctx, eventsCallKey{s}, true), s.calls.Unlock
+}
+
+func (s *eventSubscriber) subscribe(topic string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.topics[topic] = struct{}{}
+}
+
+func (s *eventSubscriber) unsubscribe(topic string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.topics, topic)
+}
+
+func (s *eventSubscriber) subscribed(topic string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.topics[topic]
+
+	return ok
+}
+
+// subscriptions returns the sorted topics the module is subscribed to.
+func (s *eventSubscriber) subscriptions() []string {
+
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var topics []string
+	for topic := range s.topics {
+		topics = append(topics, topic)
+	}
+	slices.Sort(topics)
+
+	return topics
+}
+
+// setSubscriptions replaces the topics the module is subscribed to, e.g. by the ones of a snapshot.
+func (s *eventSubscriber) setSubscriptions(topics []string) {
+
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.topics)
+	for _, topic := range topics {
+		s.topics[topic] = struct{}{}
+	}
+}
+
+// start attaches the module to the bus and delivers its events until close.
+func (s *eventSubscriber) start(ctx context.Context, module Module) {
+
+	if s == nil {
+		return
+	}
+
+	s.bus.attach(s)
+	s.started = true
+
+	go func() {
+		defer close(s.done)
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case e := <-s.queue:
+				s.deliver(ctx, module, e)
+			}
+		}
+	}()
+}
+
+// close detaches the module from the bus, and waits for the delivery of the current event.
+// The events left in the queue are dropped.
+func (s *eventSubscriber) close() {
+
+	if s == nil {
+		return
+	}
+
+	s.bus.detach(s)
+	s.stopOnce.Do(func() { close(s.stop) })
+
+	if s.started {
+		<-s.done
+	}
+}
+
+// deliver invokes the event handler of the guest. Failures are logged, they don't stop the delivery of the next events.
+func (s *eventSubscriber) deliver(ctx context.Context, module Module, e event) {
+
+	// The guest may have unsubscribed since the event has been queued.
+	if !s.subscribed(e.topic) {
+		return
+	}
+
+	err := s.handle(ctx, module, e)
+	if err != nil {
+		s.log.WarnContext(ctx, "can't deliver event", "topic", e.topic, "err", err)
+	}
+}
+
+// handle invokes the event handler of the guest, which returns a MultiPackedData of a string pack of its error, or nothing.
+func (s *eventSubscriber) handle(ctx context.Context, module Module, e event) error {
+
+	res, err := module.GuestFunction(ctx, eventHandlerName).Invoke(e.topic, e.payload)
+	if err != nil {
+		return err
+	}
+
+	if res.multiPackedData == 0 {
+		return nil
+	}
+
+	pds, err := res.ReadPacks()
+	if err != nil {
+		return err
+	}
+	defer res.memory.FreePack(pds...)
+
+	if len(pds) != 1 {
+		return fmt.Errorf("invalid %s results, expected 1 pack, got %d", eventHandlerName, len(pds))
+	}
+
+	msg, err := res.memory.ReadStringPack(pds[0])
+	if err != nil {
+		return errors.Join(fmt.Errorf("invalid %s error", eventHandlerName), err)
+	}
+
+	return &GuestError{
+		Namespace: s.namespace,
+		Function:  eventHandlerName,
+		Message:   msg,
+	}
+}
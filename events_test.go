@@ -0,0 +1,233 @@
+package wasify_test
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go"
+)
+
+//go:embed testdata/wasm/mdk_events/main.wasm
+var wasm_mdkEvents []byte
+
+func TestEventBus(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+		Runtime:     wasify.RuntimeWazero,
+		LogSeverity: wasify.LogError,
+	})
+	assert.NoError(t, err)
+	defer runtime.Close(ctx)
+
+	// handled receives the events as the guest handles them, "topic: payload".
+	handled := make(chan string, 100)
+
+	// Events with the "block" payload are handled once unblock is closed.
+	unblock := make(chan struct{})
+
+	bus := wasify.NewEventBus()
+
+	// Events with the "republish" payload publish events to the module while it handles them, until Publish fails.
+	republished := make(chan error, 1)
+
+	module, err := runtime.NewModule(ctx, &wasify.ModuleConfig{
+		Namespace: "mdk_events",
+		Wasm: wasify.Wasm{
+			Binary: wasm_mdkEvents,
+		},
+		Events:          bus,
+		EventQueueSize:  2,
+		EnableSnapshots: true,
+		EnableReset:     true,
+		HostFunctions: []wasify.HostFunction{
+			{
+				Name: "handled",
+				Callback: func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {
+					topic, _ := m.Memory.ReadStringPack(params[0])
+					payload, _ := m.Memory.ReadBytesPack(params[1])
+
+					handled <- fmt.Sprintf("%s: %s", topic, payload)
+					switch string(payload) {
+					case "block":
+						<-unblock
+					case "republish":
+						for i := 1; ; i++ {
+							err := bus.Publish(ctx, topic, []byte(fmt.Sprint(i)))
+							if err != nil {
+								republished <- err
+								break
+							}
+						}
+					}
+
+					return 0
+				},
+				Params: []wasify.ValueType{wasify.ValueTypeString, wasify.ValueTypeBytes},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	defer module.Close(ctx)
+
+	// next returns the next event handled by the guest.
+	next := func(t *testing.T) string {
+		select {
+		case e := <-handled:
+			return e
+		case <-time.After(10 * time.Second):
+			t.Fatal("no event has been handled")
+			return ""
+		}
+	}
+
+	publish := func(t *testing.T, topic string, payload string) {
+		assert.NoError(t, bus.Publish(ctx, topic, []byte(payload)))
+	}
+
+	invoke := func(t *testing.T, name string, params ...any) {
+		_, err := module.GuestFunction(ctx, name).Invoke(params...)
+		assert.NoError(t, err)
+	}
+
+	t.Run("ordering", func(t *testing.T) {
+
+		// The queue holds 2 events, so Publish waits for the guest most of the time.
+		for i := 0; i < 50; i++ {
+			publish(t, "greeting", fmt.Sprint(i))
+		}
+
+		for i := 0; i < 50; i++ {
+			assert.Equal(t, fmt.Sprintf("greeting: %d", i), next(t))
+		}
+	})
+
+	t.Run("subscriptions", func(t *testing.T) {
+
+		publish(t, "farewell", "ignored")
+
+		invoke(t, "subscribe", "farewell")
+		publish(t, "farewell", "bye")
+		assert.Equal(t, "farewell: bye", next(t))
+
+		invoke(t, "unsubscribe", "farewell")
+		publish(t, "farewell", "ignored")
+		publish(t, "greeting", "hello")
+		assert.Equal(t, "greeting: hello", next(t))
+	})
+
+	t.Run("reset", func(t *testing.T) {
+
+		invoke(t, "subscribe", "farewell")
+
+		snapshot, err := module.Snapshot()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"farewell", "greeting"}, snapshot.Topics)
+
+		// The guest is back to the subscriptions it made at instantiation.
+		assert.NoError(t, module.Reset(ctx))
+
+		publish(t, "farewell", "ignored")
+		publish(t, "greeting", "hello")
+		assert.Equal(t, "greeting: hello", next(t))
+	})
+
+	t.Run("handler error", func(t *testing.T) {
+
+		// The error is logged, the next events are delivered.
+		publish(t, "greeting", "invalid")
+		publish(t, "greeting", "hello")
+		assert.Equal(t, "greeting: hello", next(t))
+	})
+
+	t.Run("backpressure", func(t *testing.T) {
+
+		publish(t, "greeting", "block")
+		assert.Equal(t, "greeting: block", next(t))
+
+		// The guest is blocked, the queue fills up.
+		publish(t, "greeting", "1")
+		publish(t, "greeting", "2")
+
+		timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		err := bus.Publish(timeout, "greeting", []byte("3"))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		close(unblock)
+		assert.Equal(t, "greeting: 1", next(t))
+		assert.Equal(t, "greeting: 2", next(t))
+	})
+
+	t.Run("publish from handler", func(t *testing.T) {
+
+		publish(t, "greeting", "republish")
+		assert.Equal(t, "greeting: republish", next(t))
+
+		// The queue of the module is full, Publish fails instead of waiting for the handler which calls it.
+		select {
+		case err := <-republished:
+			assert.ErrorContains(t, err, "can't publish event greeting to module mdk_events from one of its calls")
+		case <-time.After(10 * time.Second):
+			t.Fatal("Publish is blocked")
+		}
+
+		assert.Equal(t, "greeting: 1", next(t))
+		assert.Equal(t, "greeting: 2", next(t))
+	})
+
+	t.Run("without event bus", func(t *testing.T) {
+
+		runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+			Runtime:     wasify.RuntimeWazero,
+			LogSeverity: wasify.LogError,
+		})
+		assert.NoError(t, err)
+		defer runtime.Close(ctx)
+
+		module, err := runtime.NewModule(ctx, &wasify.ModuleConfig{
+			Namespace: "mdk_events",
+			Wasm: wasify.Wasm{
+				Binary: wasm_mdkEvents,
+			},
+			HostFunctions: []wasify.HostFunction{
+				{
+					Name: "handled",
+					Callback: func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {
+						return 0
+					},
+					Params: []wasify.ValueType{wasify.ValueTypeString, wasify.ValueTypeBytes},
+				},
+			},
+		})
+		assert.NoError(t, err)
+		defer module.Close(ctx)
+
+		_, err = module.GuestFunction(ctx, "subscribe").Invoke("farewell")
+
+		var guestErr *wasify.GuestError
+		if assert.ErrorAs(t, err, &guestErr) {
+			assert.Equal(t, "module mdk_events has no event bus, can't subscribe to farewell", guestErr.Message)
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+
+		assert.NoError(t, module.Close(ctx))
+
+		// Closed modules are detached from the bus.
+		publish(t, "greeting", "ignored")
+
+		select {
+		case e := <-handled:
+			t.Errorf("%q has been handled by a closed module", e)
+		case <-time.After(10 * time.Millisecond):
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package wasify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/wasify-io/wasify-go/internal/types"
+	"github.com/wasify-io/wasify-go/internal/utils"
+)
+
+// AsyncHostFunctionCallback is the function signature for the callback executed by an async host function.
+//
+// It runs on the guest thread, like a HostFunctionCallback, so it must read the params it needs before it returns,
+// the guest frees them afterwards. It must not block: it starts the work, e.g. in a new goroutine,
+// and returns, so the guest gets the handle of the future right away.
+// The work completes future from any goroutine with Future.Complete or Future.Fail.
+type AsyncHostFunctionCallback func(ctx context.Context, moduleProxy *ModuleProxy, params []PackedData, future *Future)
+
+// Future is the pending result of an async host function call, see HostFunction.AsyncCallback.
+//
+// The guest polls or waits for it through the mdk, so it can call several async host functions
+// and wait for all of them, their I/O running concurrently within one guest function call.
+// The results are written into the memory of the guest when the guest reads them, on the guest thread.
+type Future struct {
+	handle uint64
+
+	// function is the name of the host function, results the types it returns.
+	function string
+	results  []ValueType
+
+	once   sync.Once
+	done   chan struct{}
+	values []any
+	err    error
+}
+
+func newFuture(handle uint64, hf *HostFunction) *Future {
+	return &Future{
+		handle:   handle,
+		function: hf.Name,
+		results:  hf.Results,
+		done:     make(chan struct{}),
+	}
+}
+
+// Complete completes the future with the results of the host function,
+// which can be []byte, byte, uint32, uint64, float32, float64 and string values matching HostFunction.Results.
+// If they don't match, the future fails instead.
+//
+// Only the first call of Complete or Fail has an effect, later calls are ignored.
+// The future may be completed after its handle was removed: the guest dropped it, e.g. the guest function returned
+// without waiting for it, the callback panicked, or the module was closed or reset. Completing it is then a no-op,
+// apart from closing Done, the guest can't read it anymore.
+func (f *Future) Complete(results ...any) {
+
+	err := f.checkResults(results)
+	if err != nil {
+		f.Fail(err)
+		return
+	}
+
+	f.once.Do(func() {
+		f.values = results
+		close(f.done)
+	})
+}
+
+// Fail completes the future with an error, which the guest reads as the error of the call.
+// Like Complete, failing a future whose handle was removed is a no-op.
+func (f *Future) Fail(err error) {
+
+	if err == nil {
+		err = errors.New("future failed without an error")
+	}
+
+	f.once.Do(func() {
+		f.err = err
+		close(f.done)
+	})
+}
+
+// Done returns a channel which is closed once the future is completed.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// checkResults checks results match the types the host function returns.
+func (f *Future) checkResults(results []any) error {
+
+	if len(results) != len(f.results) {
+		return fmt.Errorf("%s: results mismatch expected: %d received: %d", f.function, len(f.results), len(results))
+	}
+
+	for i, result := range results {
+		valueType, _, err := types.GetOffsetSizeAndDataTypeByConversion(result)
+		if err != nil {
+			return fmt.Errorf("%s: result %d: %w", f.function, i, err)
+		}
+
+		if ValueType(valueType) != f.results[i] {
+			return fmt.Errorf("%s: result %d: expected %s, got %s", f.function, i, types.ValueType(f.results[i]), valueType)
+		}
+	}
+
+	return nil
+}
+
+// envelope writes the outcome of a completed future into memory, as the dispatcher of the mdk does:
+// a MultiPackedData of the results, or 0, and a string pack of the error, or 0.
+func (f *Future) envelope(memory Memory) (MultiPackedData, error) {
+
+	if f.err != nil {
+		return memory.WriteMultiPack(0, memory.WriteStringPack(f.err.Error())), nil
+	}
+
+	pds := make([]PackedData, len(f.values))
+	for i, v := range f.values {
+		pd, err := writeAnyPack(memory, v)
+		if err != nil {
+			memory.FreePack(pds[:i]...)
+			return 0, errors.Join(fmt.Errorf("can't write result %d of %s", i, f.function), err)
+		}
+		pds[i] = pd
+	}
+
+	var results MultiPackedData
+	if len(pds) > 0 {
+		results = memory.WriteMultiPack(pds...)
+	}
+
+	return memory.WriteMultiPack(PackedData(results), 0), nil
+}
+
+// writeAnyPack allocates memory for v, writes v into it and returns its pack.
+func writeAnyPack(memory Memory, v any) (PackedData, error) {
+
+	valueType, size, err := types.GetOffsetSizeAndDataTypeByConversion(v)
+	if err != nil {
+		return 0, err
+	}
+
+	offset, err := memory.Malloc(size)
+	if err != nil {
+		return 0, err
+	}
+
+	err = memory.WriteAny(offset, v)
+	if err != nil {
+		return 0, err
+	}
+
+	pd, err := utils.PackUI64(valueType, offset, size)
+	if err != nil {
+		return 0, err
+	}
+
+	return PackedData(pd), nil
+}
+
+// futures are the futures of a module the guest hasn't read nor dropped yet, by handle.
+type futures struct {
+	mu      sync.Mutex
+	last    uint64
+	pending map[uint64]*Future
+}
+
+func newFutures() *futures {
+	return &futures{pending: make(map[uint64]*Future)}
+}
+
+// add returns a new pending future of the host function.
+func (fs *futures) add(hf *HostFunction) *Future {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	// Handles are never reused, so a stale handle can't read the future of another call.
+	fs.last++
+	f := newFuture(fs.last, hf)
+	fs.pending[f.handle] = f
+
+	return f
+}
+
+func (fs *futures) get(handle uint64) (*Future, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	f, ok := fs.pending[handle]
+	if !ok {
+		return nil, fmt.Errorf("unknown future %d", handle)
+	}
+
+	return f, nil
+}
+
+// remove forgets the future, once the guest read or dropped it.
+func (fs *futures) remove(handle uint64) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	delete(fs.pending, handle)
+}
+
+// clear forgets every pending future, their handles are invalid afterwards.
+func (fs *futures) clear() {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	clear(fs.pending)
+}
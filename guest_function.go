@@ -3,9 +3,6 @@ package wasify
 import (
 	"errors"
 	"fmt"
-
-	"github.com/wasify-io/wasify-go/internal/types"
-	"github.com/wasify-io/wasify-go/internal/utils"
 )
 
 type GuestFunctionResult struct {
@@ -13,6 +10,11 @@ type GuestFunctionResult struct {
 	memory          Memory
 }
 
+// Memory returns the memory the results are read from, e.g. with Memory.ReadStringPack.
+func (r GuestFunctionResult) Memory() Memory {
+	return r.memory
+}
+
 // ReadPacks decodes the packedData from a GuestFunctionResult instance and retrieves a sequence of packed datas.
 // NOTE: Frees multiPackedData, which means ReadPacks should be called once.
 func (r GuestFunctionResult) ReadPacks() ([]PackedData, error) {
@@ -21,16 +23,8 @@ func (r GuestFunctionResult) ReadPacks() ([]PackedData, error) {
 		return nil, errors.New("packedData is empty")
 	}
 
-	t, offsetU32, size := utils.UnpackUI64(uint64(r.multiPackedData))
-
-	if t != types.ValueTypePack {
-		err := fmt.Errorf("Can't unpack host data, the type is not a valueTypePack. expected %d, got %d", types.ValueTypePack, t)
-		return nil, err
-	}
-
-	bytes, err := r.memory.ReadBytes(offsetU32, size)
+	results, err := readPacks(r.memory, r.multiPackedData)
 	if err != nil {
-		err := errors.Join(errors.New("ReadPacks error, can't read bytes:"), err)
 		return nil, err
 	}
 
@@ -40,17 +34,22 @@ func (r GuestFunctionResult) ReadPacks() ([]PackedData, error) {
 		return nil, err
 	}
 
-	packedDataArray := utils.BytesToUint64Array(bytes)
+	return results, nil
+}
 
-	// calculate the number of elements in the array
-	count := size / 8
+// GuestError is the error returned by a guest function registered with mdk.Export,
+// or the panic it raised, as returned by GuestFunction.Invoke. Use errors.As to inspect it.
+type GuestError struct {
+	// Namespace of the module the guest function belongs to.
+	Namespace string
 
-	results := make([]PackedData, count)
+	// Function is the name the guest function has been exported with.
+	Function string
 
-	// Iterate over the packedData, unpack and read data of each element into a Result
-	for i, pd := range packedDataArray {
-		results[i] = PackedData(pd)
-	}
+	// Message is the error message of the guest.
+	Message string
+}
 
-	return results, nil
+func (e *GuestError) Error() string {
+	return fmt.Sprintf("guest function %s.%s failed: %s", e.Namespace, e.Function, e.Message)
 }
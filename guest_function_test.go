@@ -3,6 +3,7 @@ package wasify_test
 import (
 	"context"
 	_ "embed"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -59,3 +60,232 @@ func TestGuestFunctions(t *testing.T) {
 		t.Log("TestGuestFunctions RES:", res)
 	})
 }
+
+//go:embed testdata/wasm/mdk_allocator/main.wasm
+var wasm_mdkAllocator []byte
+
+// TestMdkAllocator runs a guest built with Go, without cgo, so the host allocates through the mdk's wasify_malloc and wasify_free.
+func TestMdkAllocator(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+		Runtime:     wasify.RuntimeWazero,
+		LogSeverity: wasify.LogError,
+	})
+	assert.NoError(t, err)
+	defer runtime.Close(ctx)
+
+	module, err := runtime.NewModule(ctx, &wasify.ModuleConfig{
+		Namespace: "mdk_allocator",
+		Wasm: wasify.Wasm{
+			Binary: wasm_mdkAllocator,
+		},
+	})
+	assert.NoError(t, err)
+	defer module.Close(ctx)
+
+	var exports []string
+	for _, fn := range module.ExportedFunctions() {
+		exports = append(exports, fn.Name)
+	}
+	assert.Contains(t, exports, "wasify_malloc")
+	assert.Contains(t, exports, "wasify_free")
+	assert.NotContains(t, exports, "malloc")
+
+	for i := 0; i < 3; i++ {
+		res, err := module.GuestFunction(ctx, "greet").Invoke("Wasify")
+		assert.NoError(t, err)
+
+		pds, err := res.ReadPacks()
+		assert.NoError(t, err)
+		assert.Len(t, pds, 2)
+
+		greeting, err := module.Memory().ReadStringPack(pds[0])
+		assert.NoError(t, err)
+		assert.Equal(t, "Hello, Wasify", greeting)
+
+		size, err := module.Memory().ReadUint64Pack(pds[1])
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(6), size)
+
+		assert.NoError(t, module.Memory().FreePack(pds...))
+	}
+
+	offset, err := module.Memory().Malloc(16)
+	assert.NoError(t, err)
+	assert.NotZero(t, offset)
+	assert.Zero(t, offset%8, "blocks are 8-byte aligned")
+	assert.NoError(t, module.Memory().Free(offset))
+}
+
+//go:embed testdata/wasm/mdk_export/main.wasm
+var wasm_mdkExport []byte
+
+func TestMdkExport(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+		Runtime:     wasify.RuntimeWazero,
+		LogSeverity: wasify.LogError,
+	})
+	assert.NoError(t, err)
+	defer runtime.Close(ctx)
+
+	module, err := runtime.NewModule(ctx, &wasify.ModuleConfig{
+		Namespace: "mdk_export",
+		Wasm: wasify.Wasm{
+			Binary: wasm_mdkExport,
+		},
+	})
+	assert.NoError(t, err)
+	defer module.Close(ctx)
+
+	invoke := func(t *testing.T, name string, params ...any) []any {
+
+		res, err := module.GuestFunction(ctx, name).Invoke(params...)
+		assert.NoError(t, err)
+
+		pds, err := res.ReadPacks()
+		assert.NoError(t, err)
+
+		values := make([]any, len(pds))
+		for i, pd := range pds {
+			values[i], _, _, err = module.Memory().ReadAnyPack(pd)
+			assert.NoError(t, err)
+		}
+
+		assert.NoError(t, module.Memory().FreePack(pds...))
+
+		return values
+	}
+
+	t.Run("results", func(t *testing.T) {
+		assert.Equal(t, []any{"Hello, Wasify! Hello, Wasify! "}, invoke(t, "greet", "Wasify", uint32(2)))
+		assert.Equal(t, []any{[]byte{3, 2, 1}}, invoke(t, "reverse", []byte{1, 2, 3}))
+		assert.Equal(t, []any{float64(212), byte(1)}, invoke(t, "fahrenheit", float64(100)))
+	})
+
+	t.Run("no results", func(t *testing.T) {
+		_, err := module.GuestFunction(ctx, "noop").Invoke()
+		assert.NoError(t, err)
+	})
+
+	t.Run("guest errors", func(t *testing.T) {
+
+		tests := []struct {
+			name    string
+			params  []any
+			message string
+		}{
+			{"greet", []any{"", uint32(1)}, "name is empty"},
+			{"crash", nil, "panic: boom"},
+			{"greet", []any{"Wasify"}, "expected 2 params, got 1"},
+			{"greet", []any{uint32(1), "Wasify"}, "param 0: expected ValueTypeString, got ValueTypeI32"},
+			{"missing", nil, "function missing is not exported"},
+		}
+
+		for _, tt := range tests {
+			_, err := module.GuestFunction(ctx, tt.name).Invoke(tt.params...)
+
+			var guestErr *wasify.GuestError
+			if assert.ErrorAs(t, err, &guestErr) {
+				assert.Equal(t, "mdk_export", guestErr.Namespace)
+				assert.Equal(t, tt.name, guestErr.Function)
+				assert.Equal(t, tt.message, guestErr.Message)
+			}
+		}
+
+		// The module is still usable after the guest failed.
+		assert.Equal(t, []any{"Hello, Wasify! "}, invoke(t, "greet", "Wasify", uint32(1)))
+	})
+}
+
+//go:embed testdata/wasm/mdk_import/main.wasm
+var wasm_mdkImport []byte
+
+func TestMdkImport(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+		Runtime:     wasify.RuntimeWazero,
+		LogSeverity: wasify.LogError,
+	})
+	assert.NoError(t, err)
+	defer runtime.Close(ctx)
+
+	var recorded []byte
+
+	module, err := runtime.NewModule(ctx, &wasify.ModuleConfig{
+		Namespace: "mdk_import",
+		Wasm: wasify.Wasm{
+			Binary: wasm_mdkImport,
+		},
+		HostFunctions: []wasify.HostFunction{
+			{
+				Name: "greet",
+				Callback: func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {
+					name, _ := m.Memory.ReadStringPack(params[0])
+					times, _ := m.Memory.ReadUint32Pack(params[1])
+					return m.Memory.WriteMultiPack(m.Memory.WriteStringPack(strings.Repeat("Hello, "+name+"! ", int(times))))
+				},
+				Params:  []wasify.ValueType{wasify.ValueTypeString, wasify.ValueTypeI32},
+				Results: []wasify.ValueType{wasify.ValueTypeString},
+			},
+			{
+				Name: "record",
+				Callback: func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {
+					data, _ := m.Memory.ReadBytesPack(params[0])
+					recorded = append(recorded, data...)
+					return 0
+				},
+				Params: []wasify.ValueType{wasify.ValueTypeBytes},
+			},
+			{
+				Name: "measure",
+				Callback: func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {
+					return m.Memory.WriteMultiPack(m.Memory.WriteStringPack("warm"))
+				},
+				Results: []wasify.ValueType{wasify.ValueTypeString},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	defer module.Close(ctx)
+
+	t.Run("results", func(t *testing.T) {
+
+		res, err := module.GuestFunction(ctx, "greet").Invoke("Wasify", uint32(2))
+		assert.NoError(t, err)
+
+		pds, err := res.ReadPacks()
+		assert.NoError(t, err)
+		assert.Len(t, pds, 1)
+
+		greeting, err := module.Memory().ReadStringPack(pds[0])
+		assert.NoError(t, err)
+		assert.Equal(t, "Hello, Wasify! Hello, Wasify! ", greeting)
+	})
+
+	t.Run("no results", func(t *testing.T) {
+
+		for i := 0; i < 2; i++ {
+			_, err := module.GuestFunction(ctx, "record").Invoke([]byte("data"))
+			assert.NoError(t, err)
+		}
+
+		assert.Equal(t, []byte("datadata"), recorded)
+	})
+
+	t.Run("mismatched results", func(t *testing.T) {
+
+		_, err := module.GuestFunction(ctx, "measure").Invoke()
+
+		var guestErr *wasify.GuestError
+		if assert.ErrorAs(t, err, &guestErr) {
+			assert.Equal(t, "result 0: expected ValueTypeF64, got ValueTypeString", guestErr.Message)
+		}
+	})
+}
@@ -4,10 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/tetratelabs/wazero/api"
-	"github.com/wasify-io/wasify-go/internal/types"
-	"github.com/wasify-io/wasify-go/internal/utils"
 )
 
 type wazeroGuestFunction struct {
@@ -16,24 +15,42 @@ type wazeroGuestFunction struct {
 	name         string
 	memory       Memory
 	moduleConfig *ModuleConfig
+
+	// events serializes the calls with the delivery of events to the module, see EventBus.
+	events *eventSubscriber
+
+	// dispatch reports whether fn is the dispatcher of a guest built with the mdk,
+	// which invokes the function registered with mdk.Export under name.
+	dispatch bool
 }
 
 // call invokes wazero's CallWithStack method, which returns ome uint64 message,
 // in most cases it is used to call built in methods such as "malloc", "free"
 // See wazero's CallWithStack for more details.
 func (gf *wazeroGuestFunction) call(params ...uint64) (uint64, error) {
+	return gf.callContext(gf.ctx, params...)
+}
+
+// callContext is like call, but runs the guest function with ctx,
+// which is passed down to any host function the guest calls.
+func (gf *wazeroGuestFunction) callContext(ctx context.Context, params ...uint64) (uint64, error) {
 
 	// size of params len(params) + one size for return uint64 value
 	stack := make([]uint64, len(params)+1)
 	copy(stack, params)
 
-	err := gf.fn.CallWithStack(gf.ctx, stack[:])
+	err := gf.fn.CallWithStack(ctx, stack[:])
 	if err != nil {
 		err = errors.Join(errors.New("error invoking internal call func"), err)
 		gf.moduleConfig.log.Error(err.Error())
 		return 0, err
 	}
 
+	// Without results, stack[0] still holds the first param.
+	if len(gf.fn.Definition().ResultTypes()) == 0 {
+		return 0, nil
+	}
+
 	return stack[0], nil
 }
 
@@ -56,57 +73,144 @@ func (gf *wazeroGuestFunction) call(params ...uint64) (uint64, error) {
 // or an error if any step in the process fails.
 func (gf *wazeroGuestFunction) Invoke(params ...any) (*GuestFunctionResult, error) {
 
-	var err error
+	call := &Call{
+		Kind:      CallGuestFunction,
+		Namespace: gf.moduleConfig.Namespace,
+		Function:  gf.name,
+		Args:      params,
+		Memory:    gf.memory,
+	}
+
+	// Wait for the delivery of an event to the module, see EventBus.
+	ctx, end := gf.events.enter(gf.ctx)
+	defer end()
+
+	multiPackedData, err := chainInterceptors(gf.moduleConfig.interceptors, gf.invoke)(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GuestFunctionResult{
+		multiPackedData: uint64(multiPackedData),
+		memory:          gf.memory,
+	}
+
+	return res, nil
+}
+
+// invoke writes the args of the call into memory and calls the guest function, it's the last Invoker of the interceptor chain.
+func (gf *wazeroGuestFunction) invoke(ctx context.Context, c *Call) (MultiPackedData, error) {
+
+	params := c.Args
 
 	log := gf.moduleConfig.log.Info
 	if gf.moduleConfig.Namespace == "malloc" || gf.moduleConfig.Namespace == "free" {
 		log = gf.moduleConfig.log.Debug
 	}
 
-	log("calling guest function", "namespace", gf.moduleConfig.Namespace, "function", gf.name, "params", params)
+	log("calling guest function", "function", gf.name, "params", params)
 
 	stack := make([]uint64, len(params))
 
+	var err error
 	for i, p := range params {
-		valueType, offsetSize, err := types.GetOffsetSizeAndDataTypeByConversion(p)
-		if err != nil {
-			err = errors.Join(fmt.Errorf("Can't convert guest func param %s", gf.name), err)
-			return nil, err
-		}
-
-		// allocate memory for each value
-		offsetI32, err := gf.memory.Malloc(offsetSize)
+		pd, err := writeAnyPack(gf.memory, p)
 		if err != nil {
-			err = errors.Join(fmt.Errorf("An error occurred while attempting to alloc memory for guest func param in: %s", gf.name), err)
+			err = errors.Join(fmt.Errorf("can't write param %d of guest func %s", i, gf.name), err)
 			gf.moduleConfig.log.Error(err.Error())
-			return nil, err
+			return 0, err
 		}
 
-		err = gf.memory.WriteAny(offsetI32, p)
-		if err != nil {
-			err = errors.Join(errors.New("Can't write arg to"), err)
-			return nil, err
-		}
+		stack[i] = uint64(pd)
+	}
 
-		stack[i], err = utils.PackUI64(valueType, offsetI32, offsetSize)
-		if err != nil {
-			err = errors.Join(fmt.Errorf("An error occurred while attempting to pack data for guest func param in:  %s", gf.name), err)
-			gf.moduleConfig.log.Error(err.Error())
-			return nil, err
-		}
+	// Trace the call, host functions called by the guest receive ctx, so their spans become children of this one.
+	info := CallInfo{
+		Kind:      CallGuestFunction,
+		Namespace: gf.moduleConfig.Namespace,
+		Function:  gf.name,
+		ArgsSize:  packedDataSize(stack...),
 	}
+	ctx, span := gf.moduleConfig.tracer.Start(ctx, info)
+
+	start := time.Now()
+
+	var multiPackedData uint64
+	if gf.dispatch {
+		multiPackedData, err = gf.callDispatch(ctx, stack)
+	} else {
+		multiPackedData, err = gf.callContext(ctx, stack...)
+	}
+	span.End(err)
+
+	gf.moduleConfig.metrics.ObserveCall(info, CallResult{
+		Duration:    time.Since(start),
+		ResultsSize: multiPackedDataSize(gf.memory, multiPackedData),
+		Err:         err,
+	})
+	gf.moduleConfig.metrics.ObserveMemorySize(gf.moduleConfig.Namespace, gf.memory.Size())
 
-	multiPackedData, err := gf.call(stack...)
 	if err != nil {
 		err = errors.Join(fmt.Errorf("An error occurred while attempting to invoke the guest function: %s", gf.name), err)
 		gf.moduleConfig.log.Error(err.Error())
-		return nil, err
+		return 0, err
 	}
 
-	res := &GuestFunctionResult{
-		multiPackedData: multiPackedData,
-		memory:          gf.memory,
+	return MultiPackedData(multiPackedData), nil
+}
+
+// callDispatch invokes the function through the dispatcher of a guest built with the mdk.
+// The dispatcher takes the function name and the params as a MultiPackedData, and frees them.
+// It returns an envelope of the results and the error returned by the function, see mdk.Export.
+func (gf *wazeroGuestFunction) callDispatch(ctx context.Context, stack []uint64) (uint64, error) {
+
+	params := make([]PackedData, len(stack))
+	for i, pd := range stack {
+		params[i] = PackedData(pd)
+	}
+
+	name := gf.memory.WriteStringPack(gf.name)
+	args := gf.memory.WriteMultiPack(params...)
+	if name == 0 || (len(params) > 0 && args == 0) {
+		return 0, fmt.Errorf("can't write the name and the params of %s for the dispatcher", gf.name)
+	}
+
+	envelope, err := gf.callContext(ctx, uint64(name), uint64(args))
+	if err != nil {
+		return 0, err
+	}
+
+	pds, err := readPacks(gf.memory, envelope)
+	if err != nil {
+		return 0, errors.Join(errors.New("invalid dispatcher results"), err)
+	}
+
+	if err := gf.memory.FreePack(PackedData(envelope)); err != nil {
+		return 0, err
+	}
+
+	if len(pds) != 2 {
+		return 0, fmt.Errorf("invalid dispatcher results, expected 2 packs, got %d", len(pds))
+	}
+
+	results, guestErr := pds[0], pds[1]
+
+	if guestErr != 0 {
+		msg, err := gf.memory.ReadStringPack(guestErr)
+		if err != nil {
+			return 0, errors.Join(errors.New("invalid dispatcher error"), err)
+		}
+
+		if err := gf.memory.FreePack(guestErr); err != nil {
+			return 0, err
+		}
+
+		return 0, &GuestError{
+			Namespace: gf.moduleConfig.Namespace,
+			Function:  gf.name,
+			Message:   msg,
+		}
 	}
 
-	return res, err
+	return uint64(results), nil
 }
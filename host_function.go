@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/wasify-io/wasify-go/internal/types"
+	"github.com/wasify-io/wasify-go/internal/utils"
 )
 
 // ValueType represents the type of value used in function parameters and returns.
@@ -30,6 +31,11 @@ type HostFunction struct {
 	// Callback function to execute when the host function is invoked.
 	Callback HostFunctionCallback
 
+	// AsyncCallback makes the host function async, it's executed instead of Callback when the host function is invoked.
+	// The guest gets a Future of the results right away, and reads them through the mdk once the callback completes it,
+	// see AsyncHostFunctionCallback for more details.
+	AsyncCallback AsyncHostFunctionCallback
+
 	// Name of the host function.
 	Name string
 
@@ -57,10 +63,66 @@ type HostFunction struct {
 // It serves as an intermediary invoked between the processing of function parameters and the final return of the function.
 type HostFunctionCallback func(ctx context.Context, moduleProxy *ModuleProxy, multiPackedData []PackedData) MultiPackedData
 
+// packedResults returns the value types the host function returns on the wasm stack.
+// If the host function has any return values, they are packed as a single uint64 (MultiPackedData).
+// Async host functions always return one, which holds the handle of the future.
+func (hf *HostFunction) packedResults() []ValueType {
+	if len(hf.Results) == 0 && hf.AsyncCallback == nil {
+		return []ValueType{}
+	}
+
+	return []ValueType{ValueTypeI64}
+}
+
+// definition returns the wasm signature a guest must use to import the host function.
+func (hf *HostFunction) definition(namespace string) FunctionDefinition {
+
+	def := FunctionDefinition{
+		Namespace: namespace,
+		Name:      hf.Name,
+		Params:    make([]WasmValueType, len(hf.Params)),
+		Results:   make([]WasmValueType, len(hf.packedResults())),
+	}
+
+	// Every param and the packed result are passed as PackedData, which is an i64.
+	for i := range def.Params {
+		def.Params[i] = WasmValueTypeI64
+	}
+	for i := range def.Results {
+		def.Results[i] = WasmValueTypeI64
+	}
+
+	return def
+}
+
+// ParamError is the cause of a Trap raised when the guest calls a host function with invalid params.
+type ParamError struct {
+	// Index of the invalid param, or -1 if the number of params doesn't match.
+	Index int
+
+	// Param is the PackedData the guest passed.
+	Param PackedData
+
+	// Err describes what is wrong with the param, e.g. a *TypeMismatchError.
+	Err error
+}
+
+func (e *ParamError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("invalid params: %s", e.Err)
+	}
+
+	return fmt.Sprintf("invalid param %d (%#x): %s", e.Index, uint64(e.Param), e.Err)
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
 // preHostFunctionCallback
 // prepares parameters for the host function by converting
-// packed stack parameters into a slice of PackedData. It validates parameter counts
-// and leverages ModuleProxy for reading the data.
+// packed stack parameters into a slice of PackedData. It validates parameter counts, value types against Params
+// (see ModuleConfig.TypeValidation) and that the packed data is within the linear memory, so the callback never reads invalid params.
 func (hf *HostFunction) preHostFunctionCallback(ctx context.Context, m *ModuleProxy, stackParams []uint64) ([]PackedData, error) {
 
 	// If user did not define params, skip the whole process, we still might get stackParams[0] = 0
@@ -69,19 +131,84 @@ func (hf *HostFunction) preHostFunctionCallback(ctx context.Context, m *ModulePr
 	}
 
 	if len(hf.Params) != len(stackParams) {
-		return nil, fmt.Errorf("%s: params mismatch expected: %d received: %d ", hf.Name, len(hf.Params), len(stackParams))
+		return nil, &ParamError{
+			Index: -1,
+			Err:   fmt.Errorf("%s: params mismatch expected: %d received: %d", hf.Name, len(hf.Params), len(stackParams)),
+		}
 	}
 
+	memorySize := m.Memory.Size()
+
 	pds := make([]PackedData, len(hf.Params))
 
 	for i := range hf.Params {
 		pds[i] = PackedData(stackParams[i])
+
+		valueType, err := validatePackedData(pds[i], memorySize)
+		if err != nil {
+			return nil, &ParamError{Index: i, Param: pds[i], Err: err}
+		}
+
+		err = m.module.typeWarnings.check(hf.moduleConfig.TypeValidation, hf.moduleConfig.log, hf.Name, valueType, hf.Params[i])
+		if err != nil {
+			return nil, &ParamError{Index: i, Param: pds[i], Err: err}
+		}
 	}
 
 	return pds, nil
 
 }
 
+// validatePackedData checks that pd has a known value type and references data within a memory of memorySize bytes.
+// It returns the value type of pd.
+func validatePackedData(pd PackedData, memorySize uint32) (ValueType, error) {
+
+	valueType, offset, size := utils.UnpackUI64(uint64(pd))
+
+	// Fixed size values are read regardless of the packed size.
+	var readSize uint32
+	switch ValueType(valueType) {
+	case ValueTypeBytes, ValueTypeString:
+		readSize = size
+	case ValueTypeByte:
+		readSize = 1
+	case ValueTypeI32, ValueTypeF32:
+		readSize = 4
+	case ValueTypeI64, ValueTypeF64:
+		readSize = 8
+	default:
+		return 0, fmt.Errorf("invalid value type %d", valueType)
+	}
+
+	readSize = max(readSize, size)
+
+	if uint64(offset)+uint64(readSize) > uint64(memorySize) {
+		return 0, fmt.Errorf("%s at offset %d with size %d is out of range of memory size %d", valueType, offset, readSize, memorySize)
+	}
+
+	return ValueType(valueType), nil
+}
+
+// callback executes the callback of the host function.
+// An async host function returns the handle of a new future as an I64 pack, the future is dropped if the callback panics.
+func (hf *HostFunction) callback(ctx context.Context, m *ModuleProxy, params []PackedData) (results MultiPackedData) {
+
+	if hf.AsyncCallback == nil {
+		return hf.Callback(ctx, m, params)
+	}
+
+	future := m.module.futures.add(hf)
+	defer func() {
+		if results == 0 {
+			m.module.futures.remove(future.handle)
+		}
+	}()
+
+	hf.AsyncCallback(ctx, m, params, future)
+
+	return m.Memory.WriteMultiPack(m.Memory.WriteUint64Pack(future.handle))
+}
+
 // postHostFunctionCallback
 // stores the resulting MultiPackedData into linear memory after the host function execution.
 func (hf *HostFunction) postHostFunctionCallback(ctx context.Context, m *ModuleProxy, mpd MultiPackedData, stackParams []uint64) {
@@ -3,7 +3,11 @@ package wasify_test
 import (
 	"context"
 	_ "embed"
+	"errors"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/wasify-io/wasify-go"
@@ -106,3 +110,290 @@ func TestHostFunctions(t *testing.T) {
 		t.Log("TestHostFunctions RES:", res)
 	})
 }
+
+//go:embed testdata/wasm/mdk_async/main.wasm
+var wasm_mdkAsync []byte
+
+// TestRuntimeModules checks several modules run in one runtime, each guest calling the host functions of its own module.
+func TestRuntimeModules(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+		Runtime:     wasify.RuntimeWazero,
+		LogSeverity: wasify.LogError,
+	})
+	assert.NoError(t, err)
+	defer runtime.Close(ctx)
+
+	noop := func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {
+		return 0
+	}
+
+	newModule := func(greeting string, extra ...wasify.HostFunction) (wasify.Module, error) {
+		return runtime.NewModule(ctx, &wasify.ModuleConfig{
+			Namespace: "mdk_import",
+			Wasm: wasify.Wasm{
+				Binary: wasm_mdkImport,
+			},
+			HostFunctions: append([]wasify.HostFunction{
+				{
+					Name: "greet",
+					Callback: func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {
+						name, _ := m.Memory.ReadStringPack(params[0])
+						return m.Memory.WriteMultiPack(m.Memory.WriteStringPack(greeting + ", " + name))
+					},
+					Params:  []wasify.ValueType{wasify.ValueTypeString, wasify.ValueTypeI32},
+					Results: []wasify.ValueType{wasify.ValueTypeString},
+				},
+				{Name: "record", Callback: noop, Params: []wasify.ValueType{wasify.ValueTypeBytes}},
+				{Name: "measure", Callback: noop, Results: []wasify.ValueType{wasify.ValueTypeF64}},
+			}, extra...),
+		})
+	}
+
+	hello, err := newModule("Hello")
+	assert.NoError(t, err)
+	defer hello.Close(ctx)
+
+	hi, err := newModule("Hi")
+	assert.NoError(t, err)
+	defer hi.Close(ctx)
+
+	greet := func(module wasify.Module) string {
+		res, err := module.GuestFunction(ctx, "greet").Invoke("Wasify", uint32(1))
+		assert.NoError(t, err)
+
+		pds, err := res.ReadPacks()
+		assert.NoError(t, err)
+
+		greeting, err := module.Memory().ReadStringPack(pds[0])
+		assert.NoError(t, err)
+
+		return greeting
+	}
+
+	assert.Equal(t, "Hello, Wasify", greet(hello))
+	assert.Equal(t, "Hi, Wasify", greet(hi))
+
+	// The host functions of a namespace must have the same signatures in every module of the runtime.
+	_, err = newModule("Hey", wasify.HostFunction{Name: "extra", Callback: noop})
+	assert.ErrorContains(t, err, "already been instantiated in the runtime with other host functions")
+}
+
+func TestAsyncHostFunctions(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+		Runtime:     wasify.RuntimeWazero,
+		LogSeverity: wasify.LogError,
+	})
+	assert.NoError(t, err)
+	defer runtime.Close(ctx)
+
+	// Fetches of "concurrent" urls complete once they have all started, so they deadlock unless they run concurrently.
+	var concurrent sync.WaitGroup
+
+	// Fetches of "slow" urls complete once they're released.
+	release := make(chan struct{})
+
+	// Fetches of "held" urls signal they've started, and complete once they're let go.
+	held, letGo := make(chan struct{}), make(chan struct{})
+
+	// Fetches of "panic" urls hand their future to a goroutine and panic, the goroutine completes it once resumed.
+	resume, completed := make(chan struct{}), make(chan *wasify.Future, 1)
+
+	config := &wasify.ModuleConfig{
+		Namespace: "mdk_async",
+		Wasm: wasify.Wasm{
+			Binary: wasm_mdkAsync,
+		},
+		HostFunctions: []wasify.HostFunction{
+			{
+				Name: "fetch",
+				AsyncCallback: func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData, future *wasify.Future) {
+
+					url, _ := m.Memory.ReadStringPack(params[0])
+
+					if url == "panic" {
+						go func() {
+							<-resume
+							future.Complete("body of panic")
+							completed <- future
+						}()
+						panic("fetch panicked")
+					}
+
+					go func() {
+						switch {
+						case strings.HasPrefix(url, "concurrent"):
+							concurrent.Done()
+							concurrent.Wait()
+						case url == "slow":
+							<-release
+						case url == "held":
+							close(held)
+							<-letGo
+						case url == "never":
+							return
+						case url == "refused":
+							future.Fail(errors.New("connection refused"))
+							return
+						case url == "mismatch":
+							future.Complete(uint32(404))
+							return
+						}
+
+						future.Complete("body of " + url)
+					}()
+				},
+				Params:  []wasify.ValueType{wasify.ValueTypeString},
+				Results: []wasify.ValueType{wasify.ValueTypeString},
+			},
+		},
+	}
+
+	module, err := runtime.NewModule(ctx, config)
+	assert.NoError(t, err)
+	defer module.Close(ctx)
+
+	invoke := func(t *testing.T, ctx context.Context, name string, params ...any) ([]any, error) {
+
+		res, err := module.GuestFunction(ctx, name).Invoke(params...)
+		if err != nil {
+			return nil, err
+		}
+
+		pds, err := res.ReadPacks()
+		assert.NoError(t, err)
+
+		results := make([]any, len(pds))
+		for i, pd := range pds {
+			results[i], _, _, err = module.Memory().ReadAnyPack(pd)
+			assert.NoError(t, err)
+		}
+
+		return results, nil
+	}
+
+	t.Run("concurrent", func(t *testing.T) {
+
+		concurrent.Add(2)
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+
+		results, err := invoke(t, ctx, "fetch_all", "concurrent/a", "concurrent/b")
+		assert.NoError(t, err)
+		assert.Equal(t, []any{"body of concurrent/a\nbody of concurrent/b\n"}, results)
+	})
+
+	t.Run("poll", func(t *testing.T) {
+
+		time.AfterFunc(10*time.Millisecond, func() { close(release) })
+
+		results, err := invoke(t, ctx, "fetch_poll", "slow")
+		assert.NoError(t, err)
+		if assert.Len(t, results, 2) {
+			assert.Equal(t, "body of slow", results[0])
+			assert.NotZero(t, results[1])
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+
+		tests := []struct {
+			url     string
+			message string
+		}{
+			{"refused", "connection refused"},
+			{"mismatch", "fetch: result 0: expected ValueTypeString, got ValueTypeI32"},
+		}
+
+		for _, tt := range tests {
+			_, err := invoke(t, ctx, "fetch_all", "https://wasify.io", tt.url)
+
+			var guestErr *wasify.GuestError
+			if assert.ErrorAs(t, err, &guestErr) {
+				assert.Equal(t, tt.message, guestErr.Message)
+			}
+		}
+	})
+
+	t.Run("read twice", func(t *testing.T) {
+
+		_, err := invoke(t, ctx, "await_twice", "https://wasify.io")
+
+		var guestErr *wasify.GuestError
+		if assert.ErrorAs(t, err, &guestErr) {
+			assert.Equal(t, "the future has already been read or dropped", guestErr.Message)
+		}
+	})
+
+	t.Run("drop", func(t *testing.T) {
+
+		// fetch_drop has no results.
+		_, err := module.GuestFunction(ctx, "fetch_drop").Invoke("never")
+		assert.NoError(t, err)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		_, err := invoke(t, ctx, "fetch_all", "never", "never")
+
+		var trap *wasify.Trap
+		if assert.ErrorAs(t, err, &trap) {
+			assert.ErrorIs(t, trap, context.DeadlineExceeded)
+		}
+
+		// The module is still usable after the guest trapped.
+		results, err := invoke(t, context.Background(), "fetch_all", "https://wasify.io", "https://wasify.io/docs")
+		assert.NoError(t, err)
+		assert.Equal(t, []any{"body of https://wasify.io\nbody of https://wasify.io/docs\n"}, results)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+
+		_, err := invoke(t, ctx, "fetch_all", "panic", "https://wasify.io")
+		assert.ErrorContains(t, err, "fetch panicked")
+
+		// The future was dropped when the callback panicked, completing it afterwards is a no-op.
+		close(resume)
+
+		select {
+		case future := <-completed:
+			assert.NotNil(t, future)
+			<-future.Done()
+		case <-time.After(10 * time.Second):
+			t.Fatal("the future of the panicked callback wasn't completed")
+		}
+
+		// The module is still usable after the callback panicked.
+		results, err := invoke(t, ctx, "fetch_all", "https://wasify.io", "https://wasify.io/docs")
+		assert.NoError(t, err)
+		assert.Equal(t, []any{"body of https://wasify.io\nbody of https://wasify.io/docs\n"}, results)
+	})
+
+	t.Run("instances", func(t *testing.T) {
+
+		// Another instance of the same config has its own futures, closing it doesn't drop the ones of the module.
+		other, err := runtime.NewModule(ctx, config)
+		assert.NoError(t, err)
+
+		go func() {
+			<-held
+			assert.NoError(t, other.Close(ctx))
+			close(letGo)
+		}()
+
+		results, err := invoke(t, ctx, "fetch_poll", "held")
+		assert.NoError(t, err)
+		if assert.Len(t, results, 2) {
+			assert.Equal(t, "body of held", results[0])
+		}
+	})
+}
@@ -2,6 +2,7 @@ package wasify
 
 import (
 	"context"
+	"time"
 
 	"github.com/tetratelabs/wazero/api"
 )
@@ -13,6 +14,7 @@ import (
 //   - Initialization of wazeroModule and ModuleProxy to set up the execution environment.
 //   - Converting stack parameters into structured parameters that the host function can understand.
 //   - Executing the user-defined host function callback with the correctly formatted parameters.
+//   - Recovering panics of the callback, and aborting the guest with a Trap instead.
 //   - Processing the results of the host function, converting them back into packed data format,
 //     and writing the final packed data into linear memory.
 //
@@ -50,21 +52,86 @@ import (
 //
 // Return value: A callback function that takes a context, api.Module, and a stack of parameters,
 // and handles the integration of the host function within the wazero runtime.
-func wazeroHostFunctionCallback(wazeroModule *wazeroModule, moduleConfig *ModuleConfig, hf *HostFunction) func(context.Context, api.Module, []uint64) {
+func wazeroHostFunctionCallback(wazeroModule *wazeroModule, moduleConfig *ModuleConfig, namespace string, hf *HostFunction) func(context.Context, api.Module, []uint64) {
 
 	return func(ctx context.Context, mod api.Module, stack []uint64) {
 
 		wazeroModule.mod = mod
 		moduleProxy := &ModuleProxy{
 			Memory: wazeroModule.Memory(),
+			module: wazeroModule,
 		}
 
+		// Panics of the callback are recovered by the callback Invoker below,
+		// this one catches panics of the interceptors.
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(*Trap); ok {
+					panic(r)
+				}
+				panic(newTrap(moduleConfig, namespace, hf.Name, newPanicError(r)))
+			}
+		}()
+
+		// Invalid params abort the guest before the callback runs.
 		params, err := hf.preHostFunctionCallback(ctx, moduleProxy, stack)
 		if err != nil {
-			moduleConfig.log.Error(err.Error(), "namespace", wazeroModule.Namespace, "func", hf.Name)
+			panic(newTrap(moduleConfig, namespace, hf.Name, err))
+		}
+
+		// callback runs the host function, it's the last Invoker of the interceptor chain.
+		callback := func(ctx context.Context, c *Call) (results MultiPackedData, err error) {
+
+			// The span of the guest function calling this host function is carried by ctx,
+			// so the host function span becomes its child.
+			info := CallInfo{
+				Kind:      CallHostFunction,
+				Namespace: namespace,
+				Function:  hf.Name,
+				ArgsSize:  packedDataSize(stack[:len(hf.Params)]...),
+			}
+			ctx, span := moduleConfig.tracer.Start(ctx, info)
+
+			start := time.Now()
+
+			// Turn a panic of the callback into an error, so interceptors, the span and metrics see it.
+			defer func() {
+				if r := recover(); r != nil {
+					results, err = 0, newPanicError(r)
+				}
+
+				span.End(err)
+				moduleConfig.metrics.ObserveCall(info, CallResult{
+					Duration:    time.Since(start),
+					ResultsSize: multiPackedDataSize(moduleProxy.Memory, uint64(results)),
+					Err:         err,
+				})
+			}()
+
+			return hf.callback(ctx, moduleProxy, params), nil
 		}
 
-		results := hf.Callback(ctx, moduleProxy, params)
+		call := &Call{
+			Kind:      CallHostFunction,
+			Namespace: namespace,
+			Function:  hf.Name,
+			Memory:    moduleProxy.Memory,
+		}
+
+		// Decode the args only for interceptors, the callback reads them itself.
+		if len(moduleConfig.interceptors) > 0 {
+			call.Args, err = readArgs(moduleProxy.Memory, params)
+		}
+
+		var results MultiPackedData
+		if err == nil {
+			results, err = chainInterceptors(moduleConfig.interceptors, callback)(ctx, call)
+		}
+
+		if err != nil {
+			// Abort the guest, wazero returns the panic as an error from the guest function call.
+			panic(newTrap(moduleConfig, namespace, hf.Name, err))
+		}
 
 		hf.postHostFunctionCallback(ctx, moduleProxy, results, stack)
 
@@ -2,6 +2,13 @@ package wasify
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/wasify-io/wasify-go/internal/attrs"
+	"github.com/wasify-io/wasify-go/internal/types"
+	"github.com/wasify-io/wasify-go/internal/utils"
 )
 
 const WASIFY_NAMESPACE = "wasify"
@@ -9,14 +16,38 @@ const WASIFY_NAMESPACE = "wasify"
 // hostFunctions is a list of pre-defined host functions
 type hostFunctions struct {
 	moduleConfig *ModuleConfig
+
+	// logLimiter limits the volume of guest logs, shared by all log functions of the module.
+	logLimiter *utils.RateLimiter
 }
 
 func newHostFunctions(moduleConfig *ModuleConfig) *hostFunctions {
-	return &hostFunctions{moduleConfig}
+	return &hostFunctions{
+		moduleConfig: moduleConfig,
+		logLimiter:   utils.NewRateLimiter(moduleConfig.GuestLogRateLimit.Rate, moduleConfig.GuestLogRateLimit.Burst),
+	}
+}
+
+// all returns every pre-defined host function, registered under WASIFY_NAMESPACE.
+func (hf *hostFunctions) all() []*HostFunction {
+	return []*HostFunction{
+		hf.newLog(),
+		hf.newStructuredLog(),
+		hf.newTraceParent(),
+		hf.newFuturePoll(),
+		hf.newFutureWait(),
+		hf.newFutureDrop(),
+		hf.newSubscribe(),
+		hf.newUnsubscribe(),
+	}
 }
 
 // newLog logs data from the guest module to the host machine,
 // to avoid stdin/stdout calls and ensure sandboxing.
+//
+// Params:
+//   - message, a ValueTypeString pack
+//   - severity level, a ValueTypeByte pack, see LogSeverity
 func (hf *hostFunctions) newLog() *HostFunction {
 
 	log := &HostFunction{
@@ -28,28 +59,79 @@ func (hf *hostFunctions) newLog() *HostFunction {
 				panic(err)
 			}
 
-			lvl, err := m.Memory.ReadBytePack(params[0])
+			lvl, err := m.Memory.ReadBytePack(params[1])
 			if err != nil {
 				panic(err)
 			}
 
-			severity := LogSeverity(lvl)
+			hf.guestLog(ctx, hf.moduleConfig.log, lvl, msg)
+
+			return 0
+
+		},
+		Params:  []ValueType{ValueTypeString, ValueTypeByte},
+		Results: nil,
 
-			switch severity {
-			case LogDebug:
-				hf.moduleConfig.log.Debug(msg)
-			case LogInfo:
-				hf.moduleConfig.log.Info(msg)
-			case LogWarning:
-				hf.moduleConfig.log.Warn(msg)
-			case LogError:
-				hf.moduleConfig.log.Error(msg)
+		// required fields
+		moduleConfig: hf.moduleConfig,
+	}
+
+	return log
+}
+
+// newStructuredLog logs a record with key/value attributes from the guest module,
+// so guest logs land in the host slog handler as structured records.
+//
+// Params:
+//   - message
+//   - severity level, see LogSeverity
+//   - logger name, added as "logger" attribute if not empty
+//   - attributes, encoded by the internal/attrs package
+func (hf *hostFunctions) newStructuredLog() *HostFunction {
+
+	log := &HostFunction{
+		Name: "slog",
+		Callback: func(ctx context.Context, m *ModuleProxy, params []PackedData) MultiPackedData {
+
+			msg, err := m.Memory.ReadStringPack(params[0])
+			if err != nil {
+				panic(err)
+			}
+
+			lvl, err := m.Memory.ReadBytePack(params[1])
+			if err != nil {
+				panic(err)
+			}
+
+			name, err := m.Memory.ReadStringPack(params[2])
+			if err != nil {
+				panic(err)
+			}
+
+			encodedAttrs, err := m.Memory.ReadBytesPack(params[3])
+			if err != nil {
+				panic(err)
+			}
+
+			decodedAttrs, err := attrs.Decode(encodedAttrs)
+			if err != nil {
+				panic(err)
 			}
 
+			args := make([]any, 0, len(decodedAttrs)+1)
+			if name != "" {
+				args = append(args, slog.String("logger", name))
+			}
+			for _, a := range decodedAttrs {
+				args = append(args, slog.Any(a.Key, a.Value))
+			}
+
+			hf.guestLog(ctx, hf.moduleConfig.log, lvl, msg, args...)
+
 			return 0
 
 		},
-		Params:  []ValueType{ValueTypeBytes, ValueTypeBytes},
+		Params:  []ValueType{ValueTypeString, ValueTypeByte, ValueTypeString, ValueTypeBytes},
 		Results: nil,
 
 		// required fields
@@ -58,3 +140,228 @@ func (hf *hostFunctions) newLog() *HostFunction {
 
 	return log
 }
+
+// newTraceParent returns the W3C traceparent of the current span to the guest,
+// so the guest can propagate the trace, e.g. into requests it sends through host functions.
+// It returns no data if tracing is disabled.
+func (hf *hostFunctions) newTraceParent() *HostFunction {
+
+	traceParent := &HostFunction{
+		Name: "trace_parent",
+		Callback: func(ctx context.Context, m *ModuleProxy, params []PackedData) MultiPackedData {
+
+			tp := hf.moduleConfig.tracer.TraceParent(ctx)
+			if tp == "" {
+				return 0
+			}
+
+			return m.Memory.WriteMultiPack(m.Memory.WriteStringPack(tp))
+		},
+		Params:  nil,
+		Results: []ValueType{ValueTypeString},
+
+		// required fields
+		moduleConfig: hf.moduleConfig,
+	}
+
+	return traceParent
+}
+
+// newFuturePoll returns the outcome of a future of an async host function, if it's completed, without blocking.
+// It returns no data if the future is pending, otherwise a MultiPackedData of the results, as a multi pack,
+// and the error of the call, like the dispatcher of the mdk, and forgets the future.
+func (hf *hostFunctions) newFuturePoll() *HostFunction {
+
+	poll := &HostFunction{
+		Name: "future_poll",
+		Callback: func(ctx context.Context, m *ModuleProxy, params []PackedData) MultiPackedData {
+
+			future := hf.future(m, params[0])
+
+			select {
+			case <-future.Done():
+				return hf.readFuture(m, future)
+			default:
+				return 0
+			}
+		},
+		Params:  []ValueType{ValueTypeI64},
+		Results: []ValueType{ValueType(types.ValueTypePack), ValueTypeString},
+
+		// required fields
+		moduleConfig: hf.moduleConfig,
+	}
+
+	return poll
+}
+
+// newFutureWait blocks the guest until a future of an async host function is completed, and returns its outcome as future_poll.
+// The guest traps if the context of the guest function call is done first.
+func (hf *hostFunctions) newFutureWait() *HostFunction {
+
+	wait := &HostFunction{
+		Name: "future_wait",
+		Callback: func(ctx context.Context, m *ModuleProxy, params []PackedData) MultiPackedData {
+
+			future := hf.future(m, params[0])
+
+			select {
+			case <-future.Done():
+				return hf.readFuture(m, future)
+			case <-ctx.Done():
+				panic(errors.Join(fmt.Errorf("can't wait for future %d", future.handle), ctx.Err()))
+			}
+		},
+		Params:  []ValueType{ValueTypeI64},
+		Results: []ValueType{ValueType(types.ValueTypePack), ValueTypeString},
+
+		// required fields
+		moduleConfig: hf.moduleConfig,
+	}
+
+	return wait
+}
+
+// newFutureDrop forgets a future of an async host function the guest won't read.
+// Its callback isn't stopped, the results it completes the future with are discarded.
+func (hf *hostFunctions) newFutureDrop() *HostFunction {
+
+	drop := &HostFunction{
+		Name: "future_drop",
+		Callback: func(ctx context.Context, m *ModuleProxy, params []PackedData) MultiPackedData {
+
+			handle, err := m.Memory.ReadUint64Pack(params[0])
+			if err != nil {
+				panic(err)
+			}
+
+			m.module.futures.remove(handle)
+
+			return 0
+		},
+		Params:  []ValueType{ValueTypeI64},
+		Results: nil,
+
+		// required fields
+		moduleConfig: hf.moduleConfig,
+	}
+
+	return drop
+}
+
+// future returns the pending future whose handle is in pd.
+func (hf *hostFunctions) future(m *ModuleProxy, pd PackedData) *Future {
+
+	handle, err := m.Memory.ReadUint64Pack(pd)
+	if err != nil {
+		panic(err)
+	}
+
+	future, err := m.module.futures.get(handle)
+	if err != nil {
+		panic(err)
+	}
+
+	return future
+}
+
+// readFuture writes the outcome of a completed future into memory and forgets it, its handle is invalid afterwards.
+func (hf *hostFunctions) readFuture(m *ModuleProxy, future *Future) MultiPackedData {
+
+	envelope, err := future.envelope(m.Memory)
+	if err != nil {
+		panic(err)
+	}
+
+	m.module.futures.remove(future.handle)
+
+	return envelope
+}
+
+// newSubscribe subscribes the guest to the events of a topic, which are delivered to its wasify_event export, see EventBus.
+// It returns no data if the guest has been subscribed, the error otherwise, e.g. if the module has no EventBus.
+func (hf *hostFunctions) newSubscribe() *HostFunction {
+
+	subscribe := &HostFunction{
+		Name: "subscribe",
+		Callback: func(ctx context.Context, m *ModuleProxy, params []PackedData) MultiPackedData {
+
+			topic, err := m.Memory.ReadStringPack(params[0])
+			if err != nil {
+				panic(err)
+			}
+
+			if m.module.events == nil {
+				err := fmt.Errorf("module %s has no event bus, can't subscribe to %s", hf.moduleConfig.Namespace, topic)
+				return m.Memory.WriteMultiPack(m.Memory.WriteStringPack(err.Error()))
+			}
+
+			m.module.events.subscribe(topic)
+			hf.moduleConfig.log.DebugContext(ctx, "guest subscribed to events", "topic", topic)
+
+			return 0
+		},
+		Params:  []ValueType{ValueTypeString},
+		Results: []ValueType{ValueTypeString},
+
+		// required fields
+		moduleConfig: hf.moduleConfig,
+	}
+
+	return subscribe
+}
+
+// newUnsubscribe unsubscribes the guest from the events of a topic, the queued events of the topic aren't delivered.
+func (hf *hostFunctions) newUnsubscribe() *HostFunction {
+
+	unsubscribe := &HostFunction{
+		Name: "unsubscribe",
+		Callback: func(ctx context.Context, m *ModuleProxy, params []PackedData) MultiPackedData {
+
+			topic, err := m.Memory.ReadStringPack(params[0])
+			if err != nil {
+				panic(err)
+			}
+
+			if m.module.events != nil {
+				m.module.events.unsubscribe(topic)
+				hf.moduleConfig.log.DebugContext(ctx, "guest unsubscribed from events", "topic", topic)
+			}
+
+			return 0
+		},
+		Params:  []ValueType{ValueTypeString},
+		Results: nil,
+
+		// required fields
+		moduleConfig: hf.moduleConfig,
+	}
+
+	return unsubscribe
+}
+
+// guestLog writes a guest log record, unless the module exceeded its GuestLogRateLimit.
+func (hf *hostFunctions) guestLog(ctx context.Context, log *slog.Logger, lvl byte, msg string, args ...any) {
+
+	ok, dropped := hf.logLimiter.Allow()
+	if !ok {
+		return
+	}
+
+	if dropped > 0 {
+		log.WarnContext(ctx, "guest log records have been dropped by rate limit", "dropped", dropped)
+	}
+
+	severity := LogSeverity(lvl)
+
+	switch severity {
+	case LogDebug:
+		log.DebugContext(ctx, msg, args...)
+	case LogInfo:
+		log.InfoContext(ctx, msg, args...)
+	case LogWarning:
+		log.WarnContext(ctx, msg, args...)
+	case LogError:
+		log.ErrorContext(ctx, msg, args...)
+	}
+}
@@ -0,0 +1,408 @@
+package wasify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go/internal/attrs"
+	"github.com/wasify-io/wasify-go/internal/types"
+	"github.com/wasify-io/wasify-go/internal/utils"
+)
+
+func TestStructuredGuestLog(t *testing.T) {
+
+	ctx := context.Background()
+
+	var logs bytes.Buffer
+
+	runtime, err := NewRuntime(ctx, &RuntimeConfig{
+		Runtime:    RuntimeWazero,
+		LogHandler: slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	binary, err := os.ReadFile("testdata/wasm/guest_all_available_types/main.wasm")
+	assert.NoError(t, err)
+
+	moduleConfig := &ModuleConfig{
+		Namespace:         "guest_all_available_types",
+		Wasm:              Wasm{Binary: binary},
+		GuestLogRateLimit: RateLimit{Rate: 0.001, Burst: 2},
+	}
+
+	module, err := runtime.NewModule(ctx, moduleConfig)
+	assert.NoError(t, err)
+
+	defer func() {
+		err = module.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	memory := module.Memory()
+	proxy := &ModuleProxy{Memory: memory}
+
+	hf := newHostFunctions(moduleConfig)
+	structuredLog := hf.newStructuredLog()
+
+	encoded := attrs.AppendString(nil, "tenant", "acme")
+	encoded = attrs.AppendInt64(encoded, "items", 3)
+
+	logs.Reset()
+
+	structuredLog.Callback(ctx, proxy, []PackedData{
+		memory.WriteStringPack("invoice created"),
+		memory.WriteBytePack(byte(LogWarning)),
+		memory.WriteStringPack("billing"),
+		memory.WriteBytesPack(encoded),
+	})
+
+	assert.Contains(t, logs.String(), `"level":"WARN","msg":"invoice created","namespace":"guest_all_available_types","logger":"billing","tenant":"acme","items":3`)
+
+	// The second record uses the rest of the burst, the third one is dropped.
+	log := hf.newLog()
+	for _, msg := range []string{"second", "third"} {
+		log.Callback(ctx, proxy, []PackedData{
+			memory.WriteStringPack(msg),
+			memory.WriteBytePack(byte(LogInfo)),
+		})
+	}
+
+	assert.Contains(t, logs.String(), `"msg":"second"`)
+	assert.NotContains(t, logs.String(), `"msg":"third"`)
+}
+
+// TestGuestLog logs through the mdk of a guest, which calls the log host function with a string and a level byte,
+// and the slog host function with the name and the attributes of a mdk.Logger.
+func TestGuestLog(t *testing.T) {
+
+	ctx := context.Background()
+
+	var logs bytes.Buffer
+
+	runtime, err := NewRuntime(ctx, &RuntimeConfig{
+		Runtime:    RuntimeWazero,
+		LogHandler: slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	binary, err := os.ReadFile("testdata/wasm/mdk_log/main.wasm")
+	assert.NoError(t, err)
+
+	module, err := runtime.NewModule(ctx, &ModuleConfig{
+		Namespace: "mdk_log",
+		Wasm:      Wasm{Binary: binary},
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = module.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	logs.Reset()
+	_, err = module.GuestFunction(ctx, "log").Invoke("hello")
+	assert.NoError(t, err)
+	assert.Contains(t, logs.String(), `"level":"WARN","msg":"guest says hello","namespace":"mdk_log"`)
+
+	logs.Reset()
+	_, err = module.GuestFunction(ctx, "slog").Invoke("invoice created")
+	assert.NoError(t, err)
+	assert.Contains(t, logs.String(), `"level":"ERROR","msg":"invoice created","namespace":"mdk_log","logger":"billing","tenant":"acme","items":3`)
+}
+
+type traceParentTracer struct{ noopTracer }
+
+func (traceParentTracer) TraceParent(ctx context.Context) string {
+	return "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+}
+
+func TestTraceParent(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := NewRuntime(ctx, &RuntimeConfig{
+		Runtime: RuntimeWazero,
+		Tracer:  traceParentTracer{},
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	binary, err := os.ReadFile("testdata/wasm/guest_all_available_types/main.wasm")
+	assert.NoError(t, err)
+
+	moduleConfig := &ModuleConfig{
+		Namespace: "guest_all_available_types",
+		Wasm:      Wasm{Binary: binary},
+	}
+
+	module, err := runtime.NewModule(ctx, moduleConfig)
+	assert.NoError(t, err)
+
+	defer func() {
+		err = module.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	memory := module.Memory()
+
+	mpd := newHostFunctions(moduleConfig).newTraceParent().Callback(ctx, &ModuleProxy{Memory: memory}, nil)
+
+	// WriteMultiPack must produce a pack array readable like guest results.
+	pds, err := GuestFunctionResult{multiPackedData: uint64(mpd), memory: memory}.ReadPacks()
+	assert.NoError(t, err)
+	assert.Len(t, pds, 1)
+
+	tp, err := memory.ReadStringPack(pds[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", tp)
+}
+
+func TestHostFunctionParamsValidation(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := NewRuntime(ctx, &RuntimeConfig{
+		Runtime:     RuntimeWazero,
+		LogSeverity: LogError,
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	binary, err := os.ReadFile("testdata/wasm/guest_all_available_types/main.wasm")
+	assert.NoError(t, err)
+
+	moduleConfig := &ModuleConfig{
+		Namespace:      "guest_all_available_types",
+		Wasm:           Wasm{Binary: binary},
+		TypeValidation: TypeValidationStrict,
+	}
+
+	module, err := runtime.NewModule(ctx, moduleConfig)
+	assert.NoError(t, err)
+
+	defer func() {
+		err = module.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	memory := module.Memory()
+	proxy := &ModuleProxy{Memory: memory, module: module.(*wazeroModule)}
+
+	hf := &HostFunction{Name: "test", Params: []ValueType{ValueTypeString, ValueTypeI64}, moduleConfig: moduleConfig}
+
+	pack := func(valueType types.ValueType, offset uint32, size uint32) uint64 {
+		pd, err := utils.PackUI64(valueType, offset, size)
+		assert.NoError(t, err)
+		return pd
+	}
+
+	valid := uint64(memory.WriteStringPack("valid"))
+
+	pds, err := hf.preHostFunctionCallback(ctx, proxy, []uint64{valid, uint64(memory.WriteUint64Pack(1))})
+	assert.NoError(t, err)
+	assert.Len(t, pds, 2)
+
+	tests := []struct {
+		name   string
+		params []uint64
+		err    string
+	}{
+		{
+			name:   "params mismatch",
+			params: []uint64{valid},
+			err:    "invalid params: test: params mismatch expected: 2 received: 1",
+		},
+		{
+			name:   "invalid value type",
+			params: []uint64{valid, pack(42, 0, 8)},
+			err:    "invalid value type 42",
+		},
+		{
+			name:   "out of range data",
+			params: []uint64{pack(types.ValueTypeString, memory.Size()-2, 4), valid},
+			err:    "is out of range of memory size",
+		},
+		{
+			name:   "out of range fixed size value",
+			params: []uint64{valid, pack(types.ValueTypeI64, memory.Size()-4, 4)},
+			err:    "ValueTypeI64 at offset " + strconv.Itoa(int(memory.Size()-4)) + " with size 8 is out of range",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := hf.preHostFunctionCallback(ctx, proxy, test.params)
+
+			var paramErr *ParamError
+			assert.ErrorAs(t, err, &paramErr)
+			assert.ErrorContains(t, err, test.err)
+		})
+	}
+
+	t.Run("type mismatch", func(t *testing.T) {
+		mismatched := []uint64{uint64(memory.WriteBytesPack([]byte("valid"))), uint64(memory.WriteUint64Pack(1))}
+
+		_, err := hf.preHostFunctionCallback(ctx, proxy, mismatched)
+
+		var mismatchErr *TypeMismatchError
+		assert.ErrorAs(t, err, &mismatchErr)
+		assert.Equal(t, &TypeMismatchError{Expected: ValueTypeString, Actual: ValueTypeBytes}, mismatchErr)
+		assert.EqualError(t, err, fmt.Sprintf("invalid param 0 (%#x): packed data type mismatch, expected ValueTypeString, got ValueTypeBytes", mismatched[0]))
+
+		moduleConfig.TypeValidation = TypeValidationLenient
+		defer func() { moduleConfig.TypeValidation = TypeValidationStrict }()
+
+		_, err = hf.preHostFunctionCallback(ctx, proxy, mismatched)
+		assert.NoError(t, err)
+	})
+}
+
+func TestReadPackTypeValidation(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := NewRuntime(ctx, &RuntimeConfig{
+		Runtime:     RuntimeWazero,
+		LogSeverity: LogError,
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	binary, err := os.ReadFile("testdata/wasm/guest_all_available_types/main.wasm")
+	assert.NoError(t, err)
+
+	moduleConfig := &ModuleConfig{
+		Namespace:      "guest_all_available_types",
+		Wasm:           Wasm{Binary: binary},
+		TypeValidation: TypeValidationStrict,
+	}
+
+	module, err := runtime.NewModule(ctx, moduleConfig)
+	assert.NoError(t, err)
+
+	defer func() {
+		err = module.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	memory := module.Memory()
+
+	u64, err := memory.ReadUint64Pack(memory.WriteUint64Pack(2023))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2023), u64)
+
+	_, err = memory.ReadUint32Pack(memory.WriteStringPack("four"))
+	assert.Equal(t, &TypeMismatchError{Expected: ValueTypeI32, Actual: ValueTypeString}, err)
+
+	_, err = memory.ReadStringPack(memory.WriteBytesPack([]byte("bytes")))
+	assert.Equal(t, &TypeMismatchError{Expected: ValueTypeString, Actual: ValueTypeBytes}, err)
+
+	// Lenient validation is the default, like before packs were validated.
+	var defaults ModuleConfig
+	moduleConfig.TypeValidation = defaults.TypeValidation
+
+	str, err := memory.ReadStringPack(memory.WriteBytesPack([]byte("bytes")))
+	assert.NoError(t, err)
+	assert.Equal(t, "bytes", str)
+}
+
+// TestLenientTypeMismatchLog tests that lenient type validation warns once per function and value type,
+// and logs the next mismatches of the same types at debug level.
+func TestLenientTypeMismatchLog(t *testing.T) {
+
+	ctx := context.Background()
+
+	var logs bytes.Buffer
+
+	runtime, err := NewRuntime(ctx, &RuntimeConfig{
+		Runtime:     RuntimeWazero,
+		LogSeverity: LogDebug,
+		LogHandler:  slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	binary, err := os.ReadFile("testdata/wasm/guest_all_available_types/main.wasm")
+	assert.NoError(t, err)
+
+	moduleConfig := &ModuleConfig{
+		Namespace: "guest_all_available_types",
+		Wasm:      Wasm{Binary: binary},
+	}
+
+	module, err := runtime.NewModule(ctx, moduleConfig)
+	assert.NoError(t, err)
+
+	defer func() {
+		err = module.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	memory := module.Memory()
+	proxy := &ModuleProxy{Memory: memory, module: module.(*wazeroModule)}
+
+	hf := &HostFunction{Name: "test", Params: []ValueType{ValueTypeString}, moduleConfig: moduleConfig}
+
+	logs.Reset()
+
+	for i := 0; i < 3; i++ {
+		_, err = memory.ReadStringPack(memory.WriteBytesPack([]byte("bytes")))
+		assert.NoError(t, err)
+
+		_, err = hf.preHostFunctionCallback(ctx, proxy, []uint64{uint64(memory.WriteBytesPack([]byte("bytes")))})
+		assert.NoError(t, err)
+	}
+
+	// A different value type is warned about again.
+	_, err = memory.ReadStringPack(memory.WriteUint64Pack(1))
+	assert.NoError(t, err)
+
+	levels := map[string]int{}
+	for _, line := range bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n")) {
+		var record struct {
+			Level string
+			Msg   string
+		}
+		assert.NoError(t, json.Unmarshal(line, &record))
+
+		if strings.Contains(record.Msg, "packed data type mismatch") {
+			levels[record.Level]++
+		}
+	}
+
+	assert.Equal(t, map[string]int{"WARN": 3, "DEBUG": 4}, levels)
+}
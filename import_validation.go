@@ -0,0 +1,155 @@
+package wasify
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// wasiNamespace is the namespace of WASI functions, which are provided by the runtime itself.
+const wasiNamespace = "wasi_snapshot_preview1"
+
+// ImportMismatch describes a guest import whose signature differs from the declared host function.
+type ImportMismatch struct {
+	// Imported is the function as the guest imports it.
+	Imported FunctionDefinition
+
+	// Declared is the function as the host declares it.
+	Declared FunctionDefinition
+}
+
+// ImportReport is the result of comparing the functions a guest imports
+// against the host functions declared in ModuleConfig.
+type ImportReport struct {
+	// Missing lists functions imported by the guest which the host does not provide.
+	Missing []FunctionDefinition
+
+	// Extra lists host functions declared in ModuleConfig.HostFunctions which the guest does not import.
+	// Extra functions do not prevent instantiation.
+	Extra []FunctionDefinition
+
+	// Mismatched lists functions provided by the host with a different signature than the guest imports.
+	Mismatched []ImportMismatch
+
+	// Unknown lists functions imported from namespaces the ModuleConfig doesn't provide,
+	// e.g. other modules instantiated in the same runtime. They can't be validated,
+	// so they do not prevent instantiation, the runtime resolves them.
+	Unknown []FunctionDefinition
+}
+
+// HasErrors reports whether the guest can't be instantiated against the declared host functions.
+func (r *ImportReport) HasErrors() bool {
+	return len(r.Missing) > 0 || len(r.Mismatched) > 0
+}
+
+// String returns a human readable, multi-line report.
+func (r *ImportReport) String() string {
+
+	var sb strings.Builder
+
+	for _, fn := range r.Missing {
+		fmt.Fprintf(&sb, "missing: %s is imported by the guest, but not provided by the host\n", fn)
+	}
+
+	for _, m := range r.Mismatched {
+		fmt.Fprintf(&sb, "mismatched: %s.%s is imported as %s, but declared as %s\n", m.Imported.Namespace, m.Imported.Name, m.Imported.Signature(), m.Declared.Signature())
+	}
+
+	for _, fn := range r.Extra {
+		fmt.Fprintf(&sb, "extra: %s is declared by the host, but not imported by the guest\n", fn)
+	}
+
+	for _, fn := range r.Unknown {
+		fmt.Fprintf(&sb, "unknown: %s is imported from a namespace the host doesn't provide\n", fn)
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// ImportValidationError is returned by NewModule when the guest imports don't match the declared host functions.
+type ImportValidationError struct {
+	Namespace string
+	Report    *ImportReport
+}
+
+func (e *ImportValidationError) Error() string {
+	return fmt.Sprintf("guest imports don't match host functions of module %s:\n%s", e.Namespace, e.Report)
+}
+
+// ValidateImports compares the functions imported by a compiled module with the host functions
+// declared in moduleConfig and with the pre-defined wasify host functions.
+//
+// Imports from the WASI namespace are skipped, because they are provided by the runtime.
+// Imports from any other namespace than moduleConfig.Namespace or WASIFY_NAMESPACE are reported as unknown,
+// they may be provided by other modules of the runtime.
+func ValidateImports(info ModuleInfo, moduleConfig *ModuleConfig) *ImportReport {
+
+	report := new(ImportReport)
+
+	// Collect the signatures the host provides, per namespace.
+	declared := map[string]map[string]FunctionDefinition{
+		moduleConfig.Namespace: {},
+		WASIFY_NAMESPACE:       {},
+	}
+
+	for _, hf := range moduleConfig.HostFunctions {
+		declared[moduleConfig.Namespace][hf.Name] = hf.definition(moduleConfig.Namespace)
+	}
+
+	for _, hf := range newHostFunctions(moduleConfig).all() {
+		declared[WASIFY_NAMESPACE][hf.Name] = hf.definition(WASIFY_NAMESPACE)
+	}
+
+	imported := info.ImportedFunctions()
+
+	for namespace, fns := range imported {
+
+		if namespace == wasiNamespace {
+			continue
+		}
+
+		// Only the namespaces of this ModuleConfig can be validated.
+		if _, ok := declared[namespace]; !ok {
+			report.Unknown = append(report.Unknown, fns...)
+			continue
+		}
+
+		for _, fn := range fns {
+			def, ok := declared[namespace][fn.Name]
+			switch {
+			case !ok:
+				report.Missing = append(report.Missing, fn)
+			case !slices.Equal(def.Params, fn.Params) || !slices.Equal(def.Results, fn.Results):
+				report.Mismatched = append(report.Mismatched, ImportMismatch{Imported: fn, Declared: def})
+			}
+		}
+	}
+
+	// Only user-defined host functions are reported as extra,
+	// pre-defined ones are always available but rarely all imported.
+	for name, def := range declared[moduleConfig.Namespace] {
+		if !slices.ContainsFunc(imported[moduleConfig.Namespace], func(fn FunctionDefinition) bool { return fn.Name == name }) {
+			report.Extra = append(report.Extra, def)
+		}
+	}
+
+	// Map iteration order is random, keep the report stable.
+	sortDefinitions(report.Missing)
+	sortDefinitions(report.Extra)
+	sortDefinitions(report.Unknown)
+	sort.Slice(report.Mismatched, func(i, j int) bool {
+		return report.Mismatched[i].Imported.String() < report.Mismatched[j].Imported.String()
+	})
+
+	return report
+}
+
+func sortDefinitions(defs []FunctionDefinition) {
+	sort.Slice(defs, func(i, j int) bool {
+		if defs[i].Namespace != defs[j].Namespace {
+			return defs[i].Namespace < defs[j].Namespace
+		}
+		return defs[i].Name < defs[j].Name
+	})
+}
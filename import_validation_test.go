@@ -0,0 +1,93 @@
+package wasify_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go"
+)
+
+func TestValidateImports(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+		Runtime:     wasify.RuntimeWazero,
+		LogSeverity: wasify.LogError,
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	compiled, err := runtime.CompileModule(ctx, wasify.Wasm{Binary: wasm_hostAllAvailableTypes})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = compiled.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	callback := func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {
+		return 0
+	}
+
+	t.Run("matching", func(t *testing.T) {
+		report := wasify.ValidateImports(compiled, &wasify.ModuleConfig{
+			Namespace: "host_all_available_types",
+			HostFunctions: []wasify.HostFunction{
+				{Name: "hostTest", Callback: callback, Params: make([]wasify.ValueType, 7), Results: make([]wasify.ValueType, 7)},
+			},
+		})
+		assert.False(t, report.HasErrors())
+		assert.Empty(t, report.String())
+	})
+
+	t.Run("missing, extra and mismatched", func(t *testing.T) {
+		report := wasify.ValidateImports(compiled, &wasify.ModuleConfig{
+			Namespace: "host_all_available_types",
+			HostFunctions: []wasify.HostFunction{
+				{Name: "hostTest", Callback: callback, Params: make([]wasify.ValueType, 2)},
+				{Name: "unused", Callback: callback},
+			},
+		})
+		assert.True(t, report.HasErrors())
+		assert.Empty(t, report.Missing)
+		assert.Len(t, report.Mismatched, 1)
+		assert.Equal(t, "(i64, i64) -> ()", report.Mismatched[0].Declared.Signature())
+		assert.Len(t, report.Extra, 1)
+		assert.Equal(t, "unused", report.Extra[0].Name)
+
+	})
+
+	t.Run("unknown namespace", func(t *testing.T) {
+		// The guest may import another module of the runtime, which the ModuleConfig doesn't describe.
+		report := wasify.ValidateImports(compiled, &wasify.ModuleConfig{
+			Namespace: "other_namespace",
+		})
+		assert.False(t, report.HasErrors())
+		assert.Empty(t, report.Missing)
+		assert.Len(t, report.Unknown, 1)
+		assert.Equal(t, "host_all_available_types.hostTest(i64, i64, i64, i64, i64, i64, i64) -> (i64)", report.Unknown[0].String())
+		assert.Contains(t, report.String(), "unknown: host_all_available_types.hostTest")
+	})
+
+	t.Run("NewModule rejects mismatched imports", func(t *testing.T) {
+		module, err := runtime.NewModule(ctx, &wasify.ModuleConfig{
+			Namespace: "host_all_available_types",
+			Wasm: wasify.Wasm{
+				Binary: wasm_hostAllAvailableTypes,
+			},
+		})
+		assert.Nil(t, module)
+
+		var validationErr *wasify.ImportValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Len(t, validationErr.Report.Missing, 1)
+		assert.Contains(t, err.Error(), "missing: host_all_available_types.hostTest")
+	})
+}
@@ -0,0 +1,135 @@
+package wasify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/wasify-io/wasify-go/internal/types"
+	"github.com/wasify-io/wasify-go/internal/utils"
+)
+
+// Call is a guest or host function call passed through the interceptor chain.
+type Call struct {
+	Kind CallKind
+
+	// Namespace of the module the call belongs to.
+	// For host functions, it's the namespace the function is imported from, e.g. WASIFY_NAMESPACE.
+	Namespace string
+
+	// Function is the name of the guest or host function.
+	Function string
+
+	// Args are the decoded arguments, e.g. []byte, byte, uint32, uint64, float32, float64 or string.
+	//
+	// For guest function calls, Args are the params passed to Invoke, an interceptor may replace them before calling next.
+	// For host function calls, Args are read from the guest memory, changing them has no effect on the callback.
+	Args []any
+
+	// Memory of the module the call runs in.
+	Memory Memory
+}
+
+// Invoker runs a call and returns its results.
+type Invoker func(ctx context.Context, call *Call) (MultiPackedData, error)
+
+// Interceptor wraps guest function invocations and host function callbacks,
+// e.g. to authorize, log or measure calls without repeating it in every HostFunctionCallback.
+//
+// An interceptor must call next to continue the call, or return an error to abort it.
+// For guest function calls, the error is returned by Invoke.
+// For host function calls, the error aborts the guest, and is returned by the Invoke which called the guest.
+//
+// Interceptors set in RuntimeConfig run before the ones set in ModuleConfig, each list in its order.
+//
+// Example:
+//
+//	func logCalls(ctx context.Context, call *wasify.Call, next wasify.Invoker) (wasify.MultiPackedData, error) {
+//		results, err := next(ctx, call)
+//		values, _ := call.ReadResults(results)
+//		slog.Info("call", "function", call.Function, "args", call.Args, "results", values, "err", err)
+//		return results, err
+//	}
+type Interceptor func(ctx context.Context, call *Call, next Invoker) (MultiPackedData, error)
+
+// ReadResults decodes the results returned by next.
+// Unlike GuestFunctionResult.ReadPacks, it doesn't free the results, so they can still be read by the caller.
+func (c *Call) ReadResults(mpd MultiPackedData) ([]any, error) {
+
+	pds, err := readPacks(c.Memory, uint64(mpd))
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(pds))
+
+	for i, pd := range pds {
+		values[i], _, _, err = c.Memory.ReadAnyPack(pd)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("can't read result %d of %s", i, c.Function), err)
+		}
+	}
+
+	return values, nil
+}
+
+// readArgs decodes the params of a host function call.
+func readArgs(memory Memory, params []PackedData) ([]any, error) {
+
+	args := make([]any, len(params))
+
+	for i, pd := range params {
+		var err error
+
+		args[i], _, _, err = memory.ReadAnyPack(pd)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("can't read param %d", i), err)
+		}
+	}
+
+	return args, nil
+}
+
+// chainInterceptors returns an Invoker which runs the interceptors in order, and then invoker.
+func chainInterceptors(interceptors []Interceptor, invoker Invoker) Invoker {
+
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context, call *Call) (MultiPackedData, error) {
+			return interceptor(ctx, call, next)
+		}
+	}
+
+	return invoker
+}
+
+// readPacks reads the packed data array referenced by multiPackedData.
+func readPacks(memory Memory, multiPackedData uint64) ([]PackedData, error) {
+
+	if multiPackedData == 0 {
+		return nil, nil
+	}
+
+	t, offsetU32, size := utils.UnpackUI64(multiPackedData)
+
+	if t != types.ValueTypePack {
+		err := fmt.Errorf("Can't unpack host data, the type is not a valueTypePack. expected %d, got %d", types.ValueTypePack, t)
+		return nil, err
+	}
+
+	bytes, err := memory.ReadBytes(offsetU32, size)
+	if err != nil {
+		err := errors.Join(errors.New("ReadPacks error, can't read bytes:"), err)
+		return nil, err
+	}
+
+	packedDataArray := utils.BytesToUint64Array(bytes)
+
+	results := make([]PackedData, len(packedDataArray))
+
+	for i, pd := range packedDataArray {
+		results[i] = PackedData(pd)
+	}
+
+	return results, nil
+}
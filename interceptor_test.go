@@ -0,0 +1,165 @@
+package wasify_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go"
+)
+
+func TestInterceptors(t *testing.T) {
+
+	ctx := context.Background()
+
+	var events []string
+
+	record := func(name string) wasify.Interceptor {
+		return func(ctx context.Context, call *wasify.Call, next wasify.Invoker) (wasify.MultiPackedData, error) {
+			events = append(events, name+" "+call.Kind.String()+" "+call.Function)
+			return next(ctx, call)
+		}
+	}
+
+	var hostArgs, hostResults []any
+	denyHost := false
+
+	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+		Runtime:      wasify.RuntimeWazero,
+		Interceptors: []wasify.Interceptor{record("runtime")},
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	module, err := runtime.NewModule(ctx, &wasify.ModuleConfig{
+		Namespace: "host_all_available_types",
+		Wasm: wasify.Wasm{
+			Binary: wasm_hostAllAvailableTypes,
+		},
+		Interceptors: []wasify.Interceptor{
+			record("module"),
+			func(ctx context.Context, call *wasify.Call, next wasify.Invoker) (wasify.MultiPackedData, error) {
+				if call.Kind != wasify.CallHostFunction {
+					return next(ctx, call)
+				}
+
+				if denyHost {
+					return 0, errors.New("permission denied")
+				}
+
+				hostArgs = call.Args
+
+				results, err := next(ctx, call)
+				hostResults, _ = call.ReadResults(results)
+
+				return results, err
+			},
+		},
+		HostFunctions: []wasify.HostFunction{
+			{
+				Name: "hostTest",
+				Callback: func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {
+					return m.Memory.WriteMultiPack(
+						m.Memory.WriteStringPack("Host: Wasify."),
+						m.Memory.WriteUint32Pack(11),
+					)
+				},
+				Params: []wasify.ValueType{
+					wasify.ValueTypeBytes,
+					wasify.ValueTypeByte,
+					wasify.ValueTypeI32,
+					wasify.ValueTypeI64,
+					wasify.ValueTypeF32,
+					wasify.ValueTypeF64,
+					wasify.ValueTypeString,
+				},
+				Results: []wasify.ValueType{wasify.ValueTypeString, wasify.ValueTypeI32},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = module.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	t.Run("chain order and decoded values", func(t *testing.T) {
+
+		_, err := module.GuestFunction(ctx, "guestTest").Invoke()
+		assert.NoError(t, err)
+
+		assert.Equal(t, []string{
+			"runtime guest guestTest",
+			"module guest guestTest",
+			"runtime host hostTest",
+			"module host hostTest",
+		}, events)
+
+		assert.Equal(t, []any{
+			[]byte("Guest: Wello Wasify!"),
+			byte(1),
+			uint32(11),
+			uint64(2023),
+			float32(11.1),
+			float64(11.2023),
+			"Guest: Wasify.",
+		}, hostArgs)
+
+		assert.Equal(t, []any{"Host: Wasify.", uint32(11)}, hostResults)
+	})
+
+	t.Run("host call aborted by an interceptor", func(t *testing.T) {
+
+		denyHost = true
+		defer func() { denyHost = false }()
+
+		_, err := module.GuestFunction(ctx, "guestTest").Invoke()
+		assert.ErrorContains(t, err, "permission denied")
+	})
+}
+
+func TestInterceptorAbortsGuestCall(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+		Runtime: wasify.RuntimeWazero,
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	module, err := runtime.NewModule(ctx, &wasify.ModuleConfig{
+		Namespace: "guest_all_available_types",
+		Wasm: wasify.Wasm{
+			Binary: wasm_guestAllAvailableTypes,
+		},
+		Interceptors: []wasify.Interceptor{
+			func(ctx context.Context, call *wasify.Call, next wasify.Invoker) (wasify.MultiPackedData, error) {
+				if len(call.Args) != 7 {
+					return 0, errors.New("guestTest expects 7 args")
+				}
+				return next(ctx, call)
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = module.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	res, err := module.GuestFunction(ctx, "guestTest").Invoke("too few")
+	assert.Nil(t, res)
+	assert.EqualError(t, err, "guestTest expects 7 args")
+}
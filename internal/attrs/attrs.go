@@ -0,0 +1,148 @@
+// Package attrs encodes key/value log attributes into a compact binary form,
+// so they can be passed from a guest to the host in a single PackedData.
+//
+// Each attribute is encoded as:
+//   - key: uint32 length (little-endian) followed by the key bytes
+//   - kind: one byte, see Kind
+//   - value: uint32 length followed by the bytes for KindString,
+//     8 bytes (little-endian) for KindInt64, KindUint64 and KindFloat64,
+//     1 byte for KindBool
+package attrs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Kind is the type of an attribute value.
+type Kind uint8
+
+const (
+	KindString Kind = iota
+	KindInt64
+	KindUint64
+	KindFloat64
+	KindBool
+)
+
+// Attr is a decoded attribute. Value is one of string, int64, uint64, float64 or bool.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// AppendString appends a string attribute to buf.
+func AppendString(buf []byte, key string, v string) []byte {
+	buf = appendKey(buf, key, KindString)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(v)))
+	return append(buf, v...)
+}
+
+// AppendInt64 appends an int64 attribute to buf.
+func AppendInt64(buf []byte, key string, v int64) []byte {
+	buf = appendKey(buf, key, KindInt64)
+	return binary.LittleEndian.AppendUint64(buf, uint64(v))
+}
+
+// AppendUint64 appends a uint64 attribute to buf.
+func AppendUint64(buf []byte, key string, v uint64) []byte {
+	buf = appendKey(buf, key, KindUint64)
+	return binary.LittleEndian.AppendUint64(buf, v)
+}
+
+// AppendFloat64 appends a float64 attribute to buf.
+func AppendFloat64(buf []byte, key string, v float64) []byte {
+	buf = appendKey(buf, key, KindFloat64)
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+}
+
+// AppendBool appends a bool attribute to buf.
+func AppendBool(buf []byte, key string, v bool) []byte {
+	buf = appendKey(buf, key, KindBool)
+	if v {
+		return append(buf, 1)
+	}
+	return append(buf, 0)
+}
+
+func appendKey(buf []byte, key string, kind Kind) []byte {
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(key)))
+	buf = append(buf, key...)
+	return append(buf, byte(kind))
+}
+
+// Decode decodes all attributes encoded in buf.
+func Decode(buf []byte) ([]Attr, error) {
+
+	var res []Attr
+
+	for len(buf) > 0 {
+
+		key, rest, err := readString(buf)
+		if err != nil {
+			return nil, errors.Join(errors.New("can't decode attribute key"), err)
+		}
+
+		if len(rest) < 1 {
+			return nil, fmt.Errorf("missing kind of attribute %s", key)
+		}
+		kind, rest := Kind(rest[0]), rest[1:]
+
+		var v any
+
+		switch kind {
+		case KindString:
+			v, rest, err = readString(rest)
+		case KindInt64, KindUint64, KindFloat64:
+			if len(rest) < 8 {
+				err = errors.New("value is truncated")
+				break
+			}
+			u := binary.LittleEndian.Uint64(rest)
+			rest = rest[8:]
+			switch kind {
+			case KindInt64:
+				v = int64(u)
+			case KindUint64:
+				v = u
+			default:
+				v = math.Float64frombits(u)
+			}
+		case KindBool:
+			if len(rest) < 1 {
+				err = errors.New("value is truncated")
+				break
+			}
+			v, rest = rest[0] != 0, rest[1:]
+		default:
+			err = fmt.Errorf("unsupported kind %d", kind)
+		}
+
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("can't decode value of attribute %s", key), err)
+		}
+
+		res = append(res, Attr{key, v})
+		buf = rest
+	}
+
+	return res, nil
+}
+
+func readString(buf []byte) (string, []byte, error) {
+
+	if len(buf) < 4 {
+		return "", nil, errors.New("length is truncated")
+	}
+
+	size := binary.LittleEndian.Uint32(buf)
+	buf = buf[4:]
+
+	if uint64(size) > uint64(len(buf)) {
+		return "", nil, fmt.Errorf("length %d exceeds remaining %d bytes", size, len(buf))
+	}
+
+	return string(buf[:size]), buf[size:], nil
+}
@@ -0,0 +1,37 @@
+package attrs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeDecode(t *testing.T) {
+
+	var buf []byte
+	buf = AppendString(buf, "user", "wasify")
+	buf = AppendInt64(buf, "delta", -42)
+	buf = AppendUint64(buf, "count", 42)
+	buf = AppendFloat64(buf, "ratio", 0.5)
+	buf = AppendBool(buf, "ok", true)
+
+	decoded, err := Decode(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, []Attr{
+		{"user", "wasify"},
+		{"delta", int64(-42)},
+		{"count", uint64(42)},
+		{"ratio", 0.5},
+		{"ok", true},
+	}, decoded)
+
+	decoded, err = Decode(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, decoded)
+
+	_, err = Decode(buf[:len(buf)-1])
+	assert.Error(t, err)
+
+	_, err = Decode(append(appendKey(nil, "invalid", Kind(255)), 0))
+	assert.Error(t, err)
+}
@@ -0,0 +1,148 @@
+package codegen
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go/internal/types"
+)
+
+const fixture = "../../testdata/wasm/codegen/"
+
+// TestGenerateFixture checks the generated code of the codegen fixture is up to date.
+func TestGenerateFixture(t *testing.T) {
+
+	c, err := ParseFile(fixture+"contract/contract.go", Config{
+		Namespace:      "codegen",
+		HostInterface:  "Host",
+		GuestInterface: "Guest",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "github.com/wasify-io/wasify-go/testdata/wasm/codegen/contract", c.ImportPath)
+
+	for _, tt := range []struct {
+		file     string
+		generate func(*Contract, string) ([]byte, error)
+		pkg      string
+	}{
+		{"host/wasify_gen.go", GenerateHost, "host"},
+		{"guest/wasify_gen.go", GenerateGuest, "main"},
+	} {
+		expected, err := os.ReadFile(fixture + tt.file)
+		assert.NoError(t, err)
+
+		src, err := tt.generate(c, tt.pkg)
+		assert.NoError(t, err)
+		assert.Equal(t, string(expected), string(src), "%s is out of date, run go generate", tt.file)
+	}
+}
+
+func TestParse(t *testing.T) {
+
+	config := Config{
+		Namespace:      "test",
+		HostInterface:  "Host",
+		GuestInterface: "Guest",
+		ImportPath:     "example.com/contract",
+	}
+
+	t.Run("functions", func(t *testing.T) {
+
+		c, err := Parse("contract.go", []byte(`package contract
+
+import "context"
+
+type ID uint64
+type Name = string
+type Blob []uint8
+
+type Host interface {
+	GetUserID(ctx context.Context, name Name) (ID, error)
+	Store(_ Blob, a, b float32) error
+}
+`), config)
+		assert.NoError(t, err)
+
+		assert.Equal(t, []Function{
+			{
+				Method:  "GetUserID",
+				Name:    "get_user_id",
+				Context: true,
+				Params:  []Value{{Name: "name", Type: "contract.Name", ValueType: types.ValueTypeString}},
+				Results: []Value{{Type: "contract.ID", ValueType: types.ValueTypeI64}},
+			},
+			{
+				Method: "Store",
+				Name:   "store",
+				Params: []Value{
+					{Type: "contract.Blob", ValueType: types.ValueTypeBytes},
+					{Name: "a", Type: "float32", ValueType: types.ValueTypeF32},
+					{Name: "b", Type: "float32", ValueType: types.ValueTypeF32},
+				},
+			},
+		}, c.Host)
+		assert.Empty(t, c.Guest)
+		assert.Empty(t, c.GuestInterface)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+
+		tests := map[string]string{
+			"no interfaces":     `type Other interface{ Run() error }`,
+			"no error":          `type Host interface{ Run() string }`,
+			"unsupported param": `type Host interface{ Run(int) error }`,
+			"unsupported named": `type T int; type Host interface{ Run(T) error }`,
+			"recursive named":   `type A B; type B A; type Host interface{ Run(A) error }`,
+			"guest context":     `type Guest interface{ Run(ctx context.Context) error }`,
+			"embedded":          `type Host interface{ Other }`,
+			"same wasm name":    `type Host interface{ UserID() error; UserId() error }`,
+			"unsupported array": `type Host interface{ Run([4]byte) error }`,
+			"unsupported slice": `type Host interface{ Run([]string) error }`,
+		}
+
+		for name, src := range tests {
+			_, err := Parse("contract.go", []byte("package contract\n"+src), config)
+			assert.Error(t, err, name)
+		}
+
+		_, err := Parse("contract.go", []byte("package contract\ntype Host interface{}"), Config{HostInterface: "Host"})
+		assert.Error(t, err, "namespace is required")
+	})
+}
+
+func TestGenerateChecksPackage(t *testing.T) {
+
+	c := &Contract{Package: "mdk", HostInterface: "Host"}
+
+	_, err := GenerateGuest(c, "main")
+	assert.Error(t, err)
+
+	c.Package = "main"
+	_, err = GenerateGuest(c, "main")
+	assert.Error(t, err)
+}
+
+func TestSnakeCase(t *testing.T) {
+
+	for name, expected := range map[string]string{
+		"Greet":       "greet",
+		"TraceParent": "trace_parent",
+		"UserID":      "user_id",
+		"HTTPRequest": "http_request",
+		"Sha256Sum":   "sha256_sum",
+		"getValue":    "get_value",
+	} {
+		assert.Equal(t, expected, snakeCase(name))
+	}
+}
+
+func TestImportPath(t *testing.T) {
+
+	path, err := ImportPath(".")
+	assert.NoError(t, err)
+	assert.Equal(t, "github.com/wasify-io/wasify-go/internal/codegen", path)
+
+	assert.Equal(t, "example.com/m", modulePath([]byte("// comment\nmodule \"example.com/m\"\n\ngo 1.21\n")))
+	assert.Empty(t, modulePath([]byte("modules example.com/m\n")))
+}
@@ -0,0 +1,419 @@
+// Package codegen generates the host and guest code of a contract, a Go file declaring
+// the interface of the host functions and the interface of the guest functions of a module.
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/wasify-io/wasify-go/internal/types"
+)
+
+// Contract is the definition shared by the host and the guest.
+type Contract struct {
+	// Source is the file name of the contract.
+	Source string
+
+	// Package is the package name of the contract, used to qualify its types in the generated code.
+	Package string
+
+	// ImportPath is the import path of the contract package.
+	ImportPath string
+
+	// Namespace is the namespace the guest imports the host functions from.
+	Namespace string
+
+	// HostInterface and GuestInterface are the names of the interfaces, empty if the contract doesn't declare them.
+	HostInterface  string
+	GuestInterface string
+
+	Host  []Function
+	Guest []Function
+
+	// hasContext reports whether a host function takes a context.
+	hasContext bool
+}
+
+// Function is a method of the host or guest interface.
+type Function struct {
+	// Method is the name of the interface method.
+	Method string
+
+	// Name is the name of the function in wasm, the method name in snake case, e.g. "trace_parent".
+	Name string
+
+	// Context reports whether the first param of the method is a context.Context, which isn't passed to wasm.
+	Context bool
+
+	Params []Value
+
+	// Results are the results of the method, without the trailing error.
+	Results []Value
+}
+
+// Value is a param or a result of a Function.
+type Value struct {
+	// Name of the param, empty if the method doesn't name it.
+	Name string
+
+	// Type is the Go type as written in the generated code, e.g. "string" or "contract.Celsius".
+	Type string
+
+	// ValueType is the type the value is packed as.
+	ValueType types.ValueType
+}
+
+// Config selects what to parse from the contract file.
+type Config struct {
+	Namespace string
+
+	// HostInterface and GuestInterface are the names of the interfaces, e.g. "Host" and "Guest".
+	// A missing interface is ignored, but at least one must be declared.
+	HostInterface  string
+	GuestInterface string
+
+	// ImportPath of the contract package. If empty, it's resolved from the go.mod the contract file belongs to.
+	ImportPath string
+}
+
+// ParseFile parses the contract file.
+func ParseFile(filename string, config Config) (*Contract, error) {
+
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.ImportPath == "" {
+		config.ImportPath, err = ImportPath(filepath.Dir(filename))
+		if err != nil {
+			return nil, errors.Join(errors.New("can't resolve the import path of the contract, set it explicitly"), err)
+		}
+	}
+
+	return Parse(filename, src, config)
+}
+
+// Parse parses the source of a contract file.
+//
+// Methods of the interfaces take and return []byte, byte, uint32, uint64, float32, float64 and string,
+// or types declared in the contract based on them, and return an error as their last result.
+// Methods of the host interface may also take a context.Context as their first param.
+func Parse(filename string, src []byte, config Config) (*Contract, error) {
+
+	if config.Namespace == "" {
+		return nil, errors.New("namespace is required")
+	}
+
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Contract{
+		Source:     filepath.Base(filename),
+		Package:    file.Name.Name,
+		ImportPath: config.ImportPath,
+		Namespace:  config.Namespace,
+	}
+
+	p := &contractParser{
+		contract: c,
+		named:    make(map[string]ast.Expr),
+	}
+
+	var host, guest *ast.InterfaceType
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+
+			switch iface, _ := ts.Type.(*ast.InterfaceType); {
+			case iface != nil && ts.Name.Name == config.HostInterface:
+				host, c.HostInterface = iface, ts.Name.Name
+			case iface != nil && ts.Name.Name == config.GuestInterface:
+				guest, c.GuestInterface = iface, ts.Name.Name
+			case ts.TypeParams == nil:
+				p.named[ts.Name.Name] = ts.Type
+			}
+		}
+	}
+
+	if host == nil && guest == nil {
+		return nil, fmt.Errorf("%s declares neither the %s nor the %s interface", filename, config.HostInterface, config.GuestInterface)
+	}
+
+	if host != nil {
+		if c.Host, err = p.functions(host, true); err != nil {
+			return nil, errors.Join(fmt.Errorf("invalid %s interface", c.HostInterface), err)
+		}
+	}
+
+	if guest != nil {
+		if c.Guest, err = p.functions(guest, false); err != nil {
+			return nil, errors.Join(fmt.Errorf("invalid %s interface", c.GuestInterface), err)
+		}
+	}
+
+	return c, nil
+}
+
+type contractParser struct {
+	contract *Contract
+
+	// named are the types declared in the contract, by name.
+	named map[string]ast.Expr
+}
+
+func (p *contractParser) functions(iface *ast.InterfaceType, host bool) ([]Function, error) {
+
+	var functions []Function
+
+	names := make(map[string]string)
+
+	for _, m := range iface.Methods.List {
+		ft, ok := m.Type.(*ast.FuncType)
+		if !ok || len(m.Names) != 1 {
+			return nil, errors.New("embedded interfaces are not supported")
+		}
+
+		fn := Function{
+			Method: m.Names[0].Name,
+			Name:   snakeCase(m.Names[0].Name),
+		}
+
+		if method, ok := names[fn.Name]; ok {
+			return nil, fmt.Errorf("methods %s and %s have the same wasm name %s", method, fn.Method, fn.Name)
+		}
+		names[fn.Name] = fn.Method
+
+		params := fields(ft.Params)
+
+		if len(params) > 0 && isContext(params[0].Type) {
+			if !host {
+				return nil, fmt.Errorf("%s: guest functions can't take a context.Context", fn.Method)
+			}
+			fn.Context, p.contract.hasContext = true, true
+			params = params[1:]
+		}
+
+		for i, f := range params {
+			v, err := p.value(f.Name, f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s: param %d: %w", fn.Method, i, err)
+			}
+			fn.Params = append(fn.Params, v)
+		}
+
+		results := fields(ft.Results)
+
+		if len(results) == 0 || !isIdent(results[len(results)-1].Type, "error") {
+			return nil, fmt.Errorf("%s: the last result must be an error", fn.Method)
+		}
+
+		for i, f := range results[:len(results)-1] {
+			v, err := p.value(f.Name, f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s: result %d: %w", fn.Method, i, err)
+			}
+			fn.Results = append(fn.Results, v)
+		}
+
+		functions = append(functions, fn)
+	}
+
+	return functions, nil
+}
+
+// value resolves the ValueType of a type expression of the contract.
+func (p *contractParser) value(name string, expr ast.Expr) (Value, error) {
+
+	v := Value{Name: name}
+
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if vt, ok := basicValueTypes[t.Name]; ok {
+			v.Type, v.ValueType = t.Name, vt
+			return v, nil
+		}
+
+		if underlying, ok := p.named[t.Name]; ok {
+			// Hide the type while resolving it, so recursive declarations fail instead of looping.
+			delete(p.named, t.Name)
+			resolved, err := p.value(name, underlying)
+			p.named[t.Name] = underlying
+
+			if err != nil {
+				return v, fmt.Errorf("type %s: %w", t.Name, err)
+			}
+			v.Type, v.ValueType = p.contract.Package+"."+t.Name, resolved.ValueType
+			return v, nil
+		}
+	case *ast.ArrayType:
+		if elem, ok := t.Elt.(*ast.Ident); ok && t.Len == nil && (elem.Name == "byte" || elem.Name == "uint8") {
+			v.Type, v.ValueType = "[]"+elem.Name, types.ValueTypeBytes
+			return v, nil
+		}
+	}
+
+	return v, fmt.Errorf("unsupported type %s", typeString(expr))
+}
+
+var basicValueTypes = map[string]types.ValueType{
+	"byte":    types.ValueTypeByte,
+	"uint8":   types.ValueTypeByte,
+	"uint32":  types.ValueTypeI32,
+	"uint64":  types.ValueTypeI64,
+	"float32": types.ValueTypeF32,
+	"float64": types.ValueTypeF64,
+	"string":  types.ValueTypeString,
+}
+
+type field struct {
+	Name string
+	Type ast.Expr
+}
+
+// fields flattens a field list, so grouped params like (a, b string) become one field each.
+func fields(list *ast.FieldList) []field {
+
+	if list == nil {
+		return nil
+	}
+
+	var res []field
+
+	for _, f := range list.List {
+		if len(f.Names) == 0 {
+			res = append(res, field{Type: f.Type})
+			continue
+		}
+
+		for _, n := range f.Names {
+			name := n.Name
+			if name == "_" {
+				name = ""
+			}
+			res = append(res, field{Name: name, Type: f.Type})
+		}
+	}
+
+	return res
+}
+
+func isIdent(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
+}
+
+func isContext(expr ast.Expr) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	return ok && isIdent(sel.X, "context") && sel.Sel.Name == "Context"
+}
+
+func typeString(expr ast.Expr) string {
+
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return typeString(t.X) + "." + t.Sel.Name
+	case *ast.StarExpr:
+		return "*" + typeString(t.X)
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "[]" + typeString(t.Elt)
+		}
+		return "[...]" + typeString(t.Elt)
+	case *ast.MapType:
+		return "map[" + typeString(t.Key) + "]" + typeString(t.Value)
+	}
+
+	return fmt.Sprintf("%T", expr)
+}
+
+// snakeCase converts a method name to snake case, e.g. "TraceParent" to "trace_parent" and "UserID" to "user_id".
+func snakeCase(name string) string {
+
+	runes := []rune(name)
+
+	var b strings.Builder
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// A word starts at an upper case letter after a lower case one, or before a lower case one in an acronym.
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// ImportPath resolves the import path of the package in dir from the go.mod of its module.
+func ImportPath(dir string) (string, error) {
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for root := dir; ; root = filepath.Dir(root) {
+		data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			module := modulePath(data)
+			if module == "" {
+				return "", fmt.Errorf("no module path in %s", filepath.Join(root, "go.mod"))
+			}
+
+			rel, err := filepath.Rel(root, dir)
+			if err != nil {
+				return "", err
+			}
+
+			if rel == "." {
+				return module, nil
+			}
+
+			return module + "/" + filepath.ToSlash(rel), nil
+		}
+
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		if filepath.Dir(root) == root {
+			return "", fmt.Errorf("%s is not in a Go module", dir)
+		}
+	}
+}
+
+// modulePath returns the module path declared by a go.mod file.
+func modulePath(gomod []byte) string {
+
+	for _, line := range strings.Split(string(gomod), "\n") {
+		line = strings.TrimSpace(line)
+		if module, ok := strings.CutPrefix(line, "module"); ok && len(module) > 0 && (module[0] == ' ' || module[0] == '\t') {
+			return strings.Trim(strings.TrimSpace(module), `"`)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,447 @@
+package codegen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"regexp"
+	"strings"
+
+	"github.com/wasify-io/wasify-go/internal/types"
+)
+
+// valueTypeNames are the names of the ValueType constants and of the pack methods of each ValueType,
+// e.g. wasify.ValueTypeI32, Memory.ReadUint32Pack and mdk.WriteUint32Pack.
+var valueTypeNames = map[types.ValueType]struct {
+	constant string
+	pack     string
+	base     string
+}{
+	types.ValueTypeBytes:  {"ValueTypeBytes", "Bytes", "[]byte"},
+	types.ValueTypeByte:   {"ValueTypeByte", "Byte", "byte"},
+	types.ValueTypeI32:    {"ValueTypeI32", "Uint32", "uint32"},
+	types.ValueTypeI64:    {"ValueTypeI64", "Uint64", "uint64"},
+	types.ValueTypeF32:    {"ValueTypeF32", "Float32", "float32"},
+	types.ValueTypeF64:    {"ValueTypeF64", "Float64", "float64"},
+	types.ValueTypeString: {"ValueTypeString", "String", "string"},
+}
+
+// generatedNames match the names of the locals of generated functions, params can't use them.
+var generatedNames = regexp.MustCompile(`^([prv][0-9]+|ctx|err|g|values|impl|m|params)$`)
+
+// GenerateHost generates the host code of the contract in package pkg:
+// the Namespace constant, HostFunctions, which turns an implementation of the host interface into wasify host functions,
+// and GuestClient, which invokes the guest functions with typed params and results.
+func GenerateHost(c *Contract, pkg string) ([]byte, error) {
+
+	if err := checkPackage(c, pkg, "wasify", "context", "bytes", "fmt"); err != nil {
+		return nil, err
+	}
+
+	g := &generator{}
+
+	g.header(c, pkg)
+
+	g.line("import (")
+	g.line(`"context"`)
+	if len(c.Guest) > 0 {
+		g.line(`"bytes"`)
+		g.line(`"fmt"`)
+	}
+	g.line("")
+	g.line(`"github.com/wasify-io/wasify-go"`)
+	if len(c.Host) > 0 || len(c.Guest) > 0 {
+		g.line("")
+		g.line("%s %q", c.Package, c.ImportPath)
+	}
+	g.line(")")
+	g.line("")
+
+	g.line("// Namespace is the namespace the guest imports the host functions from, use it as the ModuleConfig.Namespace of the module.")
+	g.line("const Namespace = %q", c.Namespace)
+
+	if c.HostInterface != "" {
+		g.hostFunctions(c)
+	}
+
+	if c.GuestInterface != "" {
+		g.guestClient(c)
+	}
+
+	return g.format()
+}
+
+// GenerateGuest generates the guest code of the contract in package pkg:
+// the wasm imports of the host functions, HostClient, which implements the host interface by calling them,
+// and ExportGuest, which exports an implementation of the guest interface with mdk.Export.
+func GenerateGuest(c *Contract, pkg string) ([]byte, error) {
+
+	if err := checkPackage(c, pkg, "mdk", "context"); err != nil {
+		return nil, err
+	}
+
+	g := &generator{}
+
+	g.header(c, pkg)
+
+	g.line("import (")
+	if c.hasContext {
+		g.line(`"context"`)
+		g.line("")
+	}
+	g.line(`"github.com/wasify-io/wasify-go/mdk"`)
+	if len(c.Host) > 0 || len(c.Guest) > 0 {
+		g.line("")
+		g.line("%s %q", c.Package, c.ImportPath)
+	}
+	g.line(")")
+
+	if c.HostInterface != "" {
+		g.hostImports(c)
+	}
+
+	if c.GuestInterface != "" {
+		g.line("")
+		g.line("// ExportGuest exports the methods of impl as the guest functions of %s.%s, call it from an init function.", c.Package, c.GuestInterface)
+		g.line("func ExportGuest(impl %s.%s) {", c.Package, c.GuestInterface)
+		for _, fn := range c.Guest {
+			g.line("mdk.Export(%q, impl.%s)", fn.Name, fn.Method)
+		}
+		g.line("}")
+	}
+
+	return g.format()
+}
+
+// checkPackage checks the package name of the contract doesn't clash with the generated package or its imports.
+func checkPackage(c *Contract, pkg string, imports ...string) error {
+
+	if pkg == "" {
+		return errors.New("package name is required")
+	}
+
+	if c.Package == pkg {
+		return fmt.Errorf("the contract package %s can't be the package of the generated code", pkg)
+	}
+
+	for _, name := range imports {
+		if c.Package == name {
+			return fmt.Errorf("the contract package can't be named %s", name)
+		}
+	}
+
+	return nil
+}
+
+type generator struct {
+	buf bytes.Buffer
+}
+
+func (g *generator) line(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+func (g *generator) format() ([]byte, error) {
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, errors.Join(errors.New("generated invalid code"), err)
+	}
+
+	return src, nil
+}
+
+func (g *generator) header(c *Contract, pkg string) {
+	g.line("// Code generated by wasify-gen from %s. DO NOT EDIT.", c.Source)
+	g.line("")
+	g.line("package %s", pkg)
+	g.line("")
+}
+
+func (g *generator) hostFunctions(c *Contract) {
+
+	g.line("")
+	g.line("// HostFunctions returns the host functions of %s.%s implemented by impl, use them as the ModuleConfig.HostFunctions of the module.", c.Package, c.HostInterface)
+	g.line("// An error returned by impl traps the guest which called the host function, see wasify.Trap.")
+	g.line("func HostFunctions(impl %s.%s) []wasify.HostFunction {", c.Package, c.HostInterface)
+	g.line("return []wasify.HostFunction{")
+
+	for _, fn := range c.Host {
+		g.line("{")
+		g.line("Name: %q,", fn.Name)
+		g.line("Callback: func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {")
+
+		args := make([]string, 0, len(fn.Params)+1)
+		if fn.Context {
+			args = append(args, "ctx")
+		}
+
+		for i, p := range fn.Params {
+			g.line("p%d, err := m.Memory.Read%sPack(params[%d])", i, valueTypeNames[p.ValueType].pack, i)
+			g.line("if err != nil {")
+			g.line("panic(err)")
+			g.line("}")
+			args = append(args, convert(fmt.Sprintf("p%d", i), valueTypeNames[p.ValueType].base, p.Type))
+		}
+
+		call := fmt.Sprintf("impl.%s(%s)", fn.Method, strings.Join(args, ", "))
+
+		if len(fn.Results) == 0 {
+			g.line("if err := %s; err != nil {", call)
+			g.line("panic(err)")
+			g.line("}")
+			g.line("return 0")
+		} else {
+			results := make([]string, len(fn.Results))
+			writes := make([]string, len(fn.Results))
+			for i, r := range fn.Results {
+				results[i] = fmt.Sprintf("r%d", i)
+				writes[i] = fmt.Sprintf("m.Memory.Write%sPack(%s)", valueTypeNames[r.ValueType].pack, convert(results[i], r.Type, valueTypeNames[r.ValueType].base))
+			}
+
+			g.line("%s, err := %s", strings.Join(results, ", "), call)
+			g.line("if err != nil {")
+			g.line("panic(err)")
+			g.line("}")
+			g.line("return m.Memory.WriteMultiPack(%s)", strings.Join(writes, ", "))
+		}
+
+		g.line("},")
+		if len(fn.Params) > 0 {
+			g.line("Params: %s,", valueTypes(fn.Params))
+		}
+		if len(fn.Results) > 0 {
+			g.line("Results: %s,", valueTypes(fn.Results))
+		}
+		g.line("},")
+	}
+
+	g.line("}")
+	g.line("}")
+}
+
+func (g *generator) guestClient(c *Contract) {
+
+	g.line("")
+	g.line("// GuestClient invokes the guest functions of %s.%s exported by Module.", c.Package, c.GuestInterface)
+	g.line("type GuestClient struct {")
+	g.line("Module wasify.Module")
+	g.line("}")
+
+	for _, fn := range c.Guest {
+		names := paramNames(fn.Params, c.Package)
+
+		params := []string{"ctx context.Context"}
+		args := []string{"ctx", "g.Module", fmt.Sprintf("%q", fn.Name), fmt.Sprint(len(fn.Results))}
+		for i, p := range fn.Params {
+			params = append(params, names[i]+" "+p.Type)
+			args = append(args, convert(names[i], p.Type, valueTypeNames[p.ValueType].base))
+		}
+
+		var results []string
+		for i, r := range fn.Results {
+			results = append(results, fmt.Sprintf("r%d %s", i, r.Type))
+		}
+		results = append(results, "err error")
+
+		g.line("")
+		g.line("// %s invokes the %s guest function.", fn.Method, fn.Name)
+		g.line("func (g GuestClient) %s(%s) (%s) {", fn.Method, strings.Join(params, ", "), strings.Join(results, ", "))
+
+		if len(fn.Results) == 0 {
+			g.line("_, err = invokeGuest(%s)", strings.Join(args, ", "))
+			g.line("return err")
+			g.line("}")
+			continue
+		}
+
+		g.line("values, err := invokeGuest(%s)", strings.Join(args, ", "))
+		g.line("if err != nil {")
+		g.line("return")
+		g.line("}")
+
+		values := make([]string, len(fn.Results))
+		for i, r := range fn.Results {
+			values[i] = convert(fmt.Sprintf("v%d", i), valueTypeNames[r.ValueType].base, r.Type)
+			g.line("v%d, err := guestResult[%s](values, %d)", i, valueTypeNames[r.ValueType].base, i)
+			g.line("if err != nil {")
+			g.line("return")
+			g.line("}")
+		}
+
+		g.line("return %s, nil", strings.Join(values, ", "))
+		g.line("}")
+	}
+
+	g.line(`
+// invokeGuest invokes a guest function and reads its results.
+func invokeGuest(ctx context.Context, module wasify.Module, name string, results int, params ...any) ([]any, error) {
+
+	res, err := module.GuestFunction(ctx, name).Invoke(params...)
+	if err != nil {
+		return nil, err
+	}
+
+	if results == 0 {
+		return nil, nil
+	}
+
+	pds, err := res.ReadPacks()
+	if err != nil {
+		return nil, err
+	}
+	defer module.Memory().FreePack(pds...)
+
+	if len(pds) != results {
+		return nil, fmt.Errorf("guest function %%s returned %%d results, expected %%d", name, len(pds), results)
+	}
+
+	values := make([]any, len(pds))
+	for i, pd := range pds {
+		values[i], _, _, err = module.Memory().ReadAnyPack(pd)
+		if err != nil {
+			return nil, err
+		}
+
+		// The packs are freed, keep a copy of the bytes.
+		if b, ok := values[i].([]byte); ok {
+			values[i] = bytes.Clone(b)
+		}
+	}
+
+	return values, nil
+}
+
+// guestResult returns the result i of a guest function as a T.
+func guestResult[T any](values []any, i int) (T, error) {
+
+	v, ok := values[i].(T)
+	if !ok {
+		return v, fmt.Errorf("result %%d is a %%T, expected a %%T", i, values[i], v)
+	}
+
+	return v, nil
+}`)
+}
+
+func (g *generator) hostImports(c *Contract) {
+
+	for _, fn := range c.Host {
+		stub := "_import" + fn.Method
+
+		params := make([]string, len(fn.Params))
+		signature := make([]string, len(fn.Params))
+		for i, p := range fn.Params {
+			params[i] = "mdk.PackedData"
+			signature[i] = p.Type
+		}
+
+		results := make([]string, 0, len(fn.Results)+1)
+		for _, r := range fn.Results {
+			results = append(results, r.Type)
+		}
+		results = append(results, "error")
+
+		var stubResult string
+		if len(fn.Results) > 0 {
+			stubResult = " mdk.MultiPackedData"
+		}
+
+		g.line("")
+		g.line("//go:wasmimport %s %s", c.Namespace, fn.Name)
+		g.line("func %s(%s)%s", stub, strings.Join(params, ", "), stubResult)
+		g.line("")
+		g.line("var import%s = mdk.Import[func(%s) (%s)](%s)", fn.Method, strings.Join(signature, ", "), strings.Join(results, ", "), stub)
+	}
+
+	g.line("")
+	g.line("// HostClient calls the host functions of %s.%s.", c.Package, c.HostInterface)
+	g.line("type HostClient struct{}")
+	g.line("")
+	g.line("var _ %s.%s = HostClient{}", c.Package, c.HostInterface)
+
+	for _, fn := range c.Host {
+		names := paramNames(fn.Params, c.Package)
+
+		var params []string
+		if fn.Context {
+			params = append(params, "_ context.Context")
+		}
+		for i, p := range fn.Params {
+			params = append(params, names[i]+" "+p.Type)
+		}
+
+		results := make([]string, 0, len(fn.Results)+1)
+		for _, r := range fn.Results {
+			results = append(results, r.Type)
+		}
+		results = append(results, "error")
+
+		g.line("")
+		g.line("// %s calls the %s host function.", fn.Method, fn.Name)
+		g.line("func (HostClient) %s(%s) (%s) {", fn.Method, strings.Join(params, ", "), strings.Join(results, ", "))
+		g.line("return import%s(%s)", fn.Method, strings.Join(names, ", "))
+		g.line("}")
+	}
+}
+
+// paramNames returns the names of the params in generated functions.
+// Unnamed params and params named like the locals of generated functions are named by index, e.g. p0.
+func paramNames(params []Value, pkg string) []string {
+
+	names := make([]string, len(params))
+
+	for i, p := range params {
+		if p.Name == "" || generatedNames.MatchString(p.Name) || isImportName(p.Name) || p.Name == pkg {
+			names[i] = fmt.Sprintf("p%d", i)
+		} else {
+			names[i] = p.Name
+		}
+	}
+
+	return names
+}
+
+func isImportName(name string) bool {
+	switch name {
+	case "context", "bytes", "fmt", "wasify", "mdk":
+		return true
+	}
+
+	return false
+}
+
+// convert returns the expression converting expr of the type from to the type to, e.g. "float64(c)".
+func convert(expr string, from string, to string) string {
+
+	if canonicalType(from) == canonicalType(to) {
+		return expr
+	}
+
+	return to + "(" + expr + ")"
+}
+
+// canonicalType returns the type name with aliases resolved, e.g. "uint8" to "byte".
+func canonicalType(typ string) string {
+
+	switch typ {
+	case "uint8":
+		return "byte"
+	case "[]uint8":
+		return "[]byte"
+	}
+
+	return typ
+}
+
+func valueTypes(values []Value) string {
+
+	names := make([]string, len(values))
+	for i, v := range values {
+		names[i] = "wasify." + valueTypeNames[v.ValueType].constant
+	}
+
+	return "[]wasify.ValueType{" + strings.Join(names, ", ") + "}"
+}
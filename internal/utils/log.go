@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"log/slog"
 	"os"
 )
@@ -31,6 +32,46 @@ func NewLogger(severity LogSeverity) *slog.Logger {
 	return logger
 }
 
+// NewLoggerWithHandler returns new slog ref which writes to a user-supplied handler.
+//
+// If severity is set, records below it are dropped before they reach the handler,
+// otherwise the handler's own level is used.
+// If handler is nil, the default stderr logger is returned, see NewLogger.
+func NewLoggerWithHandler(handler slog.Handler, severity LogSeverity) *slog.Logger {
+
+	if handler == nil {
+		return NewLogger(severity)
+	}
+
+	if severity == 0 {
+		return slog.New(handler)
+	}
+
+	return slog.New(&levelHandler{GetlogLevel(severity), handler})
+}
+
+// levelHandler wraps a slog.Handler and drops records below level.
+type levelHandler struct {
+	level   slog.Level
+	handler slog.Handler
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level && h.handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{h.level, h.handler.WithAttrs(attrs)}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{h.level, h.handler.WithGroup(name)}
+}
+
 // GetlogLevel gets 'slog' level based on severity specified by user
 func GetlogLevel(s LogSeverity) slog.Level {
 
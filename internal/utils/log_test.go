@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"bytes"
 	"log/slog"
 	"testing"
 
@@ -30,3 +31,25 @@ func TestGetLogLevel(t *testing.T) {
 		}
 	}
 }
+
+func TestNewLoggerWithHandler(t *testing.T) {
+
+	var buf bytes.Buffer
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+
+	// Without severity the handler's own level applies.
+	NewLoggerWithHandler(handler, 0).Debug("debug message")
+	assert.Contains(t, buf.String(), "debug message")
+
+	buf.Reset()
+
+	// Severity drops records below it, also for derived loggers.
+	logger := NewLoggerWithHandler(handler, LogWarning).With("namespace", "test")
+	logger.Info("info message")
+	logger.Warn("warn message")
+	assert.NotContains(t, buf.String(), "info message")
+	assert.Contains(t, buf.String(), "warn message")
+	assert.Contains(t, buf.String(), "namespace=test")
+
+	assert.NotNil(t, NewLoggerWithHandler(nil, LogDebug))
+}
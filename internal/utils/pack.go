@@ -14,6 +14,8 @@ import (
 // - Next 32 bits: offset
 // - Lowest 24 bits: size
 //
+// The layout is specified by the wire protocol, see PROTOCOL.md.
+//
 // This function will return error if the provided size is larger than what can be represented in 24 bits
 // (i.e., larger than 16,777,215).
 func PackUI64(dataType types.ValueType, offset uint32, size uint32) (uint64, error) {
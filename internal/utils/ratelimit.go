@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket which allows rate events per second, with bursts of up to burst events.
+//
+// A nil *RateLimiter allows every event.
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+	dropped uint64
+
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+// NewRateLimiter returns a RateLimiter, or nil if rate is not positive (no limit).
+// A burst lower than 1 is treated as 1.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+
+	if rate <= 0 {
+		return nil
+	}
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// Allow reports whether an event may happen now.
+// When it does, it also returns the number of events dropped since the last allowed one.
+func (l *RateLimiter) Allow() (bool, uint64) {
+
+	if l == nil {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		l.dropped++
+		return false, 0
+	}
+
+	l.tokens--
+
+	dropped := l.dropped
+	l.dropped = 0
+
+	return true, dropped
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+
+	assert.Nil(t, NewRateLimiter(0, 10))
+
+	var unlimited *RateLimiter
+	ok, _ := unlimited.Allow()
+	assert.True(t, ok)
+
+	now := time.Unix(0, 0)
+	l := NewRateLimiter(1, 2)
+	l.now = func() time.Time { return now }
+
+	// Burst is allowed, then events are dropped until tokens refill.
+	for i := 0; i < 2; i++ {
+		ok, dropped := l.Allow()
+		assert.True(t, ok)
+		assert.Zero(t, dropped)
+	}
+
+	ok, _ = l.Allow()
+	assert.False(t, ok)
+	ok, _ = l.Allow()
+	assert.False(t, ok)
+
+	now = now.Add(time.Second)
+
+	ok, dropped := l.Allow()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), dropped)
+}
@@ -0,0 +1,301 @@
+package wasmbin
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Section ids used to export globals.
+const (
+	importSectionID = 2
+	globalSectionID = 6
+	exportSectionID = 7
+
+	// tagSectionID is the section of the exception handling proposal, it goes between the memory and the global sections.
+	tagSectionID = 13
+)
+
+// Minimum encoded sizes of the section entries, used to reject counts the section data can't hold:
+// a global is a value type, a mutability and an init expression of at least one byte,
+// an import is two name sizes, a kind and a descriptor of at least one byte.
+const (
+	minGlobalSize = 3
+	minImportSize = 4
+)
+
+// Import and export kinds.
+const (
+	kindFunc   = 0x00
+	kindTable  = 0x01
+	kindMemory = 0x02
+	kindGlobal = 0x03
+)
+
+// ExportGlobals returns a copy of the wasm binary in which every global defined by the module is exported,
+// so its value can be read and restored by the host.
+// Globals which are not exported yet are exported as prefix followed by the global index, e.g. "wasify.global.0".
+//
+// It also returns the export names of all exported globals, in index order.
+func ExportGlobals(bin []byte, prefix string) ([]byte, []string, error) {
+
+	sections, err := Sections(bin)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		importedGlobals uint64
+		definedGlobals  uint64
+		exports         *Section
+		exportsAt       = len(sections)
+	)
+
+	for i := range sections {
+		s := &sections[i]
+
+		switch s.ID {
+		case importSectionID:
+			importedGlobals, err = countImportedGlobals(s.Data)
+			if err != nil {
+				return nil, nil, err
+			}
+		case globalSectionID:
+			count, n := binary.Uvarint(s.Data)
+			if n <= 0 {
+				return nil, nil, errors.New("invalid global section")
+			}
+			// Don't trust the count to size the exports, a global takes at least minGlobalSize bytes.
+			if count > uint64(len(s.Data)-n)/minGlobalSize {
+				return nil, nil, fmt.Errorf("invalid global section: %d globals don't fit in %d bytes", count, len(s.Data)-n)
+			}
+			definedGlobals = count
+		case exportSectionID:
+			exports = s
+			exportsAt = i
+		}
+
+		// A missing export section goes before the first section which must follow it.
+		if exports == nil && exportsAt == len(sections) && s.ID != customSectionID && s.ID != tagSectionID && s.ID > exportSectionID {
+			exportsAt = i
+		}
+	}
+
+	// Export names by global index, of the exports which already exist.
+	exported := make(map[uint64]string)
+
+	var (
+		count   uint64
+		entries []byte
+	)
+
+	if exports != nil {
+		count, entries, err = parseExports(exports.Data, func(name string, kind byte, index uint64) {
+			if kind == kindGlobal {
+				if _, ok := exported[index]; !ok {
+					exported[index] = name
+				}
+			}
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var names []string
+
+	for index := uint64(0); index < importedGlobals+definedGlobals; index++ {
+
+		if name, ok := exported[index]; ok {
+			names = append(names, name)
+			continue
+		}
+
+		// Imported globals belong to another module, leave them alone.
+		if index < importedGlobals {
+			continue
+		}
+
+		name := prefix + strconv.FormatUint(index, 10)
+
+		entries = binary.AppendUvarint(entries, uint64(len(name)))
+		entries = append(entries, name...)
+		entries = append(entries, kindGlobal)
+		entries = binary.AppendUvarint(entries, index)
+		count++
+
+		names = append(names, name)
+	}
+
+	payload := binary.AppendUvarint(nil, count)
+	payload = append(payload, entries...)
+
+	res := make([]byte, 0, len(bin)+len(payload))
+	res = append(res, header...)
+
+	for i, s := range sections {
+		if i == exportsAt {
+			res = append(res, exportSectionID)
+			res = binary.AppendUvarint(res, uint64(len(payload)))
+			res = append(res, payload...)
+		}
+
+		if exports != nil && i == exportsAt {
+			continue
+		}
+
+		res = append(res, bin[s.start:s.end]...)
+	}
+
+	if exportsAt == len(sections) {
+		res = append(res, exportSectionID)
+		res = binary.AppendUvarint(res, uint64(len(payload)))
+		res = append(res, payload...)
+	}
+
+	return res, names, nil
+}
+
+// countImportedGlobals returns the number of globals in an import section.
+func countImportedGlobals(data []byte) (uint64, error) {
+
+	r := &reader{data: data}
+
+	count := r.uvarint()
+	if r.err == nil && count > uint64(len(data)-r.pos)/minImportSize {
+		r.fail(fmt.Errorf("%d imports don't fit in %d bytes", count, len(data)-r.pos))
+	}
+
+	var globals uint64
+
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		r.name()
+		r.name()
+
+		switch kind := r.byte(); kind {
+		case kindFunc:
+			r.uvarint()
+		case kindTable:
+			r.byte()
+			r.limits()
+		case kindMemory:
+			r.limits()
+		case kindGlobal:
+			r.byte()
+			r.byte()
+			globals++
+		default:
+			r.fail(fmt.Errorf("invalid import kind %d", kind))
+		}
+	}
+
+	if r.err != nil {
+		return 0, errors.Join(errors.New("invalid import section"), r.err)
+	}
+
+	return globals, nil
+}
+
+// parseExports calls fn for every export of an export section.
+// It returns the number of exports and the raw export entries.
+func parseExports(data []byte, fn func(name string, kind byte, index uint64)) (uint64, []byte, error) {
+
+	r := &reader{data: data}
+
+	count := r.uvarint()
+	start := r.pos
+
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		name := r.name()
+		kind := r.byte()
+		index := r.uvarint()
+
+		if r.err == nil {
+			fn(name, kind, index)
+		}
+	}
+
+	if r.err != nil {
+		return 0, nil, errors.Join(errors.New("invalid export section"), r.err)
+	}
+
+	return count, append([]byte(nil), data[start:r.pos]...), nil
+}
+
+// reader decodes the values of a section and keeps the first error,
+// so the values don't need to be checked one by one.
+type reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *reader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *reader) byte() byte {
+
+	if r.err != nil {
+		return 0
+	}
+
+	if r.pos >= len(r.data) {
+		r.fail(errors.New("unexpected end of section"))
+		return 0
+	}
+
+	b := r.data[r.pos]
+	r.pos++
+
+	return b
+}
+
+func (r *reader) uvarint() uint64 {
+
+	if r.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		r.fail(fmt.Errorf("invalid integer at offset %d", r.pos))
+		return 0
+	}
+	r.pos += n
+
+	return v
+}
+
+func (r *reader) name() string {
+
+	size := r.uvarint()
+
+	if r.err != nil {
+		return ""
+	}
+
+	if size > uint64(len(r.data)-r.pos) {
+		r.fail(fmt.Errorf("invalid name size at offset %d", r.pos))
+		return ""
+	}
+
+	name := string(r.data[r.pos : r.pos+int(size)])
+	r.pos += int(size)
+
+	return name
+}
+
+// limits skips the limits of a table or memory.
+func (r *reader) limits() {
+
+	flags := r.byte()
+
+	r.uvarint()
+	if flags&0x01 != 0 {
+		r.uvarint()
+	}
+}
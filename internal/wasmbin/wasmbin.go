@@ -0,0 +1,122 @@
+// Package wasmbin provides minimal helpers to work with the sections of a wasm binary
+// without decoding the whole module.
+package wasmbin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// header is the magic number and version every wasm binary starts with.
+var header = []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+
+// customSectionID is the id of custom sections, which carry data ignored by the wasm semantics.
+const customSectionID = 0
+
+// Section is a raw section of a wasm binary.
+type Section struct {
+	ID byte
+
+	// Name is set only for custom sections.
+	Name string
+
+	// Data is the section payload, excluding the name of a custom section.
+	Data []byte
+
+	// start and end are the boundaries of the whole section, including id and size, in the binary.
+	start, end int
+}
+
+// Sections splits the wasm binary into its sections.
+func Sections(bin []byte) ([]Section, error) {
+
+	if !bytes.HasPrefix(bin, header) {
+		return nil, errors.New("invalid wasm binary, magic number or version mismatch")
+	}
+
+	var sections []Section
+
+	for pos := len(header); pos < len(bin); {
+
+		start := pos
+		id := bin[pos]
+		pos++
+
+		size, n := binary.Uvarint(bin[pos:])
+		if n <= 0 || size > uint64(len(bin)-pos-n) {
+			return nil, fmt.Errorf("invalid size of section %d at offset %d", id, start)
+		}
+		pos += n
+
+		s := Section{ID: id, Data: bin[pos : pos+int(size)], start: start, end: pos + int(size)}
+		pos = s.end
+
+		if id == customSectionID {
+			nameSize, n := binary.Uvarint(s.Data)
+			if n <= 0 || nameSize > uint64(len(s.Data)-n) {
+				return nil, fmt.Errorf("invalid name of custom section at offset %d", start)
+			}
+			s.Name = string(s.Data[n : n+int(nameSize)])
+			s.Data = s.Data[n+int(nameSize):]
+		}
+
+		sections = append(sections, s)
+	}
+
+	return sections, nil
+}
+
+// CustomSection returns the payload of the first custom section with the given name.
+func CustomSection(bin []byte, name string) ([]byte, bool, error) {
+
+	sections, err := Sections(bin)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, s := range sections {
+		if s.ID == customSectionID && s.Name == name {
+			return s.Data, true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+// RemoveCustomSection returns a copy of the wasm binary without any custom section of the given name.
+func RemoveCustomSection(bin []byte, name string) ([]byte, error) {
+
+	sections, err := Sections(bin)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 0, len(bin))
+	res = append(res, header...)
+
+	for _, s := range sections {
+		if s.ID == customSectionID && s.Name == name {
+			continue
+		}
+		res = append(res, bin[s.start:s.end]...)
+	}
+
+	return res, nil
+}
+
+// AppendCustomSection returns a copy of the wasm binary with a custom section appended at the end.
+func AppendCustomSection(bin []byte, name string, data []byte) []byte {
+
+	payload := binary.AppendUvarint(nil, uint64(len(name)))
+	payload = append(payload, name...)
+	payload = append(payload, data...)
+
+	res := make([]byte, 0, len(bin)+len(payload)+binary.MaxVarintLen32+1)
+	res = append(res, bin...)
+	res = append(res, customSectionID)
+	res = binary.AppendUvarint(res, uint64(len(payload)))
+
+	return append(res, payload...)
+}
@@ -0,0 +1,156 @@
+package wasmbin
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCustomSections(t *testing.T) {
+
+	// Minimal module with an empty type section.
+	bin := append(append([]byte{}, header...), 0x01, 0x01, 0x00)
+
+	withSection := AppendCustomSection(bin, "wasify.test", []byte("payload"))
+
+	data, ok, err := CustomSection(withSection, "wasify.test")
+	if err != nil || !ok {
+		t.Fatalf("expected custom section, got ok=%v err=%v", ok, err)
+	}
+
+	if string(data) != "payload" {
+		t.Errorf("expected payload, got %q", data)
+	}
+
+	sections, err := Sections(withSection)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(sections) != 2 || sections[0].ID != 1 || sections[1].Name != "wasify.test" {
+		t.Errorf("unexpected sections %+v", sections)
+	}
+
+	removed, err := RemoveCustomSection(withSection, "wasify.test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !bytes.Equal(removed, bin) {
+		t.Errorf("expected %v, got %v", bin, removed)
+	}
+
+	_, ok, err = CustomSection(removed, "wasify.test")
+	if err != nil || ok {
+		t.Errorf("expected no custom section, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestSectionsInvalid(t *testing.T) {
+
+	if _, err := Sections([]byte("invalid wasm data")); err == nil {
+		t.Error("expected an error for invalid header, but got none")
+	}
+
+	// Section size exceeds the binary.
+	bin := append(append([]byte{}, header...), 0x01, 0x05, 0x00)
+	if _, err := Sections(bin); err == nil {
+		t.Error("expected an error for truncated section, but got none")
+	}
+}
+
+func TestExportGlobals(t *testing.T) {
+
+	// Module with an imported global, two defined globals, one of them already exported as "counter",
+	// and a start section which must stay after the export section.
+	bin := append([]byte{}, header...)
+	bin = append(bin,
+		// type section: one func type () -> ()
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+		// import section: "env" "g" global i32 const
+		0x02, 0x0a, 0x01, 0x03, 'e', 'n', 'v', 0x01, 'g', 0x03, 0x7f, 0x00,
+		// function section: one func of type 0
+		0x03, 0x02, 0x01, 0x00,
+		// global section: two mutable i32 globals initialized to 0
+		0x06, 0x0b, 0x02, 0x7f, 0x01, 0x41, 0x00, 0x0b, 0x7f, 0x01, 0x41, 0x00, 0x0b,
+		// export section: "counter" global 2
+		0x07, 0x0b, 0x01, 0x07, 'c', 'o', 'u', 'n', 't', 'e', 'r', 0x03, 0x02,
+		// start section: func 0
+		0x08, 0x01, 0x00,
+		// code section: one empty body
+		0x0a, 0x04, 0x01, 0x02, 0x00, 0x0b,
+	)
+
+	exported, names, err := ExportGlobals(bin, "wasify.global.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(names) != 2 || names[0] != "wasify.global.1" || names[1] != "counter" {
+		t.Errorf("unexpected names %v", names)
+	}
+
+	sections, err := Sections(exported)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var ids []byte
+	for _, s := range sections {
+		ids = append(ids, s.ID)
+	}
+	if !bytes.Equal(ids, []byte{1, 2, 3, 6, 7, 8, 10}) {
+		t.Errorf("unexpected section order %v", ids)
+	}
+
+	// Exporting again doesn't change anything.
+	again, namesAgain, err := ExportGlobals(exported, "wasify.global.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !bytes.Equal(again, exported) || len(namesAgain) != 2 {
+		t.Errorf("expected the same binary and names, got %v", namesAgain)
+	}
+
+	// Without an export section, a new one is added before the start section.
+	withoutExports := append([]byte{}, bin[:len(header)+6+12+4+13]...)
+	withoutExports = append(withoutExports, bin[len(header)+6+12+4+13+13:]...)
+
+	exported, names, err = ExportGlobals(withoutExports, "g")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(names) != 2 || names[0] != "g1" || names[1] != "g2" {
+		t.Errorf("unexpected names %v", names)
+	}
+
+	sections, err = Sections(exported)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(sections) != 7 || sections[4].ID != 7 || sections[5].ID != 8 {
+		t.Errorf("unexpected sections %+v", sections)
+	}
+}
+
+func TestExportGlobalsInvalidCount(t *testing.T) {
+
+	tests := map[string][]byte{
+		// global section claiming 2^63 globals in 4 bytes
+		"globals": {0x06, 0x0d, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01, 0x7f, 0x00, 0x0b},
+		// import section claiming 2^35 imports in 5 bytes
+		"imports": {0x02, 0x0a, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01, 0x00, 0x00, 0x03, 0x7f},
+	}
+
+	for name, section := range tests {
+		t.Run(name, func(t *testing.T) {
+			bin := append(append([]byte{}, header...), section...)
+
+			if _, _, err := ExportGlobals(bin, "g"); err == nil {
+				t.Error("expected an error for a count the section can't hold, but got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package mdk
+
+import "unsafe"
+
+// maxFreeBlocks is the number of freed blocks kept per size to be reused by malloc,
+// the rest are released to the garbage collector.
+const maxFreeBlocks = 16
+
+// allocator hands out Go-allocated memory blocks by address, so the host and the guest can exchange data
+// through linear memory without a C allocator.
+//
+// Go's garbage collector doesn't know about addresses held by the host, so every block is pinned
+// by keeping it in allocs until it's freed. Blocks are allocated as uint64 words, so they're 8-byte aligned.
+//
+// NOTE: Like the rest of the mdk, it's meant for the single-threaded wasm guest and isn't safe for concurrent use.
+type allocator struct {
+	// allocs pins the allocated blocks by address.
+	allocs map[uintptr][]uint64
+
+	// freed are the blocks released by free, by number of words.
+	freed map[int][][]uint64
+}
+
+var heap = &allocator{
+	allocs: make(map[uintptr][]uint64),
+	freed:  make(map[int][][]uint64),
+}
+
+// malloc allocates a zeroed block of size bytes and returns its address.
+// A zero size allocates a block of one word, so every allocation has a distinct address.
+func (a *allocator) malloc(size uint32) uintptr {
+
+	words := max(int((uint64(size)+7)/8), 1)
+
+	var block []uint64
+
+	if freed := a.freed[words]; len(freed) > 0 {
+		block = freed[len(freed)-1]
+		a.freed[words] = freed[:len(freed)-1]
+		clear(block)
+	} else {
+		block = make([]uint64, words)
+	}
+
+	ptr := uintptr(unsafe.Pointer(&block[0]))
+	a.allocs[ptr] = block
+
+	return ptr
+}
+
+// free releases the block at ptr. Unknown addresses, including 0, are ignored.
+func (a *allocator) free(ptr uintptr) {
+
+	block, ok := a.allocs[ptr]
+	if !ok {
+		return
+	}
+
+	delete(a.allocs, ptr)
+
+	if freed := a.freed[len(block)]; len(freed) < maxFreeBlocks {
+		a.freed[len(block)] = append(freed, block)
+	}
+}
+
+// block returns a pointer to the block at ptr. It's taken from the block itself, not converted from ptr,
+// so it stays a valid Go pointer where pointer conversions are checked, e.g. in tests run with -race.
+func (a *allocator) block(ptr uintptr) unsafe.Pointer {
+	return unsafe.Pointer(&a.allocs[ptr][0])
+}
+
+// malloc allocates memory of the given size and returns its offset.
+func malloc(size uint32) uint64 {
+	return uint64(heap.malloc(size))
+}
+
+// free deallocates the memory previously allocated by malloc.
+// The offset parameter is a uint64 representing the starting address of the block
+// of linear memory to be deallocated.
+func free(offset uint64) {
+	heap.free(uintptr(offset))
+}
+
+// allocated returns the memory malloc allocated at offset as a pointer of type T, to write into it.
+func allocated[T any](offset uint64) *T {
+	return (*T)(heap.block(uintptr(offset)))
+}
@@ -0,0 +1,41 @@
+package mdk
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocator(t *testing.T) {
+
+	a := &allocator{
+		allocs: make(map[uintptr][]uint64),
+		freed:  make(map[int][][]uint64),
+	}
+
+	empty := a.malloc(0)
+	assert.NotZero(t, empty)
+	assert.NotEqual(t, empty, a.malloc(0), "zero size allocations have distinct addresses")
+
+	ptr := a.malloc(12)
+	assert.Zero(t, ptr%8, "blocks are 8-byte aligned")
+	assert.Len(t, a.allocs[ptr], 2)
+
+	data := unsafe.Slice((*byte)(a.block(ptr)), 12)
+	copy(data, "dirty memory")
+
+	a.free(ptr)
+	assert.NotContains(t, a.allocs, ptr)
+
+	// The freed block is reused, zeroed.
+	reused := a.malloc(16)
+	assert.Equal(t, ptr, reused)
+	assert.Equal(t, make([]byte, 16), unsafe.Slice((*byte)(a.block(reused)), 16))
+
+	// Unknown addresses and double frees are ignored.
+	a.free(0)
+	a.free(reused)
+	a.free(reused)
+	assert.Len(t, a.freed[2], 1)
+}
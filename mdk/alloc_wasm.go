@@ -0,0 +1,16 @@
+//go:build wasm && go1.24
+
+package mdk
+
+// Names of the allocator functions the host calls to pass data to the guest, see wasify's Memory.Malloc and Memory.Free.
+// They're prefixed, so they don't clash with the malloc and free some toolchains, e.g. TinyGo, already export.
+
+//go:wasmexport wasify_malloc
+func _malloc(size uint32) uint32 {
+	return uint32(malloc(size))
+}
+
+//go:wasmexport wasify_free
+func _free(offset uint32) {
+	free(uint64(offset))
+}
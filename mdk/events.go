@@ -0,0 +1,92 @@
+package mdk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// handlers are the event handlers registered with Subscribe, by topic.
+var handlers = make(map[string]func(payload []byte) error)
+
+// Subscribe registers handler for the events of topic, which the host publishes on the wasify.EventBus of the module, e.g.
+//
+//	func init() {
+//		err := mdk.Subscribe("invoice.created", func(payload []byte) error {
+//			return notify(payload)
+//		})
+//		if err != nil {
+//			panic(err)
+//		}
+//	}
+//
+// and on the host:
+//
+//	err := bus.Publish(ctx, "invoice.created", payload)
+//
+// The host delivers the events of the module one at a time, in the order they've been published,
+// through the "wasify_event" export of the mdk, which calls the handler of the topic.
+// An error returned by the handler, or its panic, is logged by the host, it doesn't stop the delivery of the next events.
+//
+// Subscribing to a topic again replaces its handler. Subscribe returns an error if the module has no wasify.EventBus.
+func Subscribe(topic string, handler func(payload []byte) error) error {
+
+	pd := WriteStringPack(topic)
+	defer FreePack(pd)
+
+	mpd := _subscribe(pd)
+
+	pds := mpd.ReadPacks()
+	if len(pds) > 0 {
+		defer FreePack(pds...)
+		return errors.New(ReadStringPack(pds[0]))
+	}
+
+	handlers[topic] = handler
+
+	return nil
+}
+
+// Unsubscribe stops the delivery of the events of topic, including the ones the host has already queued.
+func Unsubscribe(topic string) {
+
+	delete(handlers, topic)
+
+	pd := WriteStringPack(topic)
+	defer FreePack(pd)
+
+	_unsubscribe(pd)
+}
+
+// handleEvent calls the handler of the topic in the string pack topic with the payload in the bytes pack payload.
+//
+// It returns a MultiPackedData of a string pack of the error of the handler, or 0 if it succeeded.
+// The topic and the payload are freed.
+func handleEvent(topic PackedData, payload PackedData) MultiPackedData {
+
+	defer FreePack(topic, payload)
+
+	// The payload is freed once the handler returns, it may keep it.
+	err := callHandler(ReadStringPack(topic), bytes.Clone(ReadBytesPack(payload)))
+	if err != nil {
+		return WriteMultiPack(WriteStringPack(err.Error()))
+	}
+
+	return 0
+}
+
+func callHandler(topic string, payload []byte) (err error) {
+
+	handler, ok := handlers[topic]
+	if !ok {
+		return fmt.Errorf("no handler subscribed to %s", topic)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return handler(payload)
+}
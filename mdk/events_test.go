@@ -0,0 +1,38 @@
+package mdk
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallHandler(t *testing.T) {
+
+	var received []byte
+
+	handlers["invoice.created"] = func(payload []byte) error {
+		switch string(payload) {
+		case "invalid":
+			return errors.New("invalid invoice")
+		case "panic":
+			panic("no invoice")
+		}
+		received = payload
+		return nil
+	}
+	defer delete(handlers, "invoice.created")
+
+	err := callHandler("invoice.created", []byte("42"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("42"), received)
+
+	err = callHandler("invoice.created", []byte("invalid"))
+	assert.EqualError(t, err, "invalid invoice")
+
+	err = callHandler("invoice.created", []byte("panic"))
+	assert.EqualError(t, err, "panic: no invoice")
+
+	err = callHandler("invoice.paid", nil)
+	assert.EqualError(t, err, "no handler subscribed to invoice.paid")
+}
@@ -0,0 +1,10 @@
+//go:build wasm && go1.24
+
+package mdk
+
+// _event is the export the host delivers the events the guest subscribed to with Subscribe through.
+
+//go:wasmexport wasify_event
+func _event(topic PackedData, payload PackedData) MultiPackedData {
+	return handleEvent(topic, payload)
+}
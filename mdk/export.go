@@ -0,0 +1,286 @@
+package mdk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/wasify-io/wasify-go/internal/types"
+)
+
+// exports are the guest functions registered with Export, by name.
+var exports = make(map[string]*export)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// export is a guest function registered with Export.
+type export struct {
+	fn      reflect.Value
+	params  []types.ValueType
+	results []types.ValueType
+
+	// hasError reports whether the last result of fn is an error.
+	hasError bool
+}
+
+// Export registers fn as a guest function the host can invoke by name, e.g.
+//
+//	func init() {
+//		mdk.Export("greet", func(name string, times uint32) (string, error) {
+//			return strings.Repeat("Hello, "+name+"! ", int(times)), nil
+//		})
+//	}
+//
+// and on the host:
+//
+//	res, err := module.GuestFunction(ctx, "greet").Invoke("Wasify", uint32(2))
+//
+// The params and results of fn can be []byte, byte, uint32, uint64, float32, float64 and string,
+// or types based on them. If the last result is an error, a non-nil error is returned by Invoke as a wasify.GuestError,
+// so is a panic of fn.
+//
+// The host invokes exported functions through the single "wasify_dispatch" export of the mdk,
+// which decodes the params, calls fn, encodes its results and frees the params.
+//
+// Export panics if fn isn't a function with supported params and results, or if name is already exported.
+//
+// NOTE: Exported functions are called through reflection, which requires Go or a TinyGo version implementing reflect.Value.Call.
+func Export(name string, fn any) {
+
+	e, err := newExport(fn)
+	if err != nil {
+		panic(fmt.Sprintf("mdk: can't export %s: %v", name, err))
+	}
+
+	if _, ok := exports[name]; ok {
+		panic(fmt.Sprintf("mdk: %s is already exported", name))
+	}
+
+	exports[name] = e
+}
+
+func newExport(fn any) (*export, error) {
+
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("%T is not a function", fn)
+	}
+
+	t := v.Type()
+	if t.IsVariadic() {
+		return nil, errors.New("variadic functions are not supported")
+	}
+
+	e := &export{fn: v}
+
+	for i := 0; i < t.NumIn(); i++ {
+		vt, ok := valueTypeOf(t.In(i))
+		if !ok {
+			return nil, fmt.Errorf("unsupported param %d type %s", i, t.In(i))
+		}
+		e.params = append(e.params, vt)
+	}
+
+	for i := 0; i < t.NumOut(); i++ {
+		if i == t.NumOut()-1 && t.Out(i) == errorType {
+			e.hasError = true
+			break
+		}
+
+		vt, ok := valueTypeOf(t.Out(i))
+		if !ok {
+			return nil, fmt.Errorf("unsupported result %d type %s", i, t.Out(i))
+		}
+		e.results = append(e.results, vt)
+	}
+
+	return e, nil
+}
+
+// valueTypeOf returns the ValueType values of type t are packed as.
+func valueTypeOf(t reflect.Type) (types.ValueType, bool) {
+
+	switch t.Kind() {
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return types.ValueTypeBytes, true
+		}
+	case reflect.Uint8:
+		return types.ValueTypeByte, true
+	case reflect.Uint32:
+		return types.ValueTypeI32, true
+	case reflect.Uint64:
+		return types.ValueTypeI64, true
+	case reflect.Float32:
+		return types.ValueTypeF32, true
+	case reflect.Float64:
+		return types.ValueTypeF64, true
+	case reflect.String:
+		return types.ValueTypeString, true
+	}
+
+	return 0, false
+}
+
+// call calls the function with args, which must be of the base types of its params, e.g. string or uint32.
+// It returns the results of the function converted to their base types, and the error it returned or panicked with.
+func (e *export) call(args []any) (results []any, err error) {
+
+	t := e.fn.Type()
+
+	if len(args) != len(e.params) {
+		return nil, fmt.Errorf("expected %d params, got %d", len(e.params), len(args))
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		v := reflect.ValueOf(arg)
+		if v.Kind() != t.In(i).Kind() {
+			return nil, fmt.Errorf("param %d: expected %s, got %T", i, t.In(i), arg)
+		}
+		in[i] = v.Convert(t.In(i))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			results, err = nil, fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	out := e.fn.Call(in)
+
+	if e.hasError {
+		if errValue := out[len(out)-1]; !errValue.IsNil() {
+			return nil, errValue.Interface().(error)
+		}
+		out = out[:len(out)-1]
+	}
+
+	results = make([]any, len(out))
+	for i, v := range out {
+		results[i] = baseValue(v, e.results[i])
+	}
+
+	return results, nil
+}
+
+// baseValue returns v converted to the base type of the ValueType, e.g. a named string type to string.
+func baseValue(v reflect.Value, vt types.ValueType) any {
+
+	switch vt {
+	case types.ValueTypeBytes:
+		return v.Bytes()
+	case types.ValueTypeByte:
+		return byte(v.Uint())
+	case types.ValueTypeI32:
+		return uint32(v.Uint())
+	case types.ValueTypeI64:
+		return v.Uint()
+	case types.ValueTypeF32:
+		return float32(v.Float())
+	case types.ValueTypeF64:
+		return v.Float()
+	default:
+		return v.String()
+	}
+}
+
+// dispatch invokes the exported function named by the string pack name with the params packed in args.
+//
+// It returns an envelope of two packs: the MultiPackedData of the results, or 0 if there is none,
+// and a string pack of the error, or 0 if the call succeeded.
+// The name, the params and args are freed.
+func dispatch(name PackedData, args MultiPackedData) MultiPackedData {
+
+	fn := ReadStringPack(name)
+	params := args.ReadPacks()
+	defer FreePack(append(params, name)...)
+
+	results, err := dispatchCall(fn, params)
+	if err != nil {
+		return WriteMultiPack(PackedData(0), WriteStringPack(err.Error()))
+	}
+
+	return WriteMultiPack(PackedData(results), PackedData(0))
+}
+
+func dispatchCall(name string, params []PackedData) (MultiPackedData, error) {
+
+	e, ok := exports[name]
+	if !ok {
+		return 0, fmt.Errorf("function %s is not exported", name)
+	}
+
+	if len(params) != len(e.params) {
+		return 0, fmt.Errorf("expected %d params, got %d", len(e.params), len(params))
+	}
+
+	args := make([]any, len(params))
+	for i, pd := range params {
+		arg, err := readPack(pd, e.params[i])
+		if err != nil {
+			return 0, fmt.Errorf("param %d: %w", i, err)
+		}
+		args[i] = arg
+	}
+
+	results, err := e.call(args)
+	if err != nil {
+		return 0, err
+	}
+
+	pds := make([]PackedData, len(results))
+	for i, r := range results {
+		pds[i] = writePack(r)
+	}
+
+	return WriteMultiPack(pds...), nil
+}
+
+// readPack reads the value of a pack of the expected type.
+// Bytes are copied, so the pack can be freed.
+func readPack(pd PackedData, expected types.ValueType) (any, error) {
+
+	if vt, _, _ := unpackUI64(uint64(pd)); vt != expected {
+		return nil, fmt.Errorf("expected %s, got %s", expected, vt)
+	}
+
+	switch expected {
+	case types.ValueTypeBytes:
+		return bytes.Clone(ReadBytesPack(pd)), nil
+	case types.ValueTypeByte:
+		return ReadBytePack(pd), nil
+	case types.ValueTypeI32:
+		return ReadI32Pack(pd), nil
+	case types.ValueTypeI64:
+		return ReadI64Pack(pd), nil
+	case types.ValueTypeF32:
+		return ReadF32Pack(pd), nil
+	case types.ValueTypeF64:
+		return ReadF64Pack(pd), nil
+	default:
+		return ReadStringPack(pd), nil
+	}
+}
+
+// writePack writes a value of a base type into memory.
+func writePack(v any) PackedData {
+
+	switch v := v.(type) {
+	case []byte:
+		return WriteBytesPack(v)
+	case byte:
+		return WriteBytePack(v)
+	case uint32:
+		return WriteUint32Pack(v)
+	case uint64:
+		return WriteUint64Pack(v)
+	case float32:
+		return WriteFloat32Pack(v)
+	case float64:
+		return WriteFloat64Pack(v)
+	default:
+		return WriteStringPack(v.(string))
+	}
+}
@@ -0,0 +1,73 @@
+package mdk
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go/internal/types"
+)
+
+func TestExport(t *testing.T) {
+
+	type Celsius float64
+
+	t.Run("signatures", func(t *testing.T) {
+
+		e, err := newExport(func(c Celsius, data []byte) (string, uint32, error) { return "", 0, nil })
+		assert.NoError(t, err)
+		assert.Equal(t, []types.ValueType{types.ValueTypeF64, types.ValueTypeBytes}, e.params)
+		assert.Equal(t, []types.ValueType{types.ValueTypeString, types.ValueTypeI32}, e.results)
+		assert.True(t, e.hasError)
+
+		for _, fn := range []any{
+			nil,
+			"greet",
+			(func())(nil),
+			func(int) {},
+			func() []string { return nil },
+			func(error) {},
+			func() (error, string) { return nil, "" },
+			func(...string) {},
+		} {
+			_, err := newExport(fn)
+			assert.Error(t, err, "%T", fn)
+		}
+
+		assert.Panics(t, func() { Export("invalid", func(int) {}) })
+
+		Export("duplicate", func() {})
+		defer delete(exports, "duplicate")
+		assert.Panics(t, func() { Export("duplicate", func() {}) })
+	})
+
+	t.Run("call", func(t *testing.T) {
+
+		e, err := newExport(func(c Celsius, name string) (float64, string, error) {
+			if name == "" {
+				return 0, "", errors.New("name is empty")
+			}
+			if c < -273.15 {
+				panic("below absolute zero")
+			}
+			return float64(c)*9/5 + 32, "Hello, " + name, nil
+		})
+		assert.NoError(t, err)
+
+		results, err := e.call([]any{float64(100), "Wasify"})
+		assert.NoError(t, err)
+		assert.Equal(t, []any{float64(212), "Hello, Wasify"}, results)
+
+		_, err = e.call([]any{float64(100), ""})
+		assert.EqualError(t, err, "name is empty")
+
+		_, err = e.call([]any{float64(-300), "Wasify"})
+		assert.EqualError(t, err, "panic: below absolute zero")
+
+		_, err = e.call([]any{float64(100)})
+		assert.EqualError(t, err, "expected 2 params, got 1")
+
+		_, err = e.call([]any{"Wasify", float64(100)})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,10 @@
+//go:build wasm && go1.24
+
+package mdk
+
+// _dispatch is the single export the host invokes functions registered with Export through.
+
+//go:wasmexport wasify_dispatch
+func _dispatch(name PackedData, args MultiPackedData) MultiPackedData {
+	return dispatch(name, args)
+}
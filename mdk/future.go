@@ -0,0 +1,147 @@
+package mdk
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/wasify-io/wasify-go/internal/types"
+)
+
+// Future is the pending result of a call to an async host function, see wasify.HostFunction.AsyncCallback.
+//
+// The host function returns right away, and runs on the host while the guest goes on, e.g. calls other async host functions,
+// so their I/O runs concurrently:
+//
+//	//go:wasmimport host fetch
+//	func _fetch(mdk.PackedData) mdk.MultiPackedData
+//
+//	func fetch(url string) *mdk.Future {
+//		pd := mdk.WriteStringPack(url)
+//		defer mdk.FreePack(pd)
+//
+//		return mdk.NewFuture(_fetch(pd))
+//	}
+//
+//	a, b := fetch("https://a.example"), fetch("https://b.example")
+//
+//	pds, err := a.Await()
+//	...
+//	pds, err = b.Await()
+//
+// A Future is read once, by Poll or Await, or dropped with Drop if the guest doesn't need its results.
+type Future struct {
+	handle uint64
+
+	// err is the reason the future can't be read, if the host function didn't return a handle.
+	err error
+}
+
+var (
+	errInvalidFuture = errors.New("invalid future, the host function isn't async")
+	errFutureRead    = errors.New("the future has already been read or dropped")
+)
+
+// NewFuture returns the Future returned by an async host function, as the MultiPackedData of its stub.
+// It frees mpd.
+func NewFuture(mpd MultiPackedData) *Future {
+
+	pds := mpd.ReadPacks()
+	defer FreePack(pds...)
+
+	if len(pds) != 1 {
+		return &Future{err: errInvalidFuture}
+	}
+
+	handle, err := readPack(pds[0], types.ValueTypeI64)
+	if err != nil {
+		return &Future{err: fmt.Errorf("%w: %w", errInvalidFuture, err)}
+	}
+
+	return &Future{handle: handle.(uint64)}
+}
+
+// Poll returns the results of the host function and true if it's completed, or false if it's still running.
+// The results must be freed with FreePack. The error is the one the host failed the future with.
+func (f *Future) Poll() ([]PackedData, bool, error) {
+
+	pd, err := f.pack()
+	if err != nil {
+		return nil, true, err
+	}
+	defer FreePack(pd)
+
+	envelope := _futurePoll(pd)
+	if envelope == 0 {
+		return nil, false, nil
+	}
+
+	f.handle = 0
+	results, err := readEnvelope(envelope)
+
+	return results, true, err
+}
+
+// Await blocks until the host function is completed and returns its results, like Poll.
+// The guest traps if the context of the guest function call is done on the host before.
+func (f *Future) Await() ([]PackedData, error) {
+
+	pd, err := f.pack()
+	if err != nil {
+		return nil, err
+	}
+	defer FreePack(pd)
+
+	f.handle = 0
+
+	return readEnvelope(_futureWait(pd))
+}
+
+// Drop tells the host the results of the future won't be read, so it can forget them.
+// The host function isn't stopped. Dropping a future which has been read does nothing.
+func (f *Future) Drop() {
+
+	if f.handle == 0 {
+		return
+	}
+
+	pd := WriteUint64Pack(f.handle)
+	defer FreePack(pd)
+
+	f.handle = 0
+	_futureDrop(pd)
+}
+
+// pack writes the handle of the future into an I64 pack, the host reads futures by handle.
+func (f *Future) pack() (PackedData, error) {
+
+	if f.err != nil {
+		return 0, f.err
+	}
+
+	if f.handle == 0 {
+		return 0, errFutureRead
+	}
+
+	return WriteUint64Pack(f.handle), nil
+}
+
+// readEnvelope reads the results and the error of a completed future, as the host writes them:
+// the MultiPackedData of the results, or 0, and a string pack of the error, or 0.
+// The envelope and the error are freed.
+func readEnvelope(envelope MultiPackedData) ([]PackedData, error) {
+
+	pds := envelope.ReadPacks()
+	if len(pds) != 2 {
+		FreePack(pds...)
+		return nil, fmt.Errorf("invalid future results, expected 2 packs, got %d", len(pds))
+	}
+
+	results, hostErr := MultiPackedData(pds[0]), pds[1]
+
+	if hostErr != 0 {
+		defer FreePack(hostErr)
+		return nil, errors.New(ReadStringPack(hostErr))
+	}
+
+	return results.ReadPacks(), nil
+}
@@ -0,0 +1,22 @@
+package mdk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidFuture(t *testing.T) {
+
+	// A host function which isn't async returns no handle.
+	f := NewFuture(0)
+
+	_, err := f.Await()
+	assert.ErrorIs(t, err, errInvalidFuture)
+
+	_, done, err := f.Poll()
+	assert.True(t, done)
+	assert.ErrorIs(t, err, errInvalidFuture)
+
+	f.Drop()
+}
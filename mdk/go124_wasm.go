@@ -0,0 +1,7 @@
+//go:build wasm && !go1.24
+
+package mdk
+
+// The mdk exports its allocator and guest functions to the host with //go:wasmexport, which needs Go 1.24 or later.
+// Building a guest with an older Go fails on this undefined name, instead of producing a module the host can't call.
+var _ = mdk_requires_go_1_24_or_later
@@ -1,9 +1,10 @@
 package mdk
 
-import "fmt"
+import (
+	"fmt"
 
-//go:wasmimport wasify log
-func _log(PackedData, PackedData)
+	"github.com/wasify-io/wasify-go/internal/attrs"
+)
 
 func Log(format string, a ...any) {
 	LogDebug(format, a...)
@@ -26,5 +27,137 @@ func LogError(format string, a ...any) {
 }
 
 func _slog(format string, lvl byte, a ...any) {
-	_log(WriteStringPack(fmt.Sprintf(format, a...)), WriteBytePack(lvl))
+	msg, level := WriteStringPack(fmt.Sprintf(format, a...)), WriteBytePack(lvl)
+	defer FreePack(msg, level)
+
+	_log(msg, level)
+}
+
+// TraceParent returns the W3C traceparent of the host span the guest is running in,
+// e.g. "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+// Pass it along with the guest's own outgoing requests to continue the trace.
+// It returns an empty string if the host has no Tracer configured.
+func TraceParent() string {
+
+	mpd := _traceParent()
+
+	pds := mpd.ReadPacks()
+	if len(pds) == 0 {
+		return ""
+	}
+	defer FreePack(pds...)
+
+	return ReadStringPack(pds[0])
+}
+
+// Logger writes structured log records with key/value attributes into the host's slog handler.
+//
+// Example:
+//
+//	log := mdk.NewLogger("billing").With("tenant", tenantID)
+//	log.Info("invoice created", "amount", 42.5, "items", 3)
+type Logger struct {
+	name  string
+	attrs []byte
+}
+
+// NewLogger returns a Logger with a name, which is added to every record as the "logger" attribute.
+func NewLogger(name string) *Logger {
+	return &Logger{name: name}
+}
+
+// With returns a Logger which includes the given attributes in every record.
+// args are alternating keys and values, like in slog.Logger.With.
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{
+		name:  l.name,
+		attrs: appendArgs(append([]byte(nil), l.attrs...), args...),
+	}
+}
+
+func (l *Logger) Debug(msg string, args ...any) {
+	l.log(1, msg, args...)
+}
+
+func (l *Logger) Info(msg string, args ...any) {
+	l.log(2, msg, args...)
+}
+
+func (l *Logger) Warning(msg string, args ...any) {
+	l.log(3, msg, args...)
+}
+
+func (l *Logger) Error(msg string, args ...any) {
+	l.log(4, msg, args...)
+}
+
+func (l *Logger) log(lvl byte, msg string, args ...any) {
+
+	pds := []PackedData{
+		WriteStringPack(msg),
+		WriteBytePack(lvl),
+		WriteStringPack(l.name),
+		WriteBytesPack(appendArgs(append([]byte(nil), l.attrs...), args...)),
+	}
+	defer FreePack(pds...)
+
+	_structuredLog(pds[0], pds[1], pds[2], pds[3])
+}
+
+// appendArgs encodes alternating keys and values into buf.
+// A key without a value is encoded with the "!BADKEY" key, like slog does.
+func appendArgs(buf []byte, args ...any) []byte {
+
+	for len(args) > 0 {
+
+		key, ok := args[0].(string)
+		if !ok || len(args) == 1 {
+			buf = appendAttr(buf, "!BADKEY", args[0])
+			args = args[1:]
+			continue
+		}
+
+		buf = appendAttr(buf, key, args[1])
+		args = args[2:]
+	}
+
+	return buf
+}
+
+func appendAttr(buf []byte, key string, v any) []byte {
+
+	switch vTyped := v.(type) {
+	case string:
+		return attrs.AppendString(buf, key, vTyped)
+	case int:
+		return attrs.AppendInt64(buf, key, int64(vTyped))
+	case int8:
+		return attrs.AppendInt64(buf, key, int64(vTyped))
+	case int16:
+		return attrs.AppendInt64(buf, key, int64(vTyped))
+	case int32:
+		return attrs.AppendInt64(buf, key, int64(vTyped))
+	case int64:
+		return attrs.AppendInt64(buf, key, vTyped)
+	case uint:
+		return attrs.AppendUint64(buf, key, uint64(vTyped))
+	case uint8:
+		return attrs.AppendUint64(buf, key, uint64(vTyped))
+	case uint16:
+		return attrs.AppendUint64(buf, key, uint64(vTyped))
+	case uint32:
+		return attrs.AppendUint64(buf, key, uint64(vTyped))
+	case uint64:
+		return attrs.AppendUint64(buf, key, vTyped)
+	case float32:
+		return attrs.AppendFloat64(buf, key, float64(vTyped))
+	case float64:
+		return attrs.AppendFloat64(buf, key, vTyped)
+	case bool:
+		return attrs.AppendBool(buf, key, vTyped)
+	case error:
+		return attrs.AppendString(buf, key, vTyped.Error())
+	default:
+		return attrs.AppendString(buf, key, fmt.Sprint(vTyped))
+	}
 }
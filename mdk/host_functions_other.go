@@ -0,0 +1,22 @@
+//go:build !wasm
+
+package mdk
+
+// The host functions are only imported by wasm guests.
+// Elsewhere they do nothing, so the mdk can be built and unit tested natively.
+
+func _log(PackedData, PackedData) {}
+
+func _structuredLog(PackedData, PackedData, PackedData, PackedData) {}
+
+func _traceParent() MultiPackedData { return 0 }
+
+func _futurePoll(PackedData) MultiPackedData { return 0 }
+
+func _futureWait(PackedData) MultiPackedData { return 0 }
+
+func _futureDrop(PackedData) {}
+
+func _subscribe(PackedData) MultiPackedData { return 0 }
+
+func _unsubscribe(PackedData) {}
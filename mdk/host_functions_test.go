@@ -0,0 +1,32 @@
+package mdk
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go/internal/attrs"
+)
+
+func TestLoggerAttrs(t *testing.T) {
+
+	log := NewLogger("billing").With("tenant", "acme")
+	assert.Equal(t, "billing", log.name)
+
+	encoded := appendArgs(append([]byte(nil), log.attrs...), "amount", 42.5, "items", 3, "paid", true, "err", errors.New("declined"), "dangling")
+
+	decoded, err := attrs.Decode(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, []attrs.Attr{
+		{Key: "tenant", Value: "acme"},
+		{Key: "amount", Value: 42.5},
+		{Key: "items", Value: int64(3)},
+		{Key: "paid", Value: true},
+		{Key: "err", Value: "declined"},
+		{Key: "!BADKEY", Value: "dangling"},
+	}, decoded)
+
+	// With doesn't modify the parent logger.
+	child := log.With("invoice", uint32(7))
+	assert.NotEqual(t, log.attrs, child.attrs)
+}
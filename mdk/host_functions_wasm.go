@@ -0,0 +1,27 @@
+//go:build wasm
+
+package mdk
+
+//go:wasmimport wasify log
+func _log(PackedData, PackedData)
+
+//go:wasmimport wasify slog
+func _structuredLog(PackedData, PackedData, PackedData, PackedData)
+
+//go:wasmimport wasify trace_parent
+func _traceParent() MultiPackedData
+
+//go:wasmimport wasify future_poll
+func _futurePoll(PackedData) MultiPackedData
+
+//go:wasmimport wasify future_wait
+func _futureWait(PackedData) MultiPackedData
+
+//go:wasmimport wasify future_drop
+func _futureDrop(PackedData)
+
+//go:wasmimport wasify subscribe
+func _subscribe(PackedData) MultiPackedData
+
+//go:wasmimport wasify unsubscribe
+func _unsubscribe(PackedData)
@@ -0,0 +1,170 @@
+package mdk
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/wasify-io/wasify-go/internal/types"
+)
+
+var (
+	packedDataType      = reflect.TypeOf(PackedData(0))
+	multiPackedDataType = reflect.TypeOf(MultiPackedData(0))
+)
+
+// Import wraps the stub of a host function import as a typed Go function of type F, e.g.
+//
+//	//go:wasmimport host greet
+//	func _greet(mdk.PackedData, mdk.PackedData) mdk.MultiPackedData
+//
+//	var greet = mdk.Import[func(name string, times uint32) (string, error)](_greet)
+//
+// The returned function writes its params into packs, calls the stub, reads the results
+// and frees all the packs, so the guest uses the host function as any other Go function.
+//
+// The params and results of F can be []byte, byte, uint32, uint64, float32, float64 and string,
+// or types based on them, matching the Params and Results of the host function.
+// The last result of F must be an error, which is returned if the results of the host function
+// don't match the results of F.
+//
+// The stub must take one PackedData per param of F, and return a MultiPackedData if F has results other than the error.
+//
+// Import panics if F or stub don't have supported signatures.
+//
+// NOTE: Imported functions are created through reflection, which requires Go or a TinyGo version implementing reflect.MakeFunc.
+func Import[F any](stub any) F {
+
+	var fn F
+
+	t := reflect.TypeOf(fn)
+
+	sig, err := newImport(t, reflect.TypeOf(stub))
+	if err != nil {
+		panic(fmt.Sprintf("mdk: can't import %T as %s: %v", stub, t, err))
+	}
+
+	s := reflect.ValueOf(stub)
+
+	reflect.ValueOf(&fn).Elem().Set(reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+		return sig.call(s, args)
+	}))
+
+	return fn
+}
+
+// hostImport is the signature of a host function wrapped by Import.
+type hostImport struct {
+	t       reflect.Type
+	params  []types.ValueType
+	results []types.ValueType
+}
+
+func newImport(t reflect.Type, stub reflect.Type) (*hostImport, error) {
+
+	if t == nil || t.Kind() != reflect.Func || t.IsVariadic() {
+		return nil, fmt.Errorf("%v is not a non-variadic function type", t)
+	}
+
+	if t.NumOut() == 0 || t.Out(t.NumOut()-1) != errorType {
+		return nil, fmt.Errorf("the last result of %s must be an error", t)
+	}
+
+	imp := &hostImport{t: t}
+
+	for i := 0; i < t.NumIn(); i++ {
+		vt, ok := valueTypeOf(t.In(i))
+		if !ok {
+			return nil, fmt.Errorf("unsupported param %d type %s", i, t.In(i))
+		}
+		imp.params = append(imp.params, vt)
+	}
+
+	for i := 0; i < t.NumOut()-1; i++ {
+		vt, ok := valueTypeOf(t.Out(i))
+		if !ok {
+			return nil, fmt.Errorf("unsupported result %d type %s", i, t.Out(i))
+		}
+		imp.results = append(imp.results, vt)
+	}
+
+	if stub == nil || stub.Kind() != reflect.Func || stub.IsVariadic() || stub.NumIn() != len(imp.params) {
+		return nil, fmt.Errorf("the stub must be a function taking %d PackedData", len(imp.params))
+	}
+
+	for i := 0; i < stub.NumIn(); i++ {
+		if stub.In(i) != packedDataType {
+			return nil, fmt.Errorf("stub param %d must be a PackedData, got %s", i, stub.In(i))
+		}
+	}
+
+	switch {
+	case len(imp.results) > 0 && (stub.NumOut() != 1 || stub.Out(0) != multiPackedDataType):
+		return nil, fmt.Errorf("the stub must return a MultiPackedData")
+	case len(imp.results) == 0 && stub.NumOut() > 1, stub.NumOut() == 1 && stub.Out(0) != multiPackedDataType:
+		return nil, fmt.Errorf("the stub must return nothing or a MultiPackedData")
+	}
+
+	return imp, nil
+}
+
+// call writes args into packs, calls the stub and reads its results.
+func (imp *hostImport) call(stub reflect.Value, args []reflect.Value) []reflect.Value {
+
+	params := make([]PackedData, len(args))
+	in := make([]reflect.Value, len(args))
+
+	for i, arg := range args {
+		params[i] = writePack(baseValue(arg, imp.params[i]))
+		in[i] = reflect.ValueOf(params[i])
+	}
+
+	out := stub.Call(in)
+
+	FreePack(params...)
+
+	var mpd MultiPackedData
+	if len(out) == 1 {
+		mpd = out[0].Interface().(MultiPackedData)
+	}
+
+	results, err := imp.readResults(mpd)
+
+	values := make([]reflect.Value, imp.t.NumOut())
+	for i := range imp.results {
+		if err == nil {
+			values[i] = reflect.ValueOf(results[i]).Convert(imp.t.Out(i))
+		} else {
+			values[i] = reflect.Zero(imp.t.Out(i))
+		}
+	}
+
+	errValue := reflect.New(errorType).Elem()
+	if err != nil {
+		errValue.Set(reflect.ValueOf(err))
+	}
+	values[len(values)-1] = errValue
+
+	return values
+}
+
+// readResults reads and frees the results of the host function.
+func (imp *hostImport) readResults(mpd MultiPackedData) ([]any, error) {
+
+	pds := mpd.ReadPacks()
+	defer FreePack(pds...)
+
+	if len(pds) != len(imp.results) {
+		return nil, fmt.Errorf("expected %d results, got %d", len(imp.results), len(pds))
+	}
+
+	results := make([]any, len(pds))
+	for i, pd := range pds {
+		result, err := readPack(pd, imp.results[i])
+		if err != nil {
+			return nil, fmt.Errorf("result %d: %w", i, err)
+		}
+		results[i] = result
+	}
+
+	return results, nil
+}
@@ -0,0 +1,52 @@
+package mdk
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go/internal/types"
+)
+
+func TestImport(t *testing.T) {
+
+	type Celsius float64
+
+	typeOf := func(v any) reflect.Type { return reflect.TypeOf(v) }
+
+	imp, err := newImport(typeOf(func(string, Celsius) ([]byte, uint64, error) { return nil, 0, nil }), typeOf(func(PackedData, PackedData) MultiPackedData { return 0 }))
+	assert.NoError(t, err)
+	assert.Equal(t, []types.ValueType{types.ValueTypeString, types.ValueTypeF64}, imp.params)
+	assert.Equal(t, []types.ValueType{types.ValueTypeBytes, types.ValueTypeI64}, imp.results)
+
+	// Host functions without results may return nothing.
+	_, err = newImport(typeOf(func(byte) error { return nil }), typeOf(func(PackedData) {}))
+	assert.NoError(t, err)
+
+	_, err = newImport(typeOf(func(byte) error { return nil }), typeOf(func(PackedData) MultiPackedData { return 0 }))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name string
+		fn   any
+		stub any
+	}{
+		{"not a function", "greet", func() {}},
+		{"no error", func() string { return "" }, func() MultiPackedData { return 0 }},
+		{"unsupported param", func(int) error { return nil }, func(PackedData) {}},
+		{"unsupported result", func() (bool, error) { return false, nil }, func() MultiPackedData { return 0 }},
+		{"stub not a function", func() error { return nil }, nil},
+		{"stub params count", func(string) error { return nil }, func() {}},
+		{"stub param type", func(string) error { return nil }, func(uint64) {}},
+		{"stub without results", func() (string, error) { return "", nil }, func() {}},
+		{"stub results type", func() (string, error) { return "", nil }, func() PackedData { return 0 }},
+	}
+
+	for _, tt := range tests {
+		_, err := newImport(typeOf(tt.fn), typeOf(tt.stub))
+		assert.Error(t, err, tt.name)
+	}
+
+	assert.Panics(t, func() { Import[func(int) error](func(PackedData) {}) })
+	assert.NotPanics(t, func() { Import[func(string) error](func(PackedData) {}) })
+}
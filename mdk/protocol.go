@@ -0,0 +1,11 @@
+package mdk
+
+// ProtocolVersion is the version of the wire protocol implemented by the mdk,
+// which the host reads from the wasify_protocol_version export at instantiation.
+//
+// Go can't emit the wasify_protocol_version custom section other SDKs declare the version with,
+// so an mdk guest the host doesn't support is only refused once it's instantiated: the export can't be called
+// before the Go runtime is initialized, so _initialize, and with it the init functions and package variables
+// of the guest, runs before the version is checked. They shouldn't call host functions, which a host refusing
+// the guest may not decode.
+const ProtocolVersion uint32 = 1
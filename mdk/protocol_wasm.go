@@ -0,0 +1,11 @@
+//go:build wasm && go1.24
+
+package mdk
+
+// _protocolVersion declares the version of the wire protocol the guest implements,
+// so the host refuses to instantiate guests it can't decode.
+
+//go:wasmexport wasify_protocol_version
+func _protocolVersion() uint32 {
+	return ProtocolVersion
+}
@@ -1,8 +1,5 @@
 package mdk
 
-// #include <stdlib.h>
-// #include <string.h>
-import "C"
 import (
 	"encoding/binary"
 	"fmt"
@@ -53,54 +50,54 @@ func readString(offset64 uint64, size int) string {
 // It allocates memory of size 'len(data)' and copies the data into this memory.
 // It returns the offset to the allocated memory and the size of the data.
 func bytesToLeakedPtr(data []byte, offsetSize uint32) (offset uint64) {
-	ptr := unsafe.Pointer(C.malloc(C.ulong(offsetSize)))
-	copy(unsafe.Slice((*byte)(ptr), C.ulong(offsetSize)), data)
-	return uint64(uintptr(ptr))
+	ptr := malloc(offsetSize)
+	copy(unsafe.Slice(allocated[byte](ptr), offsetSize), data)
+	return ptr
 }
 
 // byteToLeakedPtr allocates memory for a byte (uint8) and stores the value in that memory.
 // It returns the offset to the allocated memory.
 func byteToLeakedPtr(data byte) (offset uint64) {
-	ptr := unsafe.Pointer(C.malloc(1))
-	*(*byte)(ptr) = data
+	ptr := malloc(1)
+	*allocated[byte](ptr) = data
 
-	return uint64(uintptr(ptr))
+	return ptr
 }
 
 // uint64ToLeakedPtr allocates memory for a uint32 and stores the value in that memory.
 // It returns the offset to the allocated memory.
 func uint32ToLeakedPtr(data uint32) (offset uint64) {
-	ptr := unsafe.Pointer(C.malloc(4))
-	*(*uint32)(ptr) = data
+	ptr := malloc(4)
+	*allocated[uint32](ptr) = data
 
-	return uint64(uintptr(ptr))
+	return ptr
 }
 
 // uint64ToLeakedPtr allocates memory for a uint64 and stores the value in that memory.
 // It returns the offset to the allocated memory.
 func uint64ToLeakedPtr(data uint64) (offset uint64) {
-	ptr := unsafe.Pointer(C.malloc(4))
-	*(*uint64)(ptr) = data
+	ptr := malloc(8)
+	*allocated[uint64](ptr) = data
 
-	return uint64(uintptr(ptr))
+	return ptr
 }
 
 // float32ToLeakedPtr allocates memory for a float32 and stores the value in that memory.
 // It returns the offset to the allocated memory.
 func float32ToLeakedPtr(data float32) (offset uint64) {
-	ptr := unsafe.Pointer(C.malloc(4))
-	*(*float32)(ptr) = data
+	ptr := malloc(4)
+	*allocated[float32](ptr) = data
 
-	return uint64(uintptr(ptr))
+	return ptr
 }
 
 // float64ToLeakedPtr allocates memory for a float64 and stores the value in that memory.
 // It returns the offset to the allocated memory.
 func float64ToLeakedPtr(data float64) (offset uint64) {
-	ptr := unsafe.Pointer(C.malloc(4))
-	*(*float64)(ptr) = data
+	ptr := malloc(8)
+	*allocated[float64](ptr) = data
 
-	return uint64(uintptr(ptr))
+	return ptr
 }
 
 // stringToLeakedPtr allocates memory for a string and stores the value in that memory.
@@ -110,13 +107,6 @@ func stringToLeakedPtr(data string, offsetSize uint32) (offset uint64) {
 	return bytesToLeakedPtr(byteSlice, offsetSize)
 }
 
-// free deallocates the memory previously allocated by a call to malloc (C.malloc).
-// The offset parameter is a uint64 representing the starting address of the block
-// of linear memory to be deallocated.
-func free(offset uint64) {
-	C.free(unsafe.Pointer(uintptr(offset)))
-}
-
 // packUI64 takes a data type (in the form of a byte), a pointer (offset in memory),
 // and a size (amount of memory/data to consider). It returns a packed uint64 representation.
 //
@@ -15,7 +15,7 @@ func TestToLeakedPtr(t *testing.T) {
 	}
 
 	readBackBytes := func(offset uint64, expected any) bool {
-		readBack := unsafe.Slice(ptrToData[byte](offset), len(expected.([]byte)))
+		readBack := unsafe.Slice(allocated[byte](offset), len(expected.([]byte)))
 		for i, v := range readBack {
 			if v != expected.([]byte)[i] {
 				return false
@@ -25,28 +25,28 @@ func TestToLeakedPtr(t *testing.T) {
 	}
 
 	readBackByte := func(offset uint64, expected any) bool {
-		return *ptrToData[byte](offset) == expected.(byte)
+		return *allocated[byte](offset) == expected.(byte)
 	}
 
 	readBackUint32 := func(offset uint64, expected any) bool {
-		return *ptrToData[uint32](offset) == expected.(uint32)
+		return *allocated[uint32](offset) == expected.(uint32)
 	}
 
 	readBackUint64 := func(offset uint64, expected any) bool {
-		return *ptrToData[uint64](offset) == expected.(uint64)
+		return *allocated[uint64](offset) == expected.(uint64)
 	}
 
 	readBackFloat32 := func(offset uint64, expected any) bool {
-		return *ptrToData[float32](offset) == expected.(float32)
+		return *allocated[float32](offset) == expected.(float32)
 	}
 
 	readBackFloat64 := func(offset uint64, expected any) bool {
-		return *ptrToData[float64](offset) == expected.(float64)
+		return *allocated[float64](offset) == expected.(float64)
 	}
 
 	readBackString := func(offset uint64, expected any) bool {
 		len := len(expected.(string))
-		return string(unsafe.Slice(ptrToData[byte](offset), len)) == expected.(string)
+		return string(unsafe.Slice(allocated[byte](offset), len)) == expected.(string)
 	}
 
 	tests := []testCase{
@@ -0,0 +1,60 @@
+package wasify
+
+import (
+	"time"
+)
+
+// Metrics records invocation, allocation and memory metrics per module and function.
+// See the promwasify package for a Prometheus-compatible implementation.
+//
+// Implementations must be safe for concurrent use, and fast, as they are called on every call and allocation.
+type Metrics interface {
+	// ObserveCall records a finished GuestFunction.Invoke or host function callback.
+	ObserveCall(call CallInfo, result CallResult)
+
+	// ObserveMalloc records an allocation of size bytes in the linear memory of the module.
+	ObserveMalloc(namespace string, size uint32)
+
+	// ObserveFree records a release of memory in the linear memory of the module.
+	ObserveFree(namespace string)
+
+	// ObserveMemorySize records the current size in bytes of the linear memory of the module.
+	ObserveMemorySize(namespace string, size uint32)
+}
+
+// CallResult describes the outcome of a guest or host function call.
+type CallResult struct {
+	// Duration is the wall time of the call, including nested host function calls.
+	Duration time.Duration
+
+	// ResultsSize is the total size in bytes of the data referenced by the results.
+	ResultsSize uint64
+
+	// Err is the error the call failed with, if any.
+	Err error
+}
+
+// noopMetrics is used when no Metrics is configured.
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveCall(CallInfo, CallResult) {}
+func (noopMetrics) ObserveMalloc(string, uint32)     {}
+func (noopMetrics) ObserveFree(string)               {}
+func (noopMetrics) ObserveMemorySize(string, uint32) {}
+
+// multiPackedDataSize returns the total size of the data referenced by the packs of mpd.
+func multiPackedDataSize(memory Memory, mpd uint64) uint64 {
+
+	pds, err := readPacks(memory, mpd)
+	if err != nil {
+		return 0
+	}
+
+	var total uint64
+
+	for _, pd := range pds {
+		total += packedDataSize(uint64(pd))
+	}
+
+	return total
+}
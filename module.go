@@ -6,13 +6,20 @@ import (
 )
 
 type Module interface {
+	ModuleInfo
 	Close(ctx context.Context) error
 	GuestFunction(ctx context.Context, functionName string) GuestFunction
 	Memory() Memory
+	Snapshot() (*Snapshot, error)
+	Reset(ctx context.Context) error
+	ProtocolVersion() uint32
 }
 
 type ModuleProxy struct {
 	Memory Memory
+
+	// module is the module instance calling the host function, it holds the state of the instance, e.g. its futures.
+	module *wazeroModule
 }
 
 type GuestFunction interface {
@@ -83,17 +90,85 @@ type ModuleConfig struct {
 	// Note: If LogSeverity isn't specified, the severity is inherited from the parent, like the runtime log severity.
 	LogSeverity LogSeverity
 
+	// Logger and LogHandler route the module's logs into a user-supplied logger or handler.
+	// Note: If neither is specified, the handler is inherited from the runtime.
+	// Module logs always carry "namespace" and, if the binary has a name, "module" attributes.
+	Logger     *slog.Logger
+	LogHandler slog.Handler
+
+	// GuestLogRateLimit limits the volume of logs the guest writes through mdk.
+	// Records over the limit are dropped and the number of dropped records is reported
+	// with the next record which passes.
+	// Note: If GuestLogRateLimit isn't specified, guest logs are not limited.
+	GuestLogRateLimit RateLimit
+
+	// TypeValidation sets how mismatched PackedData types are handled, when reading packs from memory
+	// and when the guest calls a HostFunction. See TypeValidation for more details.
+	// Default: TypeValidationLenient
+	TypeValidation TypeValidation
+
+	// EnableSnapshots exports every global of the module when it's compiled, so Module.Snapshot can capture them.
+	// The binary is only rewritten when it's set, or when Snapshot is.
+	// Note: If EnableSnapshots isn't set, Module.Snapshot returns an error.
+	EnableSnapshots bool
+
+	// EnableReset keeps a copy of the memory of the module right after instantiation, so Module.Reset can restore it.
+	// It exports the globals of the module like EnableSnapshots.
+	// Note: If EnableReset isn't set, Module.Reset returns an error.
+	EnableReset bool
+
+	// Snapshot instantiates the module from a snapshot taken with Module.Snapshot,
+	// instead of running the start functions of the module.
+	// The snapshot must have been taken from the same wasm binary.
+	Snapshot *Snapshot
+
+	// RequireProtocolVersion refuses guests which don't declare the version of the wire protocol they implement,
+	// instead of assuming they implement version 1. See ProtocolVersion for more details.
+	RequireProtocolVersion bool
+
+	// Events is the EventBus the guest receives the events it subscribed to from, see mdk.Subscribe.
+	// Note: If Events isn't specified, the guest can't subscribe to events.
+	Events *EventBus
+
+	// EventQueueSize is the number of events queued for the module, before EventBus.Publish waits for it to deliver them.
+	// Default: 64
+	EventQueueSize int
+
+	// Interceptors wrap every guest function invocation and host function callback of the module.
+	// They run after the interceptors of the runtime, see Interceptor for more details.
+	Interceptors []Interceptor
+
 	// Struct members for internal use.
-	ctx context.Context
-	log *slog.Logger
+	ctx          context.Context
+	log          *slog.Logger
+	tracer       Tracer
+	metrics      Metrics
+	interceptors []Interceptor
+
+	// anonymous instantiates the guest without the module name of its binary,
+	// so several versions of it can run in the same runtime, see ReloadableModule.
+	anonymous bool
 }
 
 // Wasm configures a new wasm file.
 // Binay is required.
 // Hash is optional.
+// Signature is optional, it's a detached ed25519 signature of the Binary,
+// verified against RuntimeConfig.TrustStore.
 type Wasm struct {
-	Binary []byte
-	Hash   string
+	Binary    []byte
+	Hash      string
+	Signature []byte
+}
+
+// RateLimit configures a token bucket rate limit.
+type RateLimit struct {
+	// Rate is the number of events allowed per second. Zero means no limit.
+	Rate float64
+
+	// Burst is the number of events allowed at once, before Rate applies.
+	// Default: 1
+	Burst int
 }
 
 // FSConfig configures a directory to be pre-opened for access by the WASI module if Enabled is set to true.
@@ -111,6 +186,20 @@ type FSConfig struct {
 	GuestDir string
 }
 
+// logHandler returns the user-supplied log handler, or nil if the runtime's one should be used.
+func (c *ModuleConfig) logHandler() slog.Handler {
+	if c.Logger != nil {
+		return c.Logger.Handler()
+	}
+
+	return c.LogHandler
+}
+
+// exportGlobals reports whether the globals of the module must be exported, to take or restore snapshots, or to reset it.
+func (c *ModuleConfig) exportGlobals() bool {
+	return c.EnableSnapshots || c.EnableReset || c.Snapshot != nil
+}
+
 // getGuestDir gets the default path for guest module.
 func (fs *FSConfig) getGuestDir() string {
 
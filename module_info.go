@@ -0,0 +1,125 @@
+package wasify
+
+import (
+	"context"
+	"strings"
+)
+
+// WasmValueType is a raw WebAssembly value type as it appears in a function signature.
+//
+// Unlike ValueType, which describes the data carried by a PackedData,
+// WasmValueType describes the value on the wasm stack itself.
+type WasmValueType byte
+
+// WebAssembly value types, encoded the same way as in the binary format.
+const (
+	WasmValueTypeI32       WasmValueType = 0x7f
+	WasmValueTypeI64       WasmValueType = 0x7e
+	WasmValueTypeF32       WasmValueType = 0x7d
+	WasmValueTypeF64       WasmValueType = 0x7c
+	WasmValueTypeExternref WasmValueType = 0x6f
+)
+
+func (t WasmValueType) String() string {
+	switch t {
+	case WasmValueTypeI32:
+		return "i32"
+	case WasmValueTypeI64:
+		return "i64"
+	case WasmValueTypeF32:
+		return "f32"
+	case WasmValueTypeF64:
+		return "f64"
+	case WasmValueTypeExternref:
+		return "externref"
+	}
+
+	return "unknown"
+}
+
+// FunctionDefinition describes a function imported or exported by a wasm module.
+type FunctionDefinition struct {
+	// Namespace is the module name the function is imported from.
+	// It is empty for exported functions.
+	Namespace string
+
+	// Name of the function.
+	Name string
+
+	// Params and Results are the wasm types of the function signature.
+	Params  []WasmValueType
+	Results []WasmValueType
+}
+
+// Signature returns the wasm signature of the function, e.g. "(i64, i64) -> (i64)".
+func (f FunctionDefinition) Signature() string {
+	return "(" + joinWasmValueTypes(f.Params) + ") -> (" + joinWasmValueTypes(f.Results) + ")"
+}
+
+func (f FunctionDefinition) String() string {
+	if f.Namespace == "" {
+		return f.Name + f.Signature()
+	}
+
+	return f.Namespace + "." + f.Name + f.Signature()
+}
+
+// MemoryDefinition describes a linear memory imported or exported by a wasm module.
+type MemoryDefinition struct {
+	// Namespace is the module name the memory is imported from.
+	// It is empty for exported memories.
+	Namespace string
+
+	// Name of the memory.
+	Name string
+
+	// Min is the minimum number of 64KiB pages the memory starts with.
+	Min uint32
+
+	// Max is the maximum number of pages, valid only when HasMax is true.
+	Max    uint32
+	HasMax bool
+}
+
+// CustomSection is a custom section of a wasm binary, e.g. "producers" or ".debug_info".
+type CustomSection struct {
+	Name string
+	Data []byte
+}
+
+// ModuleInfo lists what a wasm module imports and exports, so that callers can
+// check a module's compatibility before wiring host functions to it.
+type ModuleInfo interface {
+	// Name returns the module name encoded into the binary, or empty if there is none.
+	Name() string
+
+	// ExportedFunctions returns the exported functions sorted by name.
+	ExportedFunctions() []FunctionDefinition
+
+	// ImportedFunctions returns the imported functions grouped by namespace.
+	ImportedFunctions() map[string][]FunctionDefinition
+
+	// ExportedMemories returns the exported memories sorted by name.
+	ExportedMemories() []MemoryDefinition
+
+	// ImportedMemories returns the imported memories in import order.
+	ImportedMemories() []MemoryDefinition
+
+	// CustomSections returns the custom sections in the order they appear in the binary.
+	CustomSections() []CustomSection
+}
+
+// CompiledModule is a wasm binary which has been compiled by a runtime but not instantiated yet.
+type CompiledModule interface {
+	ModuleInfo
+	Close(ctx context.Context) error
+}
+
+func joinWasmValueTypes(types []WasmValueType) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = t.String()
+	}
+
+	return strings.Join(names, ", ")
+}
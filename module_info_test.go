@@ -0,0 +1,116 @@
+package wasify_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go"
+)
+
+func TestModuleInfo(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+		Runtime:     wasify.RuntimeWazero,
+		LogSeverity: wasify.LogError,
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	i64 := wasify.WasmValueTypeI64
+	hostTest := wasify.FunctionDefinition{
+		Namespace: "host_all_available_types",
+		Name:      "hostTest",
+		Params:    []wasify.WasmValueType{i64, i64, i64, i64, i64, i64, i64},
+		Results:   []wasify.WasmValueType{i64},
+	}
+
+	assertInfo := func(t *testing.T, info wasify.ModuleInfo) {
+
+		imports := info.ImportedFunctions()
+		assert.Equal(t, []wasify.FunctionDefinition{hostTest}, imports["host_all_available_types"])
+		assert.NotEmpty(t, imports["wasi_snapshot_preview1"])
+
+		var names []string
+		for _, fn := range info.ExportedFunctions() {
+			names = append(names, fn.Name)
+			assert.Empty(t, fn.Namespace)
+		}
+		assert.Equal(t, []string{"_start", "calloc", "free", "guestTest", "malloc", "realloc"}, names)
+
+		memories := info.ExportedMemories()
+		assert.Len(t, memories, 1)
+		assert.Equal(t, "memory", memories[0].Name)
+		assert.Empty(t, info.ImportedMemories())
+
+		var sections []string
+		for _, s := range info.CustomSections() {
+			sections = append(sections, s.Name)
+		}
+		assert.Contains(t, sections, "producers")
+	}
+
+	t.Run("compiled module", func(t *testing.T) {
+
+		compiled, err := runtime.CompileModule(ctx, wasify.Wasm{Binary: wasm_hostAllAvailableTypes})
+		assert.NoError(t, err)
+
+		defer func() {
+			err = compiled.Close(ctx)
+			assert.NoError(t, err)
+		}()
+
+		assertInfo(t, compiled)
+	})
+
+	t.Run("instantiated module", func(t *testing.T) {
+
+		module, err := runtime.NewModule(ctx, &wasify.ModuleConfig{
+			Namespace: "host_all_available_types",
+			Wasm: wasify.Wasm{
+				Binary: wasm_hostAllAvailableTypes,
+			},
+			HostFunctions: []wasify.HostFunction{
+				{
+					Name: "hostTest",
+					Callback: func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {
+						return 0
+					},
+					Params:  make([]wasify.ValueType, 7),
+					Results: make([]wasify.ValueType, 1),
+				},
+			},
+		})
+		assert.NoError(t, err)
+
+		defer func() {
+			err = module.Close(ctx)
+			assert.NoError(t, err)
+		}()
+
+		assertInfo(t, module)
+	})
+
+	t.Run("invalid hash", func(t *testing.T) {
+		_, err := runtime.CompileModule(ctx, wasify.Wasm{Binary: wasm_hostAllAvailableTypes, Hash: "invalid"})
+		assert.Error(t, err)
+	})
+}
+
+func TestFunctionDefinitionSignature(t *testing.T) {
+
+	fn := wasify.FunctionDefinition{
+		Namespace: "wasify",
+		Name:      "log",
+		Params:    []wasify.WasmValueType{wasify.WasmValueTypeI64, wasify.WasmValueTypeI64},
+	}
+
+	assert.Equal(t, "(i64, i64) -> ()", fn.Signature())
+	assert.Equal(t, "wasify.log(i64, i64) -> ()", fn.String())
+}
@@ -0,0 +1,135 @@
+package wasify
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// wazeroCompiledModule wraps a wazero compiled module and
+// translates its definitions into runtime-agnostic types.
+type wazeroCompiledModule struct {
+	compiled wazero.CompiledModule
+
+	// globals are the export names of the module globals, see Module.Snapshot.
+	globals []string
+}
+
+func (c *wazeroCompiledModule) Name() string {
+	return c.compiled.Name()
+}
+
+func (c *wazeroCompiledModule) ExportedFunctions() []FunctionDefinition {
+
+	exported := c.compiled.ExportedFunctions()
+
+	defs := make([]FunctionDefinition, 0, len(exported))
+	for name, def := range exported {
+		defs = append(defs, FunctionDefinition{
+			Name:    name,
+			Params:  convertFromAPIValueTypes(def.ParamTypes()),
+			Results: convertFromAPIValueTypes(def.ResultTypes()),
+		})
+	}
+
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+
+	return defs
+}
+
+func (c *wazeroCompiledModule) ImportedFunctions() map[string][]FunctionDefinition {
+
+	defs := make(map[string][]FunctionDefinition)
+
+	for _, def := range c.compiled.ImportedFunctions() {
+		namespace, name, _ := def.Import()
+		defs[namespace] = append(defs[namespace], FunctionDefinition{
+			Namespace: namespace,
+			Name:      name,
+			Params:    convertFromAPIValueTypes(def.ParamTypes()),
+			Results:   convertFromAPIValueTypes(def.ResultTypes()),
+		})
+	}
+
+	return defs
+}
+
+func (c *wazeroCompiledModule) ExportedMemories() []MemoryDefinition {
+
+	exported := c.compiled.ExportedMemories()
+
+	defs := make([]MemoryDefinition, 0, len(exported))
+	for name, def := range exported {
+		md := convertFromAPIMemoryDefinition(def)
+		md.Name = name
+		defs = append(defs, md)
+	}
+
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+
+	return defs
+}
+
+func (c *wazeroCompiledModule) ImportedMemories() []MemoryDefinition {
+
+	imported := c.compiled.ImportedMemories()
+
+	defs := make([]MemoryDefinition, len(imported))
+	for i, def := range imported {
+		md := convertFromAPIMemoryDefinition(def)
+		md.Namespace, md.Name, _ = def.Import()
+		defs[i] = md
+	}
+
+	return defs
+}
+
+func (c *wazeroCompiledModule) CustomSections() []CustomSection {
+
+	sections := c.compiled.CustomSections()
+
+	res := make([]CustomSection, len(sections))
+	for i, s := range sections {
+		res[i] = CustomSection{
+			Name: s.Name(),
+			Data: s.Data(),
+		}
+	}
+
+	return res
+}
+
+// Close releases the compiled code.
+//
+// Note: Modules which are already instantiated from this compiled module are not affected.
+func (c *wazeroCompiledModule) Close(ctx context.Context) error {
+	err := c.compiled.Close(ctx)
+	if err != nil {
+		return errors.Join(errors.New("can't close compiled module"), err)
+	}
+
+	return nil
+}
+
+// convertFromAPIValueTypes converts wazero's api.ValueType values to WasmValueType.
+func convertFromAPIValueTypes(types []api.ValueType) []WasmValueType {
+	res := make([]WasmValueType, len(types))
+	for i, t := range types {
+		res[i] = WasmValueType(t)
+	}
+
+	return res
+}
+
+func convertFromAPIMemoryDefinition(def api.MemoryDefinition) MemoryDefinition {
+	max, hasMax := def.Max()
+
+	return MemoryDefinition{
+		Min:    def.Min(),
+		Max:    max,
+		HasMax: hasMax,
+	}
+}
@@ -23,8 +23,15 @@ import (
 func (m *wazeroModule) GuestFunction(ctx context.Context, name string) GuestFunction {
 
 	fn := m.mod.ExportedFunction(name)
+
+	// Functions registered with mdk.Export are invoked through the dispatcher.
+	dispatch := false
 	if fn == nil {
-		m.log.Warn("exported function does not exist", "function", name, "namespace", m.Namespace)
+		if fn = m.mod.ExportedFunction(dispatchFunctionName); fn != nil {
+			dispatch = true
+		} else {
+			m.log.Warn("exported function does not exist", "function", name)
+		}
 	}
 
 	return &wazeroGuestFunction{
@@ -33,6 +40,8 @@ func (m *wazeroModule) GuestFunction(ctx context.Context, name string) GuestFunc
 		name,
 		m.Memory(),
 		m.ModuleConfig,
+		m.events,
+		dispatch,
 	}
 }
 
@@ -42,12 +51,23 @@ func (m *wazeroModule) GuestFunction(ctx context.Context, name string) GuestFunc
 // logging. A canceled or otherwise done context will not prevent Close
 // from succeeding.
 func (m *wazeroModule) Close(ctx context.Context) error {
+	m.events.close()
+	m.futures.clear()
+	m.runtime.unregister(m)
+
 	err := m.mod.Close(ctx)
 	if err != nil {
 		err = errors.Join(errors.New("can't close module"), err)
 		m.log.Error(err.Error())
 		return err
 	}
+
+	err = m.wazeroCompiledModule.Close(ctx)
+	if err != nil {
+		m.log.Error(err.Error())
+		return err
+	}
+
 	return nil
 }
 
@@ -61,10 +81,32 @@ type wazeroMemory struct {
 }
 
 // The wazeroModule struct combines an instantiated wazero modul
-// with the generic module configuration.
+// with the compiled module it was instantiated from and the generic module configuration.
 type wazeroModule struct {
 	mod api.Module
+	*wazeroCompiledModule
 	*ModuleConfig
+
+	// runtime dispatches the host function calls of the guest to the module.
+	runtime *wazeroRuntime
+
+	// hostFunctions are the host functions of the module, by namespace and name.
+	hostFunctions map[string]map[string]*HostFunction
+
+	// events is the subscription of the module to ModuleConfig.Events, or nil without an EventBus.
+	events *eventSubscriber
+
+	// typeWarnings are the type mismatches lenient type validation has logged, see TypeValidationLenient.
+	typeWarnings typeWarnings
+
+	// futures are the pending futures of the async host functions the guest called, see HostFunction.AsyncCallback.
+	futures *futures
+
+	// pristine is the state of the module right after instantiation, see Reset.
+	pristine *Snapshot
+
+	// protocolVersion is the version of the wire protocol negotiated with the guest, see ProtocolVersion.
+	protocolVersion uint32
 }
 
 // ReadAnyPack extracts and reads data from a packed memory location.
@@ -111,6 +153,21 @@ func (m *wazeroMemory) ReadAnyPack(pd PackedData) (any, uint32, uint32, error) {
 
 	return data, offset, size, err
 }
+
+// unpack unpacks pd and checks its ValueType against the expected one, see ModuleConfig.TypeValidation.
+func (m *wazeroMemory) unpack(pd PackedData, expected ValueType) (uint32, uint32, error) {
+
+	valueType, offset, size := utils.UnpackUI64(uint64(pd))
+
+	err := m.typeWarnings.check(m.TypeValidation, m.log, "", ValueType(valueType), expected)
+	if err != nil {
+		m.log.Error(err.Error())
+		return 0, 0, err
+	}
+
+	return offset, size, nil
+}
+
 func (m *wazeroMemory) ReadBytes(offset uint32, size uint32) ([]byte, error) {
 	buf, ok := m.mod.Memory().Read(offset, size)
 	if !ok {
@@ -122,7 +179,11 @@ func (m *wazeroMemory) ReadBytes(offset uint32, size uint32) ([]byte, error) {
 	return buf, nil
 }
 func (m *wazeroMemory) ReadBytesPack(pd PackedData) ([]byte, error) {
-	_, offset, size := utils.UnpackUI64(uint64(pd))
+	offset, size, err := m.unpack(pd, ValueTypeBytes)
+	if err != nil {
+		return nil, err
+	}
+
 	return m.ReadBytes(offset, size)
 }
 
@@ -137,7 +198,11 @@ func (m *wazeroMemory) ReadByte(offset uint32) (byte, error) {
 	return buf, nil
 }
 func (m *wazeroMemory) ReadBytePack(pd PackedData) (byte, error) {
-	_, offset, _ := utils.UnpackUI64(uint64(pd))
+	offset, _, err := m.unpack(pd, ValueTypeByte)
+	if err != nil {
+		return 0, err
+	}
+
 	return m.ReadByte(offset)
 }
 
@@ -152,7 +217,11 @@ func (m *wazeroMemory) ReadUint32(offset uint32) (uint32, error) {
 	return data, nil
 }
 func (m *wazeroMemory) ReadUint32Pack(pd PackedData) (uint32, error) {
-	_, offset, _ := utils.UnpackUI64(uint64(pd))
+	offset, _, err := m.unpack(pd, ValueTypeI32)
+	if err != nil {
+		return 0, err
+	}
+
 	return m.ReadUint32(offset)
 }
 
@@ -167,7 +236,11 @@ func (m *wazeroMemory) ReadUint64(offset uint32) (uint64, error) {
 	return data, nil
 }
 func (m *wazeroMemory) ReadUint64Pack(pd PackedData) (uint64, error) {
-	_, offset, _ := utils.UnpackUI64(uint64(pd))
+	offset, _, err := m.unpack(pd, ValueTypeI64)
+	if err != nil {
+		return 0, err
+	}
+
 	return m.ReadUint64(offset)
 }
 
@@ -182,7 +255,11 @@ func (m *wazeroMemory) ReadFloat32(offset uint32) (float32, error) {
 	return data, nil
 }
 func (m *wazeroMemory) ReadFloat32Pack(pd PackedData) (float32, error) {
-	_, offset, _ := utils.UnpackUI64(uint64(pd))
+	offset, _, err := m.unpack(pd, ValueTypeF32)
+	if err != nil {
+		return 0, err
+	}
+
 	return m.ReadFloat32(offset)
 }
 
@@ -197,7 +274,11 @@ func (m *wazeroMemory) ReadFloat64(offset uint32) (float64, error) {
 	return data, nil
 }
 func (m *wazeroMemory) ReadFloat64Pack(pd PackedData) (float64, error) {
-	_, offset, _ := utils.UnpackUI64(uint64(pd))
+	offset, _, err := m.unpack(pd, ValueTypeF64)
+	if err != nil {
+		return 0, err
+	}
+
 	return m.ReadFloat64(offset)
 }
 
@@ -210,7 +291,11 @@ func (m *wazeroMemory) ReadString(offset uint32, size uint32) (string, error) {
 	return string(buf), err
 }
 func (m *wazeroMemory) ReadStringPack(pd PackedData) (string, error) {
-	_, offset, size := utils.UnpackUI64(uint64(pd))
+	offset, size, err := m.unpack(pd, ValueTypeString)
+	if err != nil {
+		return "", err
+	}
+
 	return m.ReadString(offset, size)
 }
 
@@ -370,7 +455,7 @@ func (m *wazeroMemory) WriteUint64Pack(v uint64) PackedData {
 		return 0
 	}
 
-	pd, err := utils.PackUI64(types.ValueTypeI32, offset, 8)
+	pd, err := utils.PackUI64(types.ValueTypeI64, offset, 8)
 	if err != nil {
 		m.log.Error(err.Error())
 		return 0
@@ -493,7 +578,7 @@ func (m *wazeroMemory) WriteMultiPack(pds ...PackedData) MultiPackedData {
 		return 0
 	}
 
-	pdsU64 := make([]uint64, size)
+	pdsU64 := make([]uint64, 0, len(pds))
 	for _, pd := range pds {
 		pdsU64 = append(pdsU64, uint64(pd))
 	}
@@ -503,7 +588,7 @@ func (m *wazeroMemory) WriteMultiPack(pds ...PackedData) MultiPackedData {
 		return 0
 	}
 
-	pd, err := utils.PackUI64(types.ValueTypeString, offset, size)
+	pd, err := utils.PackUI64(types.ValueTypePack, offset, size)
 	if err != nil {
 		return 0
 	}
@@ -517,9 +602,27 @@ func (r *wazeroMemory) Size() uint32 {
 	return r.mod.Memory().Size()
 }
 
+// Names of the functions exported by guests built with the mdk.
+// Guests built without it are expected to export the usual malloc and free.
+const (
+	mallocFunctionName   = "wasify_malloc"
+	freeFunctionName     = "wasify_free"
+	dispatchFunctionName = "wasify_dispatch"
+)
+
+// allocatorFunction returns the guest function name if the module exports it, otherwise the fallback name.
+func (m *wazeroModule) allocatorFunction(name string, fallback string) string {
+	if m.mod.ExportedFunction(name) != nil {
+		return name
+	}
+
+	return fallback
+}
+
 // Malloc allocates memory in wasm linear memory with the specified size.
 //
-// It invokes the "malloc" GuestFunction of the associated wazeroModule using the provided size parameter.
+// It invokes the "wasify_malloc" GuestFunction of the associated wazeroModule using the provided size parameter,
+// or "malloc" if the module doesn't export it.
 // Returns the allocated memory offset and any encountered error.
 //
 // Malloc allows memory allocation from within a host function or externally,
@@ -529,7 +632,9 @@ func (r *wazeroMemory) Size() uint32 {
 // NOTE: Always make sure to free memory after allocation.
 func (m *wazeroMemory) Malloc(size uint32) (uint32, error) {
 
-	r, err := m.wazeroModule.GuestFunction(m.wazeroModule.ctx, "malloc").call(uint64(size))
+	name := m.wazeroModule.allocatorFunction(mallocFunctionName, "malloc")
+
+	r, err := m.wazeroModule.GuestFunction(m.wazeroModule.ctx, name).call(uint64(size))
 	if err != nil {
 		err = errors.Join(fmt.Errorf("can't invoke malloc function "), err)
 		return 0, err
@@ -537,20 +642,28 @@ func (m *wazeroMemory) Malloc(size uint32) (uint32, error) {
 
 	offset := uint32(r)
 
+	m.metrics.ObserveMalloc(m.Namespace, size)
+	m.metrics.ObserveMemorySize(m.Namespace, m.Size())
+
 	return offset, nil
 }
 
 // Free releases the memory block at the specified offset in wazeroMemory.
-// It invokes the "free" GuestFunction of the associated wazeroModule using the provided offset parameter.
+// It invokes the "wasify_free" GuestFunction of the associated wazeroModule using the provided offset parameter,
+// or "free" if the module doesn't export it.
 // Returns any encountered error during the memory deallocation.
 func (m *wazeroMemory) Free(offsets ...uint32) error {
 
+	name := m.wazeroModule.allocatorFunction(freeFunctionName, "free")
+
 	for _, offset := range offsets {
-		_, err := m.wazeroModule.GuestFunction(m.ModuleConfig.ctx, "free").call(uint64(offset))
+		_, err := m.wazeroModule.GuestFunction(m.ModuleConfig.ctx, name).call(uint64(offset))
 		if err != nil {
 			err = errors.Join(fmt.Errorf("can't invoke free function"), err)
 			return err
 		}
+
+		m.metrics.ObserveFree(m.Namespace)
 	}
 
 	return nil
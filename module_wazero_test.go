@@ -0,0 +1,106 @@
+package wasify
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go/internal/types"
+	"github.com/wasify-io/wasify-go/internal/utils"
+)
+
+// TestWriteMultiPack checks WriteMultiPack writes exactly the packs it's given, as a ValueTypePack array,
+// the way guests and GuestFunctionResult.ReadPacks read a MultiPackedData.
+func TestWriteMultiPack(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := NewRuntime(ctx, &RuntimeConfig{
+		Runtime:     RuntimeWazero,
+		LogSeverity: LogError,
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	binary, err := os.ReadFile("testdata/wasm/guest_all_available_types/main.wasm")
+	assert.NoError(t, err)
+
+	module, err := runtime.NewModule(ctx, &ModuleConfig{
+		Namespace: "guest_all_available_types",
+		Wasm:      Wasm{Binary: binary},
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = module.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	memory := module.Memory()
+
+	greeting, year := memory.WriteStringPack("Wasify"), memory.WriteUint64Pack(2023)
+
+	mpd := memory.WriteMultiPack(greeting, year)
+
+	valueType, offset, size := utils.UnpackUI64(uint64(mpd))
+	assert.Equal(t, types.ValueTypePack, valueType)
+	assert.Equal(t, uint32(16), size)
+
+	data, err := memory.ReadBytes(offset, size)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{uint64(greeting), uint64(year)}, utils.BytesToUint64Array(data))
+
+	assert.Equal(t, MultiPackedData(0), memory.WriteMultiPack())
+	assert.NoError(t, memory.FreePack(greeting, year, PackedData(mpd)))
+}
+
+// TestWriteUint64Pack checks WriteUint64Pack tags its pack as ValueTypeI64, so it can be read back with strict type validation.
+func TestWriteUint64Pack(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := NewRuntime(ctx, &RuntimeConfig{
+		Runtime:     RuntimeWazero,
+		LogSeverity: LogError,
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	binary, err := os.ReadFile("testdata/wasm/guest_all_available_types/main.wasm")
+	assert.NoError(t, err)
+
+	module, err := runtime.NewModule(ctx, &ModuleConfig{
+		Namespace:      "guest_all_available_types",
+		Wasm:           Wasm{Binary: binary},
+		TypeValidation: TypeValidationStrict,
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = module.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	memory := module.Memory()
+
+	pd := memory.WriteUint64Pack(1 << 40)
+
+	valueType, _, size := utils.UnpackUI64(uint64(pd))
+	assert.Equal(t, types.ValueTypeI64, valueType)
+	assert.Equal(t, uint32(8), size)
+
+	v, err := memory.ReadUint64Pack(pd)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1<<40), v)
+
+	assert.NoError(t, memory.FreePack(pd))
+}
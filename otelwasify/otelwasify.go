@@ -0,0 +1,95 @@
+// Package otelwasify implements wasify.Tracer with OpenTelemetry.
+// It's a module of its own, so only the users of the adapter depend on OpenTelemetry:
+//
+//	go get github.com/wasify-io/wasify-go/otelwasify
+//
+// Example:
+//
+//	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+//		Runtime: wasify.RuntimeWazero,
+//		Tracer:  otelwasify.NewTracer(otel.GetTracerProvider()),
+//	})
+package otelwasify
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/wasify-io/wasify-go"
+)
+
+// TracerName is the instrumentation name of the spans.
+const TracerName = "github.com/wasify-io/wasify-go"
+
+// Span attributes set on every span.
+const (
+	AttrNamespace = attribute.Key("wasify.namespace")
+	AttrFunction  = attribute.Key("wasify.function")
+	AttrCallKind  = attribute.Key("wasify.call.kind")
+	AttrArgsSize  = attribute.Key("wasify.args.size")
+)
+
+// Tracer creates OpenTelemetry spans for guest and host function calls.
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TraceContext
+}
+
+var _ wasify.Tracer = (*Tracer)(nil)
+
+// NewTracer returns a Tracer which creates spans with a tracer from tp.
+func NewTracer(tp trace.TracerProvider) *Tracer {
+	return &Tracer{
+		tracer: tp.Tracer(TracerName),
+	}
+}
+
+// Start starts a span named "<namespace>.<function>".
+// Guest function spans are server spans, host function spans are client spans of the guest.
+func (t *Tracer) Start(ctx context.Context, call wasify.CallInfo) (context.Context, wasify.Span) {
+
+	kind := trace.SpanKindServer
+	if call.Kind == wasify.CallHostFunction {
+		kind = trace.SpanKindClient
+	}
+
+	ctx, span := t.tracer.Start(ctx, call.Namespace+"."+call.Function,
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(
+			AttrNamespace.String(call.Namespace),
+			AttrFunction.String(call.Function),
+			AttrCallKind.String(call.Kind.String()),
+			AttrArgsSize.Int64(int64(call.ArgsSize)),
+		),
+	)
+
+	return ctx, &otelSpan{span}
+}
+
+// TraceParent returns the W3C traceparent header value of the span carried by ctx.
+func (t *Tracer) TraceParent(ctx context.Context) string {
+
+	carrier := propagation.MapCarrier{}
+	t.propagator.Inject(ctx, carrier)
+
+	return carrier.Get("traceparent")
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+// End records err on the span, if any, and ends it.
+func (s *otelSpan) End(err error) {
+
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+
+	s.span.End()
+}
@@ -0,0 +1,61 @@
+package otelwasify_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/wasify-io/wasify-go"
+	"github.com/wasify-io/wasify-go/otelwasify"
+)
+
+func TestTracer(t *testing.T) {
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := otelwasify.NewTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx, guestSpan := tracer.Start(context.Background(), wasify.CallInfo{
+		Kind:      wasify.CallGuestFunction,
+		Namespace: "plugin",
+		Function:  "handle",
+		ArgsSize:  42,
+	})
+
+	traceParent := tracer.TraceParent(ctx)
+	sc := trace.SpanContextFromContext(ctx)
+	assert.Equal(t, "00-"+sc.TraceID().String()+"-"+sc.SpanID().String()+"-01", traceParent)
+
+	_, hostSpan := tracer.Start(ctx, wasify.CallInfo{
+		Kind:      wasify.CallHostFunction,
+		Namespace: "plugin",
+		Function:  "fetch",
+	})
+	hostSpan.End(errors.New("connection refused"))
+	guestSpan.End(nil)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+
+	host, guest := spans[0], spans[1]
+
+	assert.Equal(t, "plugin.handle", guest.Name())
+	assert.Equal(t, trace.SpanKindServer, guest.SpanKind())
+	assert.Contains(t, guest.Attributes(), otelwasify.AttrArgsSize.Int64(42))
+	assert.Contains(t, guest.Attributes(), otelwasify.AttrCallKind.String("guest"))
+	assert.Equal(t, codes.Unset, guest.Status().Code)
+
+	assert.Equal(t, "plugin.fetch", host.Name())
+	assert.Equal(t, trace.SpanKindClient, host.SpanKind())
+	assert.Equal(t, guest.SpanContext().SpanID(), host.Parent().SpanID())
+	assert.Equal(t, codes.Error, host.Status().Code)
+	assert.Equal(t, "connection refused", host.Status().Description)
+	assert.Len(t, host.Events(), 1)
+
+	assert.Empty(t, tracer.TraceParent(context.Background()))
+}
@@ -0,0 +1,300 @@
+// Package promwasify implements wasify.Metrics and exposes the metrics in the Prometheus text format,
+// without depending on the Prometheus client library.
+//
+// Example:
+//
+//	metrics := promwasify.NewCollector(nil)
+//
+//	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+//		Runtime: wasify.RuntimeWazero,
+//		Metrics: metrics,
+//	})
+//
+//	// Expose the metrics on an existing server.
+//	mux.Handle("/metrics", metrics)
+package promwasify
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/wasify-io/wasify-go"
+)
+
+// ContentType is the content type of the Prometheus text format written by Collector.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the upper bounds in seconds of the call duration histogram buckets.
+var DefaultBuckets = []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
+// Collector collects wasify metrics in memory and writes them in the Prometheus text format.
+//
+// Exported metrics:
+//   - wasify_calls_total{kind, namespace, function}
+//   - wasify_call_errors_total{kind, namespace, function}
+//   - wasify_call_duration_seconds{kind, namespace, function}, a histogram
+//   - wasify_call_args_bytes_total{kind, namespace, function}
+//   - wasify_call_results_bytes_total{kind, namespace, function}
+//   - wasify_malloc_calls_total{namespace}
+//   - wasify_malloc_bytes_total{namespace}
+//   - wasify_free_calls_total{namespace}
+//   - wasify_memory_size_bytes{namespace}
+type Collector struct {
+	buckets []float64
+
+	mu      sync.Mutex
+	calls   map[callKey]*callStats
+	modules map[string]*moduleStats
+}
+
+var _ wasify.Metrics = (*Collector)(nil)
+
+type callKey struct {
+	kind      string
+	namespace string
+	function  string
+}
+
+type callStats struct {
+	count       uint64
+	errors      uint64
+	argsBytes   uint64
+	resultBytes uint64
+
+	// buckets holds the number of observations per bucket, the last one is +Inf.
+	buckets []uint64
+	sum     float64
+}
+
+type moduleStats struct {
+	mallocs     uint64
+	mallocBytes uint64
+	frees       uint64
+	memorySize  uint32
+}
+
+// NewCollector returns an empty Collector.
+// buckets are the upper bounds in seconds of the call duration histogram, DefaultBuckets are used if nil.
+func NewCollector(buckets []float64) *Collector {
+
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &Collector{
+		buckets: buckets,
+		calls:   make(map[callKey]*callStats),
+		modules: make(map[string]*moduleStats),
+	}
+}
+
+func (c *Collector) ObserveCall(call wasify.CallInfo, result wasify.CallResult) {
+
+	key := callKey{call.Kind.String(), call.Namespace, call.Function}
+	seconds := result.Duration.Seconds()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats, ok := c.calls[key]
+	if !ok {
+		stats = &callStats{buckets: make([]uint64, len(c.buckets)+1)}
+		c.calls[key] = stats
+	}
+
+	stats.count++
+	if result.Err != nil {
+		stats.errors++
+	}
+	stats.argsBytes += call.ArgsSize
+	stats.resultBytes += result.ResultsSize
+
+	stats.buckets[sort.SearchFloat64s(c.buckets, seconds)]++
+	stats.sum += seconds
+}
+
+func (c *Collector) ObserveMalloc(namespace string, size uint32) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.module(namespace)
+	stats.mallocs++
+	stats.mallocBytes += uint64(size)
+}
+
+func (c *Collector) ObserveFree(namespace string) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.module(namespace).frees++
+}
+
+func (c *Collector) ObserveMemorySize(namespace string, size uint32) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.module(namespace).memorySize = size
+}
+
+// module returns the stats of the module, c.mu must be held.
+func (c *Collector) module(namespace string) *moduleStats {
+
+	stats, ok := c.modules[namespace]
+	if !ok {
+		stats = &moduleStats{}
+		c.modules[namespace] = stats
+	}
+
+	return stats
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format, so the Collector can be mounted on an existing server.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_, _ = c.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format to w.
+// Series are sorted by labels, so the output is stable.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+
+	c.mu.Lock()
+	calls, modules := c.sortedCalls(), c.sortedModules()
+
+	bw := &countingWriter{w: bufio.NewWriter(w)}
+
+	writeCallCounter(bw, "wasify_calls_total", "Number of guest and host function calls.", calls, c.calls, func(s *callStats) uint64 { return s.count })
+	writeCallCounter(bw, "wasify_call_errors_total", "Number of guest and host function calls which failed.", calls, c.calls, func(s *callStats) uint64 { return s.errors })
+	writeCallCounter(bw, "wasify_call_args_bytes_total", "Size of the data passed as arguments to guest and host functions.", calls, c.calls, func(s *callStats) uint64 { return s.argsBytes })
+	writeCallCounter(bw, "wasify_call_results_bytes_total", "Size of the data returned as results by guest and host functions.", calls, c.calls, func(s *callStats) uint64 { return s.resultBytes })
+
+	bw.printf("# HELP wasify_call_duration_seconds Duration of guest and host function calls.\n")
+	bw.printf("# TYPE wasify_call_duration_seconds histogram\n")
+	for _, key := range calls {
+		stats := c.calls[key]
+		labels := key.labels()
+
+		var cumulative uint64
+		for i, le := range c.buckets {
+			cumulative += stats.buckets[i]
+			bw.printf("wasify_call_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, formatFloat(le), cumulative)
+		}
+		cumulative += stats.buckets[len(c.buckets)]
+		bw.printf("wasify_call_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, cumulative)
+		bw.printf("wasify_call_duration_seconds_sum{%s} %s\n", labels, formatFloat(stats.sum))
+		bw.printf("wasify_call_duration_seconds_count{%s} %d\n", labels, stats.count)
+	}
+
+	writeModuleMetric(bw, "wasify_malloc_calls_total", "counter", "Number of allocations in the linear memory of modules.", modules, c.modules, func(s *moduleStats) uint64 { return s.mallocs })
+	writeModuleMetric(bw, "wasify_malloc_bytes_total", "counter", "Size of the allocations in the linear memory of modules.", modules, c.modules, func(s *moduleStats) uint64 { return s.mallocBytes })
+	writeModuleMetric(bw, "wasify_free_calls_total", "counter", "Number of releases of memory in the linear memory of modules.", modules, c.modules, func(s *moduleStats) uint64 { return s.frees })
+	writeModuleMetric(bw, "wasify_memory_size_bytes", "gauge", "Current size of the linear memory of modules.", modules, c.modules, func(s *moduleStats) uint64 { return uint64(s.memorySize) })
+	c.mu.Unlock()
+
+	if bw.err != nil {
+		return bw.n, bw.err
+	}
+
+	return bw.n, bw.w.Flush()
+}
+
+func (c *Collector) sortedCalls() []callKey {
+
+	keys := make([]callKey, 0, len(c.calls))
+	for key := range c.calls {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind < keys[j].kind
+		}
+		if keys[i].namespace != keys[j].namespace {
+			return keys[i].namespace < keys[j].namespace
+		}
+		return keys[i].function < keys[j].function
+	})
+
+	return keys
+}
+
+func (c *Collector) sortedModules() []string {
+
+	namespaces := make([]string, 0, len(c.modules))
+	for namespace := range c.modules {
+		namespaces = append(namespaces, namespace)
+	}
+
+	sort.Strings(namespaces)
+
+	return namespaces
+}
+
+func writeCallCounter(w *countingWriter, name, help string, keys []callKey, calls map[callKey]*callStats, value func(*callStats) uint64) {
+
+	w.printf("# HELP %s %s\n", name, help)
+	w.printf("# TYPE %s counter\n", name)
+
+	for _, key := range keys {
+		w.printf("%s{%s} %d\n", name, key.labels(), value(calls[key]))
+	}
+}
+
+func writeModuleMetric(w *countingWriter, name, typ, help string, namespaces []string, modules map[string]*moduleStats, value func(*moduleStats) uint64) {
+
+	w.printf("# HELP %s %s\n", name, help)
+	w.printf("# TYPE %s %s\n", name, typ)
+
+	for _, namespace := range namespaces {
+		w.printf("%s{namespace=\"%s\"} %d\n", name, escapeLabelValue(namespace), value(modules[namespace]))
+	}
+}
+
+func (k callKey) labels() string {
+	return fmt.Sprintf("kind=\"%s\",namespace=\"%s\",function=\"%s\"", escapeLabelValue(k.kind), escapeLabelValue(k.namespace), escapeLabelValue(k.function))
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueReplacer.Replace(v)
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// countingWriter counts the written bytes and keeps the first error, so the writes don't need to be checked one by one.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countingWriter) printf(format string, a ...any) {
+
+	if w.err != nil {
+		return
+	}
+
+	n, err := fmt.Fprintf(w.w, format, a...)
+	w.n += int64(n)
+	w.err = err
+}
@@ -0,0 +1,139 @@
+package promwasify_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wasify-io/wasify-go"
+	"github.com/wasify-io/wasify-go/promwasify"
+)
+
+func TestCollector(t *testing.T) {
+
+	c := promwasify.NewCollector([]float64{0.1, 1})
+
+	call := wasify.CallInfo{Kind: wasify.CallGuestFunction, Namespace: "plugin", Function: "handle", ArgsSize: 10}
+
+	c.ObserveCall(call, wasify.CallResult{Duration: 50 * time.Millisecond, ResultsSize: 4})
+	c.ObserveCall(call, wasify.CallResult{Duration: 2 * time.Second, Err: errors.New("failed")})
+	c.ObserveMalloc("plugin", 16)
+	c.ObserveMalloc("plugin", 8)
+	c.ObserveFree("plugin")
+	c.ObserveMemorySize("plugin", 65536)
+
+	var out strings.Builder
+	n, err := c.WriteTo(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(out.Len()), n)
+
+	for _, line := range []string{
+		`# TYPE wasify_calls_total counter`,
+		`wasify_calls_total{kind="guest",namespace="plugin",function="handle"} 2`,
+		`wasify_call_errors_total{kind="guest",namespace="plugin",function="handle"} 1`,
+		`wasify_call_args_bytes_total{kind="guest",namespace="plugin",function="handle"} 20`,
+		`wasify_call_results_bytes_total{kind="guest",namespace="plugin",function="handle"} 4`,
+		`# TYPE wasify_call_duration_seconds histogram`,
+		`wasify_call_duration_seconds_bucket{kind="guest",namespace="plugin",function="handle",le="0.1"} 1`,
+		`wasify_call_duration_seconds_bucket{kind="guest",namespace="plugin",function="handle",le="1"} 1`,
+		`wasify_call_duration_seconds_bucket{kind="guest",namespace="plugin",function="handle",le="+Inf"} 2`,
+		`wasify_call_duration_seconds_sum{kind="guest",namespace="plugin",function="handle"} 2.05`,
+		`wasify_call_duration_seconds_count{kind="guest",namespace="plugin",function="handle"} 2`,
+		`wasify_malloc_calls_total{namespace="plugin"} 2`,
+		`wasify_malloc_bytes_total{namespace="plugin"} 24`,
+		`wasify_free_calls_total{namespace="plugin"} 1`,
+		`# TYPE wasify_memory_size_bytes gauge`,
+		`wasify_memory_size_bytes{namespace="plugin"} 65536`,
+	} {
+		assert.Contains(t, out.String(), line+"\n")
+	}
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, promwasify.ContentType, rec.Header().Get("Content-Type"))
+	assert.Equal(t, out.String(), rec.Body.String())
+}
+
+func TestCollectorEscapesLabels(t *testing.T) {
+
+	c := promwasify.NewCollector(nil)
+	c.ObserveFree("a\"b\\c\nd")
+
+	var out strings.Builder
+	_, err := c.WriteTo(&out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), `wasify_free_calls_total{namespace="a\"b\\c\nd"} 1`)
+}
+
+func TestCollectorRuntime(t *testing.T) {
+
+	ctx := context.Background()
+
+	binary, err := os.ReadFile("../testdata/wasm/host_all_available_types/main.wasm")
+	assert.NoError(t, err)
+
+	c := promwasify.NewCollector(nil)
+
+	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+		Runtime: wasify.RuntimeWazero,
+		Metrics: c,
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	module, err := runtime.NewModule(ctx, &wasify.ModuleConfig{
+		Namespace: "host_all_available_types",
+		Wasm: wasify.Wasm{
+			Binary: binary,
+		},
+		HostFunctions: []wasify.HostFunction{
+			{
+				Name: "hostTest",
+				Callback: func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {
+					return m.Memory.WriteMultiPack(m.Memory.WriteStringPack("Host: Wasify."))
+				},
+				Params: []wasify.ValueType{
+					wasify.ValueTypeBytes,
+					wasify.ValueTypeByte,
+					wasify.ValueTypeI32,
+					wasify.ValueTypeI64,
+					wasify.ValueTypeF32,
+					wasify.ValueTypeF64,
+					wasify.ValueTypeString,
+				},
+				Results: []wasify.ValueType{wasify.ValueTypeString},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = module.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	_, err = module.GuestFunction(ctx, "guestTest").Invoke()
+	assert.NoError(t, err)
+
+	var out strings.Builder
+	_, err = c.WriteTo(&out)
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), `wasify_calls_total{kind="guest",namespace="host_all_available_types",function="guestTest"} 1`)
+	assert.Contains(t, out.String(), `wasify_calls_total{kind="host",namespace="host_all_available_types",function="hostTest"} 1`)
+	assert.Contains(t, out.String(), `wasify_call_args_bytes_total{kind="host",namespace="host_all_available_types",function="hostTest"} 59`)
+	assert.Contains(t, out.String(), `wasify_call_results_bytes_total{kind="host",namespace="host_all_available_types",function="hostTest"} 13`)
+	// The host wrote the result string and the pack array.
+	assert.Contains(t, out.String(), `wasify_malloc_calls_total{namespace="host_all_available_types"} 2`)
+	assert.Contains(t, out.String(), `wasify_memory_size_bytes{namespace="host_all_available_types"} `)
+}
@@ -0,0 +1,82 @@
+package wasify
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ProtocolVersion is the version of the wire protocol implemented by the host, see PROTOCOL.md.
+//
+// Guests declare the version they implement with the wasify_protocol_version custom section,
+// which the host checks before instantiating the module, or with the wasify_protocol_version export,
+// which the host calls once the module is instantiated. NewModule returns a *ProtocolVersionError
+// if the host doesn't support it.
+//
+// Guests which declare neither, e.g. guests built before versioning, are assumed to implement version 1,
+// unless ModuleConfig.RequireProtocolVersion is set.
+const ProtocolVersion uint32 = 1
+
+// MinProtocolVersion is the oldest protocol version the host supports.
+const MinProtocolVersion uint32 = 1
+
+// legacyProtocolVersion is the version of guests which don't declare one.
+const legacyProtocolVersion uint32 = 1
+
+// protocolVersionName is the name of both the export and the custom section declaring the protocol version.
+const protocolVersionName = "wasify_protocol_version"
+
+// ProtocolVersionError is returned by NewModule when the guest implements a version of the wire protocol
+// the host doesn't support, so values would be decoded wrong.
+type ProtocolVersionError struct {
+	Namespace string
+
+	// Version is the protocol version of the guest, 0 if the guest doesn't declare it
+	// and ModuleConfig.RequireProtocolVersion is set.
+	Version uint32
+}
+
+func (e *ProtocolVersionError) Error() string {
+
+	switch {
+	case e.Version == 0:
+		return fmt.Sprintf("module %s doesn't declare its wire protocol version, rebuild it with an SDK implementing versions %d to %d", e.Namespace, MinProtocolVersion, ProtocolVersion)
+	case e.Version > ProtocolVersion:
+		return fmt.Sprintf("module %s implements wire protocol version %d, newer than the host supports (%d to %d), upgrade wasify", e.Namespace, e.Version, MinProtocolVersion, ProtocolVersion)
+	default:
+		return fmt.Sprintf("module %s implements wire protocol version %d, older than the host supports (%d to %d), rebuild it with a newer SDK", e.Namespace, e.Version, MinProtocolVersion, ProtocolVersion)
+	}
+}
+
+// checkProtocolVersion returns a *ProtocolVersionError if the host doesn't support the version.
+func checkProtocolVersion(namespace string, version uint32) error {
+
+	if version < MinProtocolVersion || version > ProtocolVersion {
+		return &ProtocolVersionError{namespace, version}
+	}
+
+	return nil
+}
+
+// sectionProtocolVersion returns the version declared by the wasify_protocol_version custom section,
+// a little-endian uint32, or 0 if the module doesn't have the section.
+func sectionProtocolVersion(info ModuleInfo) (uint32, error) {
+
+	for _, section := range info.CustomSections() {
+		if section.Name != protocolVersionName {
+			continue
+		}
+
+		if len(section.Data) != 4 {
+			return 0, fmt.Errorf("the %s custom section must hold a little-endian uint32, got %d bytes", protocolVersionName, len(section.Data))
+		}
+
+		version := binary.LittleEndian.Uint32(section.Data)
+		if version == 0 {
+			return 0, fmt.Errorf("the %s custom section declares the invalid version 0", protocolVersionName)
+		}
+
+		return version, nil
+	}
+
+	return 0, nil
+}
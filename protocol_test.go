@@ -0,0 +1,170 @@
+package wasify
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go/internal/wasmbin"
+)
+
+// memoryModule is a module with a memory and nothing else, which doesn't declare a protocol version.
+var memoryModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	// memory section: one memory of 1 page
+	0x05, 0x03, 0x01, 0x00, 0x01,
+}
+
+// protocolVersionModule returns a module exporting wasify_protocol_version, which returns version as a value of valueType.
+func protocolVersionModule(valueType WasmValueType, version byte) []byte {
+
+	constOp := byte(0x41) // i32.const
+	if valueType == WasmValueTypeI64 {
+		constOp = 0x42 // i64.const
+	}
+
+	bin := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	bin = append(bin,
+		// type section: one func type () -> (valueType)
+		0x01, 0x05, 0x01, 0x60, 0x00, 0x01, byte(valueType),
+		// function section: one func of type 0
+		0x03, 0x02, 0x01, 0x00,
+		// memory section: one memory of 1 page
+		0x05, 0x03, 0x01, 0x00, 0x01,
+		// export section: func 0 as "wasify_protocol_version"
+		0x07, 0x1b, 0x01, 0x17,
+	)
+	bin = append(bin, protocolVersionName...)
+	bin = append(bin,
+		0x00, 0x00,
+		// code section: one body returning the constant version
+		0x0a, 0x06, 0x01, 0x04, 0x00, constOp, version, 0x0b,
+	)
+
+	return bin
+}
+
+// trappingModule is a command module whose _start traps, so it fails if it's instantiated.
+var trappingModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	// type section: one func type () -> ()
+	0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+	// function section: one func of type 0
+	0x03, 0x02, 0x01, 0x00,
+	// memory section: one memory of 1 page
+	0x05, 0x03, 0x01, 0x00, 0x01,
+	// export section: func 0 as "_start"
+	0x07, 0x0a, 0x01, 0x06, '_', 's', 't', 'a', 'r', 't', 0x00, 0x00,
+	// code section: one body executing unreachable
+	0x0a, 0x05, 0x01, 0x03, 0x00, 0x00, 0x0b,
+}
+
+// withProtocolSection appends a wasify_protocol_version custom section declaring version to a copy of bin.
+func withProtocolSection(bin []byte, version uint32) []byte {
+	return wasmbin.AppendCustomSection(append([]byte{}, bin...), protocolVersionName, binary.LittleEndian.AppendUint32(nil, version))
+}
+
+func TestProtocolVersion(t *testing.T) {
+
+	ctx := context.Background()
+
+	// Every module needs its own runtime, the wasify host functions are instantiated once per runtime.
+	newModule := func(t *testing.T, bin []byte, require bool) (Module, error) {
+
+		runtime, err := NewRuntime(ctx, &RuntimeConfig{
+			Runtime:     RuntimeWazero,
+			LogSeverity: LogError,
+		})
+		assert.NoError(t, err)
+		t.Cleanup(func() { runtime.Close(ctx) })
+
+		return runtime.NewModule(ctx, &ModuleConfig{
+			Namespace:              "protocol_version",
+			Wasm:                   Wasm{Binary: bin},
+			RequireProtocolVersion: require,
+		})
+	}
+
+	t.Run("export", func(t *testing.T) {
+		module, err := newModule(t, protocolVersionModule(WasmValueTypeI32, byte(ProtocolVersion)), true)
+		assert.NoError(t, err)
+		defer module.Close(ctx)
+
+		assert.Equal(t, ProtocolVersion, module.ProtocolVersion())
+	})
+
+	t.Run("custom section", func(t *testing.T) {
+		module, err := newModule(t, withProtocolSection(memoryModule, ProtocolVersion), true)
+		assert.NoError(t, err)
+		defer module.Close(ctx)
+
+		assert.Equal(t, ProtocolVersion, module.ProtocolVersion())
+	})
+
+	t.Run("custom section and export", func(t *testing.T) {
+		module, err := newModule(t, withProtocolSection(protocolVersionModule(WasmValueTypeI32, byte(ProtocolVersion)), ProtocolVersion), true)
+		assert.NoError(t, err)
+		defer module.Close(ctx)
+
+		assert.Equal(t, ProtocolVersion, module.ProtocolVersion())
+	})
+
+	t.Run("legacy guest", func(t *testing.T) {
+		module, err := newModule(t, memoryModule, false)
+		assert.NoError(t, err)
+		defer module.Close(ctx)
+
+		assert.Equal(t, uint32(1), module.ProtocolVersion())
+	})
+
+	t.Run("legacy guest with required version", func(t *testing.T) {
+		_, err := newModule(t, memoryModule, true)
+
+		var versionErr *ProtocolVersionError
+		assert.ErrorAs(t, err, &versionErr)
+		assert.Equal(t, &ProtocolVersionError{Namespace: "protocol_version"}, versionErr)
+		assert.EqualError(t, err, "module protocol_version doesn't declare its wire protocol version, rebuild it with an SDK implementing versions 1 to 1")
+	})
+
+	t.Run("unsupported export", func(t *testing.T) {
+		_, err := newModule(t, protocolVersionModule(WasmValueTypeI32, byte(ProtocolVersion+1)), false)
+
+		var versionErr *ProtocolVersionError
+		assert.ErrorAs(t, err, &versionErr)
+		assert.Equal(t, ProtocolVersion+1, versionErr.Version)
+		assert.EqualError(t, err, "module protocol_version implements wire protocol version 2, newer than the host supports (1 to 1), upgrade wasify")
+	})
+
+	t.Run("unsupported custom section", func(t *testing.T) {
+		// The guest is refused before its _start runs, it would trap otherwise.
+		_, err := newModule(t, withProtocolSection(trappingModule, ProtocolVersion+1), false)
+
+		var versionErr *ProtocolVersionError
+		assert.ErrorAs(t, err, &versionErr)
+		assert.Equal(t, ProtocolVersion+1, versionErr.Version)
+
+		_, err = newModule(t, trappingModule, false)
+		assert.Error(t, err)
+		assert.False(t, errors.As(err, &versionErr))
+	})
+
+	t.Run("invalid custom section", func(t *testing.T) {
+		bin := wasmbin.AppendCustomSection(append([]byte{}, memoryModule...), protocolVersionName, []byte{1, 0})
+
+		_, err := newModule(t, bin, false)
+		assert.EqualError(t, err, "the wasify_protocol_version custom section must hold a little-endian uint32, got 2 bytes")
+
+		_, err = newModule(t, withProtocolSection(memoryModule, 0), false)
+		assert.EqualError(t, err, "the wasify_protocol_version custom section declares the invalid version 0")
+	})
+
+	t.Run("invalid export", func(t *testing.T) {
+		_, err := newModule(t, protocolVersionModule(WasmValueTypeI64, byte(ProtocolVersion)), false)
+		assert.EqualError(t, err, "wasify_protocol_version must have the signature () -> (i32)")
+
+		_, err = newModule(t, protocolVersionModule(WasmValueTypeI32, 0), false)
+		assert.EqualError(t, err, "wasify_protocol_version returned the invalid version 0")
+	})
+}
@@ -0,0 +1,68 @@
+package wasify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// checkProtocolSection checks the version declared by the custom section of the compiled module,
+// so a guest the host doesn't support is refused before any of its code runs.
+// It returns the declared version, 0 if the module doesn't have the section.
+func checkProtocolSection(compiled *wazeroCompiledModule, namespace string) (uint32, error) {
+
+	version, err := sectionProtocolVersion(compiled)
+	if err != nil || version == 0 {
+		return 0, err
+	}
+
+	return version, checkProtocolVersion(namespace, version)
+}
+
+// negotiateProtocol returns the protocol version of the instantiated guest.
+//
+// It calls the wasify_protocol_version export, which must agree with the version declared
+// by the custom section, if any. A guest declaring neither implements the legacy version,
+// or is refused if moduleConfig.RequireProtocolVersion is set.
+func negotiateProtocol(ctx context.Context, mod api.Module, moduleConfig *ModuleConfig, declared uint32) (uint32, error) {
+
+	fn := mod.ExportedFunction(protocolVersionName)
+	if fn == nil {
+		switch {
+		case declared != 0:
+			return declared, nil
+		case moduleConfig.RequireProtocolVersion:
+			return 0, &ProtocolVersionError{Namespace: moduleConfig.Namespace}
+		}
+
+		return legacyProtocolVersion, checkProtocolVersion(moduleConfig.Namespace, legacyProtocolVersion)
+	}
+
+	def := fn.Definition()
+	if len(def.ParamTypes()) != 0 || len(def.ResultTypes()) != 1 || def.ResultTypes()[0] != api.ValueTypeI32 {
+		return 0, fmt.Errorf("%s must have the signature () -> (i32)", protocolVersionName)
+	}
+
+	res, err := fn.Call(ctx)
+	if err != nil {
+		return 0, errors.Join(fmt.Errorf("can't call %s", protocolVersionName), err)
+	}
+
+	version := api.DecodeU32(res[0])
+
+	switch {
+	case version == 0:
+		return 0, fmt.Errorf("%s returned the invalid version 0", protocolVersionName)
+	case declared != 0 && version != declared:
+		return 0, fmt.Errorf("the %s custom section declares version %d, but the export returns %d", protocolVersionName, declared, version)
+	}
+
+	return version, checkProtocolVersion(moduleConfig.Namespace, version)
+}
+
+// ProtocolVersion returns the version of the wire protocol negotiated with the guest at instantiation.
+func (m *wazeroModule) ProtocolVersion() uint32 {
+	return m.protocolVersion
+}
@@ -0,0 +1,331 @@
+package wasify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wasify-io/wasify-go/internal/utils"
+)
+
+// WasmSource loads the current version of a wasm binary, e.g. from a file or a plugin registry.
+type WasmSource func(ctx context.Context) (Wasm, error)
+
+// FileWasmSource returns a WasmSource which reads the wasm binary from the file at path.
+// hash is optional, see LoadWasmFile.
+func FileWasmSource(path string, hash string) WasmSource {
+	return func(ctx context.Context) (Wasm, error) {
+		return LoadWasmFile(path, hash)
+	}
+}
+
+// ReloadableModule is a module handle which can swap the underlying wasm binary
+// without dropping in-flight calls.
+//
+// Each version of the binary is instantiated in the same runtime, built from the RuntimeConfig,
+// with the same ModuleConfig, so it goes through the same hash, signature and import checks.
+// New calls are routed to the latest version, while the previous version is closed
+// once all calls which started on it have finished.
+type ReloadableModule struct {
+	runtime      Runtime
+	moduleConfig ModuleConfig
+	source       WasmSource
+	log          *slog.Logger
+
+	// reload serializes reloads, so two versions are never built concurrently.
+	reload sync.Mutex
+
+	// retiring counts the previous versions waiting for their in-flight calls, the runtime is closed after them.
+	retiring sync.WaitGroup
+
+	current atomic.Pointer[moduleGeneration]
+}
+
+// NewReloadableModule loads the wasm binary from source and instantiates the first version of the module.
+//
+// moduleConfig.Wasm is ignored, the binary always comes from source.
+func NewReloadableModule(ctx context.Context, runtimeConfig *RuntimeConfig, moduleConfig *ModuleConfig, source WasmSource) (*ReloadableModule, error) {
+
+	rm := &ReloadableModule{
+		moduleConfig: *moduleConfig,
+		source:       source,
+		log:          utils.NewLoggerWithHandler(runtimeConfig.logHandler(), utils.LogSeverity(runtimeConfig.LogSeverity)).With("namespace", moduleConfig.Namespace),
+	}
+
+	// Versions are swapped while the previous one still serves calls, their guests can't share a module name.
+	rm.moduleConfig.anonymous = true
+
+	wasm, err := source(ctx)
+	if err != nil {
+		err = errors.Join(errors.New("can't load wasm"), err)
+		rm.log.Error(err.Error())
+		return nil, err
+	}
+
+	// The runtime is configured on a copy, like the module of each version.
+	runtimeConfigCopy := *runtimeConfig
+	rm.runtime, err = NewRuntime(ctx, &runtimeConfigCopy)
+	if err != nil {
+		return nil, err
+	}
+
+	gen, err := rm.newGeneration(ctx, wasm)
+	if err != nil {
+		rm.runtime.Close(ctx)
+		return nil, err
+	}
+
+	rm.current.Store(gen)
+
+	return rm, nil
+}
+
+// Reload loads the wasm binary from the source and, if it has changed, swaps it in.
+// It reports whether a new version has been swapped in.
+//
+// If the new version can't be instantiated, the current version keeps serving calls and an error is returned.
+func (rm *ReloadableModule) Reload(ctx context.Context) (bool, error) {
+
+	rm.reload.Lock()
+	defer rm.reload.Unlock()
+
+	old := rm.current.Load()
+	if old == nil {
+		return false, errors.New("reloadable module is closed")
+	}
+
+	wasm, err := rm.source(ctx)
+	if err != nil {
+		err = errors.Join(errors.New("can't load wasm"), err)
+		rm.log.Error(err.Error())
+		return false, err
+	}
+
+	hash, err := utils.CalculateHash(wasm.Binary)
+	if err != nil {
+		return false, errors.Join(errors.New("can't calculate the hash"), err)
+	}
+
+	if hash == old.hash {
+		return false, nil
+	}
+
+	gen, err := rm.newGeneration(ctx, wasm)
+	if err != nil {
+		return false, err
+	}
+
+	rm.current.Store(gen)
+
+	rm.log.Info("module has been reloaded", "previous hash", old.hash, "hash", gen.hash)
+
+	// Close the previous version in the background, once its in-flight calls have finished.
+	rm.retiring.Add(1)
+	go func() {
+		defer rm.retiring.Done()
+		old.retire(context.WithoutCancel(ctx), rm.log)
+	}()
+
+	return true, nil
+}
+
+// Watch polls the source every interval and reloads the module when the binary changes.
+// It blocks until ctx is done. Reload errors are logged and the current version keeps serving calls.
+func (rm *ReloadableModule) Watch(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := rm.Reload(ctx); err != nil {
+				rm.log.Warn("can't reload module, keeping the current version", "err", err)
+			}
+		}
+	}
+}
+
+// Use calls fn with the current version of the module and keeps that version open until fn returns,
+// even if a new version is swapped in meanwhile.
+//
+// Use it to make several calls on the same version, e.g. to pass the memory written by one call to the next,
+// because the memory of a retired version is released as soon as it has no in-flight calls.
+// The results of the GuestFunction of the ReloadableModule are copied out of the module, so they can be read anytime.
+func (rm *ReloadableModule) Use(fn func(Module) error) error {
+
+	gen, err := rm.acquire()
+	if err != nil {
+		return err
+	}
+	defer gen.release()
+
+	return fn(gen.module)
+}
+
+// GuestFunction returns a GuestFunction which is resolved against the current version
+// of the module on every Invoke.
+func (rm *ReloadableModule) GuestFunction(ctx context.Context, name string) GuestFunction {
+	return &reloadableGuestFunction{ctx, name, rm}
+}
+
+// Close closes the current version of the module after its in-flight calls have finished, and the runtime.
+// Calls started after Close return an error.
+func (rm *ReloadableModule) Close(ctx context.Context) error {
+
+	rm.reload.Lock()
+	defer rm.reload.Unlock()
+
+	gen := rm.current.Swap(nil)
+	if gen == nil {
+		return nil
+	}
+
+	err := gen.retire(ctx, rm.log)
+
+	// The previous versions may still have in-flight calls, the runtime is closed once they are closed.
+	rm.retiring.Wait()
+
+	return errors.Join(err, rm.runtime.Close(ctx))
+}
+
+// newGeneration instantiates a version of the module in the runtime.
+func (rm *ReloadableModule) newGeneration(ctx context.Context, wasm Wasm) (*moduleGeneration, error) {
+
+	hash, err := utils.CalculateHash(wasm.Binary)
+	if err != nil {
+		return nil, errors.Join(errors.New("can't calculate the hash"), err)
+	}
+
+	moduleConfig := rm.moduleConfig
+	moduleConfig.Wasm = wasm
+
+	module, err := rm.runtime.NewModule(ctx, &moduleConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &moduleGeneration{
+		module: module,
+		hash:   hash,
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// acquire returns the current version of the module and marks a call as in-flight on it.
+func (rm *ReloadableModule) acquire() (*moduleGeneration, error) {
+	for {
+		gen := rm.current.Load()
+		if gen == nil {
+			return nil, errors.New("reloadable module is closed")
+		}
+
+		// The version may have been retired between Load and acquire, retry with the new one.
+		if gen.acquire() {
+			return gen, nil
+		}
+	}
+}
+
+// moduleGeneration is one version of a reloadable module with its in-flight call counter.
+type moduleGeneration struct {
+	module Module
+	hash   string
+
+	mu       sync.Mutex
+	inflight int
+	retired  bool
+
+	// done is closed when the generation is retired and has no in-flight calls.
+	done chan struct{}
+}
+
+func (g *moduleGeneration) acquire() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.retired {
+		return false
+	}
+
+	g.inflight++
+
+	return true
+}
+
+func (g *moduleGeneration) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.inflight--
+	if g.retired && g.inflight == 0 {
+		close(g.done)
+	}
+}
+
+// retire stops new calls on the generation, waits for in-flight calls to finish and closes it.
+func (g *moduleGeneration) retire(ctx context.Context, log *slog.Logger) error {
+
+	g.mu.Lock()
+	g.retired = true
+	if g.inflight == 0 {
+		close(g.done)
+	}
+	g.mu.Unlock()
+
+	<-g.done
+
+	err := g.module.Close(ctx)
+	if err != nil {
+		err = errors.Join(fmt.Errorf("can't close module version %s", g.hash), err)
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// reloadableGuestFunction resolves the guest function against the current version of the module on each call.
+type reloadableGuestFunction struct {
+	ctx    context.Context
+	name   string
+	module *ReloadableModule
+}
+
+// Invoke invokes the guest function on the current version of the module.
+// The results are copied out of the module before the version is released,
+// so they can still be read once a new version is swapped in and the previous one is closed.
+func (gf *reloadableGuestFunction) Invoke(args ...any) (*GuestFunctionResult, error) {
+
+	var res *GuestFunctionResult
+
+	err := gf.module.Use(func(m Module) error {
+		inModule, err := m.GuestFunction(gf.ctx, gf.name).Invoke(args...)
+		if err != nil {
+			return err
+		}
+
+		res, err = copyResult(inModule, gf.module.moduleConfig.TypeValidation, gf.module.log)
+		return err
+	})
+
+	return res, err
+}
+
+func (gf *reloadableGuestFunction) call(args ...uint64) (uint64, error) {
+
+	var res uint64
+
+	err := gf.module.Use(func(m Module) (err error) {
+		res, err = m.GuestFunction(gf.ctx, gf.name).call(args...)
+		return err
+	})
+
+	return res, err
+}
@@ -0,0 +1,151 @@
+package wasify_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go"
+)
+
+func TestReloadableModule(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "plugin.wasm")
+	assert.NoError(t, os.WriteFile(path, wasm_guestAllAvailableTypes, 0o644))
+
+	// A second version of the binary which behaves the same, but has a different hash.
+	_, key, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	v2, err := wasify.EmbedSignature(wasm_guestAllAvailableTypes, key)
+	assert.NoError(t, err)
+
+	module, err := wasify.NewReloadableModule(ctx,
+		&wasify.RuntimeConfig{
+			Runtime:     wasify.RuntimeWazero,
+			LogSeverity: wasify.LogError,
+		},
+		&wasify.ModuleConfig{
+			Namespace: "guest_all_available_types",
+		},
+		wasify.FileWasmSource(path, ""),
+	)
+	assert.NoError(t, err)
+
+	invoke := func(gf wasify.GuestFunction) error {
+		_, err := gf.Invoke([]byte("bytes!"), byte(1), uint32(32), uint64(64), float32(32.0), float64(64.01), "Wasify", "any type")
+		return err
+	}
+
+	guestTest := module.GuestFunction(ctx, "guestTest")
+	assert.NoError(t, invoke(guestTest))
+
+	reloaded, err := module.Reload(ctx)
+	assert.NoError(t, err)
+	assert.False(t, reloaded, "binary has not changed")
+
+	// Pin the current version, swap in a new one and check the pinned version keeps working
+	// until the in-flight call finishes.
+	var previous wasify.Module
+	err = module.Use(func(m wasify.Module) error {
+		previous = m
+
+		assert.NoError(t, os.WriteFile(path, v2, 0o644))
+
+		reloaded, err := module.Reload(ctx)
+		assert.NoError(t, err)
+		assert.True(t, reloaded)
+
+		return invoke(m.GuestFunction(ctx, "guestTest"))
+	})
+	assert.NoError(t, err)
+
+	// New calls go to the new version.
+	assert.NoError(t, invoke(guestTest))
+
+	// The previous version is closed once it has no in-flight calls.
+	assert.Eventually(t, func() bool {
+		return invoke(previous.GuestFunction(ctx, "guestTest")) != nil
+	}, time.Second, 10*time.Millisecond)
+
+	// A broken binary is rejected and the current version keeps serving calls.
+	assert.NoError(t, os.WriteFile(path, []byte("invalid wasm data"), 0o644))
+	reloaded, err = module.Reload(ctx)
+	assert.Error(t, err)
+	assert.False(t, reloaded)
+	assert.NoError(t, invoke(guestTest))
+
+	assert.NoError(t, module.Close(ctx))
+	assert.Error(t, invoke(guestTest))
+}
+
+// TestReloadableModuleResults checks the results of a call can be read after the version which returned them is closed.
+func TestReloadableModuleResults(t *testing.T) {
+
+	ctx := context.Background()
+
+	_, key, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	v2, err := wasify.EmbedSignature(wasm_mdkExport, key)
+	assert.NoError(t, err)
+
+	versions := [][]byte{wasm_mdkExport, v2}
+	source := func(ctx context.Context) (wasify.Wasm, error) {
+		return wasify.Wasm{Binary: versions[0]}, nil
+	}
+
+	module, err := wasify.NewReloadableModule(ctx,
+		&wasify.RuntimeConfig{
+			Runtime:     wasify.RuntimeWazero,
+			LogSeverity: wasify.LogError,
+		},
+		&wasify.ModuleConfig{
+			Namespace: "mdk_export",
+		},
+		source,
+	)
+	assert.NoError(t, err)
+	defer module.Close(ctx)
+
+	var previous wasify.Module
+	assert.NoError(t, module.Use(func(m wasify.Module) error {
+		previous = m
+		return nil
+	}))
+
+	res, err := module.GuestFunction(ctx, "greet").Invoke("Wasify", uint32(2))
+	assert.NoError(t, err)
+
+	versions = versions[1:]
+	reloaded, err := module.Reload(ctx)
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+
+	assert.Eventually(t, func() bool {
+		_, err := previous.GuestFunction(ctx, "greet").Invoke("Wasify", uint32(1))
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+
+	pds, err := res.ReadPacks()
+	assert.NoError(t, err)
+	assert.Len(t, pds, 1)
+
+	greeting, err := res.Memory().ReadStringPack(pds[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello, Wasify! Hello, Wasify! ", greeting)
+	assert.NoError(t, res.Memory().FreePack(pds...))
+
+	// The new version runs in the same runtime, next to the previous one until it's closed.
+	res, err = module.GuestFunction(ctx, "greet").Invoke("v2", uint32(1))
+	assert.NoError(t, err)
+	pds, err = res.ReadPacks()
+	assert.NoError(t, err)
+	greeting, err = res.Memory().ReadStringPack(pds[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello, v2! ", greeting)
+}
@@ -24,6 +24,7 @@ const (
 
 type Runtime interface {
 	NewModule(context.Context, *ModuleConfig) (Module, error)
+	CompileModule(context.Context, Wasm) (CompiledModule, error)
 	Close(ctx context.Context) error
 }
 
@@ -54,7 +55,26 @@ type RuntimeConfig struct {
 	// Specifies the type of runtime being used.
 	Runtime RuntimeType
 	// Determines the severity level of logging.
+	// If Logger or LogHandler is set, LogSeverity is applied on top of the handler's own level only when it's set.
 	LogSeverity LogSeverity
+	// Logger routes wasify logs into a user-supplied logger. Takes precedence over LogHandler.
+	Logger *slog.Logger
+	// LogHandler routes wasify logs into a user-supplied handler, e.g. slog.NewJSONHandler.
+	// If neither Logger nor LogHandler is set, logs are written as text to stderr.
+	LogHandler slog.Handler
+	// Tracer creates spans for guest function invocations and host function callbacks.
+	// Tracing is disabled if Tracer is not set.
+	Tracer Tracer
+	// Metrics records call counts, latencies, errors, marshalled bytes, allocations and memory size of modules.
+	// Metrics are disabled if Metrics is not set.
+	Metrics Metrics
+	// Interceptors wrap every guest function invocation and host function callback of the runtime's modules.
+	// See Interceptor for more details.
+	Interceptors []Interceptor
+	// TrustStore holds the keys of trusted module vendors.
+	// If set, every module must carry a valid signature from one of these keys.
+	// See TrustStore for more details.
+	TrustStore *TrustStore
 	// Pointer to a logger for recording runtime information.
 	log *slog.Logger
 }
@@ -63,7 +83,7 @@ type RuntimeConfig struct {
 // It returns the initialized runtime and any error that might occur during the process.
 func NewRuntime(ctx context.Context, c *RuntimeConfig) (runtime Runtime, err error) {
 
-	c.log = utils.NewLogger(utils.LogSeverity(c.LogSeverity))
+	c.log = utils.NewLoggerWithHandler(c.logHandler(), utils.LogSeverity(c.LogSeverity))
 
 	c.log.Info("runtime has been initialized successfully", "runtime", c.Runtime)
 
@@ -78,6 +98,15 @@ func NewRuntime(ctx context.Context, c *RuntimeConfig) (runtime Runtime, err err
 	return
 }
 
+// logHandler returns the user-supplied log handler, or nil if the default one should be used.
+func (c *RuntimeConfig) logHandler() slog.Handler {
+	if c.Logger != nil {
+		return c.Logger.Handler()
+	}
+
+	return c.LogHandler
+}
+
 // getRuntime returns an instance of the appropriate runtime implementation
 // based on the configured runtime type in the RuntimeConfig.
 func (c *RuntimeConfig) getRuntime(ctx context.Context) Runtime {
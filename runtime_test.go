@@ -1,7 +1,10 @@
 package wasify
 
 import (
+	"bytes"
 	"context"
+	"log/slog"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -34,3 +37,43 @@ func TestNewRuntimeUnsupported(t *testing.T) {
 func TestRuntimeTypeString(t *testing.T) {
 	assert.Equal(t, "Wazero", RuntimeWazero.String(), "Expected Wazero string representation")
 }
+
+func TestNewRuntimeWithLogHandler(t *testing.T) {
+	ctx := context.Background()
+
+	var runtimeLogs, moduleLogs bytes.Buffer
+
+	runtime, err := NewRuntime(ctx, &RuntimeConfig{
+		Runtime:    RuntimeWazero,
+		LogHandler: slog.NewJSONHandler(&runtimeLogs, nil),
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, runtimeLogs.String(), `"msg":"runtime has been initialized successfully"`)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	binary, err := os.ReadFile("testdata/wasm/guest_all_available_types/main.wasm")
+	assert.NoError(t, err)
+
+	module, err := runtime.NewModule(ctx, &ModuleConfig{
+		Namespace:   "guest_all_available_types",
+		Wasm:        Wasm{Binary: binary},
+		LogSeverity: LogDebug,
+		HostFunctions: []HostFunction{
+			{Name: "unused", Callback: func(ctx context.Context, m *ModuleProxy, params []PackedData) MultiPackedData { return 0 }},
+		},
+		Logger: slog.New(slog.NewJSONHandler(&moduleLogs, &slog.HandlerOptions{Level: slog.LevelDebug})).With("service", "plugins"),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, module.Close(ctx))
+
+	// Module logs go to the module logger, enriched with the namespace and the logger's own attributes.
+	assert.Contains(t, moduleLogs.String(), `"msg":"module has been instantiated successfully"`)
+	assert.Contains(t, moduleLogs.String(), `"service":"plugins","namespace":"guest_all_available_types"`)
+	// Module LogSeverity replaces the severity inherited from the runtime.
+	assert.Contains(t, moduleLogs.String(), `"level":"DEBUG","msg":"build host function"`)
+	assert.NotContains(t, runtimeLogs.String(), "module has been instantiated successfully")
+}
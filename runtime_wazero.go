@@ -3,14 +3,20 @@
 package wasify
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"os"
+	"slices"
+	"sync"
 
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 	"github.com/wasify-io/wasify-go/internal/utils"
+	"github.com/wasify-io/wasify-go/internal/wasmbin"
 )
 
 // getWazeroRuntime creates and returns a wazero runtime instance using the provided context and
@@ -22,88 +28,272 @@ func getWazeroRuntime(ctx context.Context, c *RuntimeConfig) *wazeroRuntime {
 	// Create a new wazero runtime instance with specified configuration options.
 	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
 		WithCoreFeatures(api.CoreFeaturesV2).
-		WithCustomSections(false).
+		// Keep custom sections, so they can be listed through ModuleInfo.
+		WithCustomSections(true).
 		WithCloseOnContextDone(false).
 		// Enable runtime debug if user sets LogSeverity to debug level in runtime configuration
 		WithDebugInfoEnabled(c.LogSeverity == LogDebug),
 	)
 	// Instantiate the runtime with the WASI snapshot preview1.
 	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
-	return &wazeroRuntime{runtime, c}
+	return &wazeroRuntime{
+		runtime:       runtime,
+		RuntimeConfig: c,
+		hostModules:   make(map[string][]FunctionDefinition),
+		modules:       make(map[api.Module]*wazeroModule),
+	}
 }
 
 // The wazeroRuntime struct combines a wazero runtime instance with runtime configuration.
 type wazeroRuntime struct {
 	runtime wazero.Runtime
 	*RuntimeConfig
+
+	// mu guards hostModules and modules.
+	mu sync.Mutex
+
+	// hostModules are the functions of the host modules instantiated in the runtime, by namespace.
+	// A host module is shared by the modules of its namespace, see instantiateHostModule.
+	hostModules map[string][]FunctionDefinition
+
+	// modules are the modules instantiated in the runtime, by their guest.
+	// Host functions are dispatched to the module of the guest calling them.
+	modules map[api.Module]*wazeroModule
 }
 
+// instantiatingKey is the context key of the module being instantiated,
+// its start functions may call host functions before it's registered in the runtime.
+type instantiatingKey struct{}
+
 // NewModule creates a new module instance based on the provided ModuleConfig within
 //
 // the wazero runtime context. It returns the created module and any potential error.
-func (r *wazeroRuntime) NewModule(ctx context.Context, moduleConfig *ModuleConfig) (Module, error) {
+func (r *wazeroRuntime) NewModule(ctx context.Context, moduleConfig *ModuleConfig) (_ Module, err error) {
 
 	// Set the context, logger and any missing data for the moduleConfig.
 	moduleConfig.ctx = ctx
-	moduleConfig.log = r.log
+	moduleConfig.tracer = r.Tracer
+	if moduleConfig.tracer == nil {
+		moduleConfig.tracer = noopTracer{}
+	}
+	moduleConfig.metrics = r.Metrics
+	if moduleConfig.metrics == nil {
+		moduleConfig.metrics = noopMetrics{}
+	}
+	moduleConfig.interceptors = append(append([]Interceptor(nil), r.Interceptors...), moduleConfig.Interceptors...)
 
 	// Create a new wazeroModule instance and set its ModuleConfig.
 	// Read more about wazeroModule in module_wazero.go
 	wazeroModule := new(wazeroModule)
 	wazeroModule.ModuleConfig = moduleConfig
+	wazeroModule.runtime = r
+	wazeroModule.futures = newFutures()
 
-	// If LogSeverity is set, create a new logger instance for the module.
+	// Create a logger for the module, enriched with the module namespace.
 	//
-	// Module will adopt the log level from their parent runtime.
+	// Module will adopt the log handler and the log level from their parent runtime.
 	// If you want only "Error" level for a runtime but need to debug specific module(s),
 	// you can set those modules to "Debug". This will replace the inherited log level,
 	// allowing the module to display debug information.
-	if moduleConfig.LogSeverity != 0 {
-		moduleConfig.log = utils.NewLogger(utils.LogSeverity(moduleConfig.LogSeverity))
+	handler := moduleConfig.logHandler()
+	if handler == nil {
+		handler = r.logHandler()
 	}
 
-	// Check and compare hashes if provided in the moduleConfig.
-	if moduleConfig.Wasm.Hash != "" {
-		actualHash, err := utils.CalculateHash(moduleConfig.Wasm.Binary)
-		if err != nil {
-			err = errors.Join(errors.New("can't calculate the hash"), err)
-			moduleConfig.log.Warn(err.Error(), "namespace", moduleConfig.Namespace, "needed hash", moduleConfig.Wasm.Hash, "actual wasm hash", actualHash)
-			return nil, err
+	severity := moduleConfig.LogSeverity
+	if severity == 0 {
+		severity = r.LogSeverity
+	}
+
+	moduleConfig.log = utils.NewLoggerWithHandler(handler, utils.LogSeverity(severity)).With("namespace", moduleConfig.Namespace)
+
+	// Check and compare hashes, then compile the binary.
+	compiled, err := r.compileModule(ctx, moduleConfig.Wasm, moduleConfig.exportGlobals(), moduleConfig.log)
+	if err != nil {
+		moduleConfig.log.Error(err.Error())
+		r.log.Error(err.Error(), "runtime", r.Runtime, "namespace", moduleConfig.Namespace)
+		return nil, err
+	}
+
+	wazeroModule.wazeroCompiledModule = compiled
+
+	// Release the module instance, if any, and the compiled module when the module can't be created.
+	defer func() {
+		if err == nil {
+			return
 		}
-		moduleConfig.log.Info("hash calculation", "namespace", moduleConfig.Namespace, "needed hash", moduleConfig.Wasm.Hash, "actual wasm hash", actualHash)
 
-		err = utils.CompareHashes(actualHash, moduleConfig.Wasm.Hash)
+		if wazeroModule.mod != nil {
+			r.unregister(wazeroModule)
+			wazeroModule.mod.Close(ctx)
+		}
+		compiled.Close(ctx)
+	}()
+
+	// A snapshot can only be restored into the binary it was taken from.
+	if moduleConfig.Snapshot != nil {
+		err = checkSnapshotHash(moduleConfig.Snapshot, moduleConfig.Wasm.Binary)
 		if err != nil {
-			moduleConfig.log.Warn(err.Error(), "namespace", moduleConfig.Namespace, "needed hash", moduleConfig.Wasm.Hash, "actual wasm hash", actualHash)
+			moduleConfig.log.Error(err.Error())
+			r.log.Error(err.Error(), "runtime", r.Runtime, "namespace", moduleConfig.Namespace)
 			return nil, err
 		}
 	}
 
+	if name := compiled.Name(); name != "" {
+		moduleConfig.log = moduleConfig.log.With("module", name)
+	}
+
+	// The guest may subscribe to events as soon as it's initialized.
+	if moduleConfig.Events != nil {
+		wazeroModule.events = newEventSubscriber(moduleConfig)
+	}
+
+	// Compare guest imports with the declared host functions before instantiation,
+	// so a mismatch is reported in a readable form instead of a raw instantiation error.
+	report := ValidateImports(compiled, moduleConfig)
+	if len(report.Extra) > 0 || len(report.Unknown) > 0 {
+		moduleConfig.log.Debug("guest imports can't all be matched with host functions", "report", report.String())
+	}
+	if report.HasErrors() {
+		err = &ImportValidationError{moduleConfig.Namespace, report}
+		moduleConfig.log.Error(err.Error())
+		r.log.Error(err.Error(), "runtime", r.Runtime, "namespace", moduleConfig.Namespace)
+		return nil, err
+	}
+
+	// The protocol version declared by a custom section is checked before the guest runs,
+	// the one returned by the wasify_protocol_version export once it's instantiated.
+	declaredVersion, err := checkProtocolSection(compiled, moduleConfig.Namespace)
+	if err != nil {
+		moduleConfig.log.Error(err.Error())
+		r.log.Error(err.Error(), "runtime", r.Runtime, "namespace", moduleConfig.Namespace)
+		return nil, err
+	}
+
 	// Instantiate host functions and configure wazeroModule accordingly.
-	err := r.instantiateHostFunctions(ctx, wazeroModule, moduleConfig)
+	err = r.instantiateHostFunctions(ctx, wazeroModule, moduleConfig)
 	if err != nil {
-		moduleConfig.log.Error(err.Error(), "namespace", moduleConfig.Namespace)
+		moduleConfig.log.Error(err.Error())
 		r.log.Error(err.Error(), "runtime", r.Runtime, "namespace", moduleConfig.Namespace)
 		return nil, err
 	}
 
-	moduleConfig.log.Info("host functions has been instantiated successfully", "namespace", moduleConfig.Namespace)
+	moduleConfig.log.Info("host functions has been instantiated successfully")
 
 	// Instantiate the module and set it in wazeroModule.
-	mod, err := r.instantiateModule(ctx, moduleConfig)
+	mod, err := r.instantiateModule(context.WithValue(ctx, instantiatingKey{}, wazeroModule), compiled, moduleConfig)
 	if err != nil {
-		moduleConfig.log.Error(err.Error(), "namespace", moduleConfig.Namespace)
+		moduleConfig.log.Error(err.Error())
 		r.log.Error(err.Error(), "runtime", r.Runtime, "namespace", moduleConfig.Namespace)
 		return nil, err
 	}
 
-	moduleConfig.log.Info("module has been instantiated successfully", "namespace", moduleConfig.Namespace)
+	moduleConfig.log.Info("module has been instantiated successfully")
 
 	wazeroModule.mod = mod
+	r.register(wazeroModule)
+
+	// The start functions of the guest don't run again, its subscriptions are the ones of the snapshot.
+	if moduleConfig.Snapshot != nil {
+		wazeroModule.events.setSubscriptions(moduleConfig.Snapshot.Topics)
+	}
+
+	// Refuse guests which implement a version of the wire protocol the host can't decode.
+	wazeroModule.protocolVersion, err = negotiateProtocol(ctx, mod, moduleConfig, declaredVersion)
+	if err != nil {
+		moduleConfig.log.Error(err.Error())
+		r.log.Error(err.Error(), "runtime", r.Runtime, "namespace", moduleConfig.Namespace)
+		return nil, err
+	}
+
+	moduleConfig.log.Debug("protocol version has been negotiated", "version", wazeroModule.protocolVersion)
+
+	// Keep the state after instantiation for Reset.
+	// Trailing zeros are dropped, restoring a snapshot zeroes the memory after its end anyway.
+	if moduleConfig.EnableReset {
+		wazeroModule.pristine, err = wazeroModule.snapshot()
+		if err != nil {
+			moduleConfig.log.Error(err.Error())
+			r.log.Error(err.Error(), "runtime", r.Runtime, "namespace", moduleConfig.Namespace)
+			return nil, err
+		}
+		wazeroModule.pristine.Memory = bytes.Clone(bytes.TrimRight(wazeroModule.pristine.Memory, "\x00"))
+	}
+
+	// Deliver the events the guest subscribed to, from now on.
+	wazeroModule.events.start(context.WithoutCancel(ctx), wazeroModule)
 
 	return wazeroModule, nil
 }
 
+// CompileModule verifies and compiles the wasm binary without instantiating it.
+// The returned CompiledModule can be used to inspect the module's imports and exports
+// before any host functions are wired to it.
+func (r *wazeroRuntime) CompileModule(ctx context.Context, wasm Wasm) (CompiledModule, error) {
+
+	compiled, err := r.compileModule(ctx, wasm, false, r.log)
+	if err != nil {
+		r.log.Error(err.Error(), "runtime", r.Runtime)
+		return nil, err
+	}
+
+	return compiled, nil
+}
+
+// compileModule checks the hash and the signature of the wasm binary, if required, and compiles it.
+// If exportGlobals is set, the binary is rewritten to export all its globals first, see ModuleConfig.EnableSnapshots.
+func (r *wazeroRuntime) compileModule(ctx context.Context, wasm Wasm, exportGlobals bool, log *slog.Logger) (*wazeroCompiledModule, error) {
+
+	// Check and compare hashes if provided.
+	if wasm.Hash != "" {
+		actualHash, err := utils.CalculateHash(wasm.Binary)
+		if err != nil {
+			err = errors.Join(errors.New("can't calculate the hash"), err)
+			log.Warn(err.Error(), "needed hash", wasm.Hash, "actual wasm hash", actualHash)
+			return nil, err
+		}
+		log.Info("hash calculation", "needed hash", wasm.Hash, "actual wasm hash", actualHash)
+
+		err = utils.CompareHashes(actualHash, wasm.Hash)
+		if err != nil {
+			log.Warn(err.Error(), "needed hash", wasm.Hash, "actual wasm hash", actualHash)
+			return nil, err
+		}
+	}
+
+	// Verify the signature if the runtime only accepts modules from trusted vendors.
+	if r.TrustStore != nil {
+		key, err := r.TrustStore.Verify(wasm)
+		if err != nil {
+			err = errors.Join(errors.New("can't verify module signature"), err)
+			log.Warn(err.Error())
+			return nil, err
+		}
+		log.Info("module signature has been verified", "key", key)
+	}
+
+	binary := wasm.Binary
+	var globals []string
+
+	// Export all globals, so Module.Snapshot can capture them.
+	if exportGlobals {
+		var err error
+		binary, globals, err = wasmbin.ExportGlobals(wasm.Binary, snapshotGlobalPrefix)
+		if err != nil {
+			return nil, errors.Join(errors.New("can't export globals for snapshots"), err)
+		}
+	}
+
+	// Compile the provided WebAssembly binary.
+	compiled, err := r.runtime.CompileModule(ctx, binary)
+	if err != nil {
+		return nil, errors.Join(errors.New("can't compile module"), err)
+	}
+
+	return &wazeroCompiledModule{compiled, globals}, nil
+}
+
 // convertToAPIValueTypes converts an array of ValueType values to their corresponding
 // api.ValueType representations used by the Wazero runtime.
 //
@@ -128,92 +318,158 @@ func (r *wazeroRuntime) convertToAPIValueTypes(types []ValueType) []api.ValueTyp
 	return valueTypes
 }
 
-// instantiateHostFunctions sets up and exports host functions for the module using the wazero runtime.
+// instantiateHostFunctions sets up the host functions of the module, the user-defined ones
+// and the pre-defined ones, and instantiates their host modules if the runtime doesn't have them yet.
 //
-// It configures host function callbacks, data types, and exports.
+// Host modules are shared by the modules of a runtime with the same namespace,
+// their functions call the host functions of the module of the calling guest.
 func (r *wazeroRuntime) instantiateHostFunctions(ctx context.Context, wazeroModule *wazeroModule, moduleConfig *ModuleConfig) error {
 
-	modBuilder := r.runtime.NewHostModuleBuilder(moduleConfig.Namespace)
+	wazeroModule.hostFunctions = map[string]map[string]*HostFunction{
+		moduleConfig.Namespace: {},
+		WASIFY_NAMESPACE:       {},
+	}
 
 	// Iterate over the module's host functions and set up exports.
 	for _, hostFunc := range moduleConfig.HostFunctions {
 
 		// Create a new local variable inside the loop to ensure that
-		// each closure captures its own unique variable. This prevents
-		// the inadvertent capturing of the loop iterator variable, which
-		// would result in all closures referencing the last element
-		// in the moduleConfig.HostFunctions slice.
+		// each module holds its own copy of the host function.
 		hf := hostFunc
 
-		moduleConfig.log.Debug("build host function", "namespace", moduleConfig.Namespace, "function", hf.Name)
+		moduleConfig.log.Debug("build host function", "function", hf.Name)
+
+		if hf.Callback != nil && hf.AsyncCallback != nil {
+			return fmt.Errorf("host function %s has both a Callback and an AsyncCallback", hf.Name)
+		}
 
 		// Associate the host function with module-related information.
 		// This configuration ensures that the host function can access ModuleConfig data from various contexts.
 		// See host_function.go for more details.
 		hf.moduleConfig = moduleConfig
 
-		// If hsot function has any return values, we pack it as a single uint64
-		var resultValuesPackedData = []ValueType{}
-		if len(hf.Results) > 0 {
-			resultValuesPackedData = []ValueType{ValueTypeI64}
+		wazeroModule.hostFunctions[moduleConfig.Namespace][hf.Name] = &hf
+	}
+
+	// initialize pre-defined host functions and pass any necessary configurations
+	for _, hf := range newHostFunctions(moduleConfig).all() {
+		wazeroModule.hostFunctions[WASIFY_NAMESPACE][hf.Name] = hf
+	}
+
+	// Instantiate user defined host functions
+	err := r.instantiateHostModule(ctx, moduleConfig.Namespace, wazeroModule.hostFunctions[moduleConfig.Namespace])
+	if err != nil {
+		err = errors.Join(errors.New("can't instantiate NewHostModuleBuilder [user-defined host funcs]"), err)
+		return err
+	}
+
+	err = r.instantiateHostModule(ctx, WASIFY_NAMESPACE, wazeroModule.hostFunctions[WASIFY_NAMESPACE])
+	if err != nil {
+		err = errors.Join(errors.New("can't instantiate wasify NewHostModuleBuilder [pre-defined host funcs]"), err)
+		return err
+	}
+
+	return nil
+
+}
+
+// instantiateHostModule instantiates the host module of namespace with the host functions,
+// unless the runtime has it already. In that case, its functions must have the same signatures.
+func (r *wazeroRuntime) instantiateHostModule(ctx context.Context, namespace string, hostFunctions map[string]*HostFunction) error {
+
+	definitions := make([]FunctionDefinition, 0, len(hostFunctions))
+	for _, hf := range hostFunctions {
+		definitions = append(definitions, hf.definition(namespace))
+	}
+	sortDefinitions(definitions)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if instantiated, ok := r.hostModules[namespace]; ok {
+		if !slices.EqualFunc(instantiated, definitions, func(a, b FunctionDefinition) bool { return a.String() == b.String() }) {
+			return fmt.Errorf("host module %s has already been instantiated in the runtime with other host functions", namespace)
 		}
+		return nil
+	}
+
+	modBuilder := r.runtime.NewHostModuleBuilder(namespace)
+
+	for _, def := range definitions {
+		hf := hostFunctions[def.Name]
 
 		modBuilder = modBuilder.
 			NewFunctionBuilder().
-			WithGoModuleFunction(api.GoModuleFunc(wazeroHostFunctionCallback(wazeroModule, moduleConfig, &hf)),
+			WithGoModuleFunction(api.GoModuleFunc(r.hostFunctionCallback(namespace, hf.Name)),
 				r.convertToAPIValueTypes(hf.Params),
-				r.convertToAPIValueTypes(resultValuesPackedData),
+				r.convertToAPIValueTypes(hf.packedResults()),
 			).
 			Export(hf.Name)
-
 	}
 
-	// Instantiate user defined host functions
 	_, err := modBuilder.Instantiate(ctx)
 	if err != nil {
-		err = errors.Join(errors.New("can't instantiate NewHostModuleBuilder [user-defined host funcs]"), err)
 		return err
 	}
 
-	// NewHostModuleBuilder for wasify pre-defined host functions
-	modBuilder = r.runtime.NewHostModuleBuilder(WASIFY_NAMESPACE)
+	r.hostModules[namespace] = definitions
 
-	// initialize pre-defined host functions and pass any necessary configurations
-	hf := newHostFunctions(moduleConfig)
+	return nil
+}
 
-	// register pre-defined host functions
-	log := hf.newLog()
+// hostFunctionCallback returns the callback of a host function of a shared host module,
+// which calls the host function of the module of the calling guest.
+func (r *wazeroRuntime) hostFunctionCallback(namespace string, name string) func(context.Context, api.Module, []uint64) {
 
-	// host logger
-	modBuilder.
-		NewFunctionBuilder().
-		WithGoModuleFunction(api.GoModuleFunc(wazeroHostFunctionCallback(wazeroModule, moduleConfig, log)),
-			r.convertToAPIValueTypes(log.Params),
-			r.convertToAPIValueTypes(log.Results),
-		).
-		Export(log.Name)
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
 
-	_, err = modBuilder.Instantiate(ctx)
-	if err != nil {
-		err = errors.Join(errors.New("can't instantiate wasify NewHostModuleBuilder [pre-defined host funcs]"), err)
-		return err
+		wazeroModule := r.module(ctx, mod)
+		if wazeroModule == nil {
+			panic(fmt.Errorf("host function %s.%s has been called by %s, which isn't a module of the runtime", namespace, name, mod.Name()))
+		}
+
+		hf := wazeroModule.hostFunctions[namespace][name]
+
+		wazeroHostFunctionCallback(wazeroModule, wazeroModule.ModuleConfig, namespace, hf)(ctx, mod, stack)
 	}
+}
 
-	return nil
+// module returns the module of the guest mod, or the module being instantiated by ctx if mod isn't registered yet.
+func (r *wazeroRuntime) module(ctx context.Context, mod api.Module) *wazeroModule {
+
+	r.mu.Lock()
+	m, ok := r.modules[mod]
+	r.mu.Unlock()
+
+	if ok {
+		return m
+	}
+
+	m, _ = ctx.Value(instantiatingKey{}).(*wazeroModule)
+
+	return m
+}
+
+// register routes the host function calls of the guest of the module to it.
+func (r *wazeroRuntime) register(wazeroModule *wazeroModule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.modules[wazeroModule.mod] = wazeroModule
+}
+
+func (r *wazeroRuntime) unregister(wazeroModule *wazeroModule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
+	delete(r.modules, wazeroModule.mod)
 }
 
-// instantiateModule compiles and instantiates a WebAssembly module using the wazero runtime.
+// instantiateModule instantiates a compiled WebAssembly module using the wazero runtime.
 //
-// It compiles the module, creates a module configuration, and then instantiates the module.
+// It creates a module configuration, and then instantiates the module.
 // Returns the instantiated module and any potential error.
-func (r *wazeroRuntime) instantiateModule(ctx context.Context, moduleConfig *ModuleConfig) (api.Module, error) {
-
-	// Compile the provided WebAssembly binary.
-	compiled, err := r.runtime.CompileModule(ctx, moduleConfig.Wasm.Binary)
-	if err != nil {
-		return nil, errors.Join(errors.New("can't compile module"), err)
-	}
+func (r *wazeroRuntime) instantiateModule(ctx context.Context, compiled *wazeroCompiledModule, moduleConfig *ModuleConfig) (api.Module, error) {
 
 	// TODO: Add more configurations
 	cfg := wazero.NewModuleConfig()
@@ -228,12 +484,34 @@ func (r *wazeroRuntime) instantiateModule(ctx context.Context, moduleConfig *Mod
 		)
 	}
 
+	// Reactor modules, e.g. guests built with -buildmode=c-shared, are initialized by _initialize,
+	// commands by _start. Start functions the module doesn't export are skipped.
+	cfg = cfg.WithStartFunctions("_initialize", "_start")
+
+	// The snapshot holds the state after the start functions ran, don't run them again.
+	if moduleConfig != nil && moduleConfig.Snapshot != nil {
+		cfg = cfg.WithStartFunctions()
+	}
+
+	// Several versions of the guest may be instantiated at once, their module name would conflict.
+	if moduleConfig != nil && moduleConfig.anonymous {
+		cfg = cfg.WithName("")
+	}
+
 	// Instantiate the compiled module with the provided module configuration.
-	mod, err := r.runtime.InstantiateModule(ctx, compiled, cfg)
+	mod, err := r.runtime.InstantiateModule(ctx, compiled.compiled, cfg)
 	if err != nil {
 		return nil, errors.Join(errors.New("can't instantiate module"), err)
 	}
 
+	if moduleConfig != nil && moduleConfig.Snapshot != nil {
+		err = restoreSnapshot(mod, moduleConfig.Snapshot)
+		if err != nil {
+			mod.Close(ctx)
+			return nil, errors.Join(errors.New("can't restore module snapshot"), err)
+		}
+	}
+
 	return mod, nil
 }
 
@@ -0,0 +1,301 @@
+// Module Development Kit for wasify guests written in AssemblyScript.
+//
+// It implements the same protocol as the Go mdk, so AssemblyScript guests run on the wasify host:
+//
+// - PackedData packs a value type, an offset and a size into a u64,
+//   MultiPackedData references an array of packs.
+// - The wasify_malloc and wasify_free exports the host allocates guest memory with,
+//   and the wasify_protocol_version export the host negotiates the protocol version with,
+//   which the entry file of the guest must re-export.
+// - The log host function of the wasify namespace.
+
+/** A value in linear memory: the type in the highest 8 bits, the offset in the next 32 bits and the size in the lowest 24 bits. */
+export type PackedData = u64;
+
+/** A pack of type ValueType.Pack, referencing an array of packs. */
+export type MultiPackedData = u64;
+
+/** Type tags of the packs, matching the ValueType constants of the host. */
+export enum ValueType {
+  Bytes = 0,
+  Byte = 1,
+  I32 = 2,
+  I64 = 3,
+  F32 = 4,
+  F64 = 5,
+  String = 6,
+  Pack = 255,
+}
+
+/** Severity of a log record, as the LogSeverity of the host. */
+export enum LogLevel {
+  Debug = 1,
+  Info = 2,
+  Warning = 3,
+  Error = 4,
+}
+
+/** Version of the wire protocol implemented by the package, see PROTOCOL.md in the repository. */
+export const PROTOCOL_VERSION: u32 = 1;
+
+/** The largest size a pack can hold, sizes are 24 bits. */
+export const MAX_PACK_SIZE: u32 = 0xffffff;
+
+// Host functions
+
+@external("wasify", "log")
+declare function hostLog(msg: PackedData, level: PackedData): void;
+
+// Packing
+
+export function pack(valueType: ValueType, offset: u32, size: u32): PackedData {
+  return (<u64>(<u8>valueType) << 56) | (<u64>offset << 24) | <u64>(size & MAX_PACK_SIZE);
+}
+
+export function packType(pd: PackedData): ValueType {
+  return <ValueType>(<i32>(pd >> 56));
+}
+
+export function packOffset(pd: PackedData): u32 {
+  return <u32>(pd >> 24);
+}
+
+export function packSize(pd: PackedData): u32 {
+  return <u32>(pd & MAX_PACK_SIZE);
+}
+
+/** Returns the name of a value type, the same as the host, so errors read the same on both sides. */
+export function valueTypeName(valueType: ValueType): string {
+  switch (valueType) {
+    case ValueType.Bytes:
+      return "ValueTypeBytes";
+    case ValueType.Byte:
+      return "ValueTypeByte";
+    case ValueType.I32:
+      return "ValueTypeI32";
+    case ValueType.I64:
+      return "ValueTypeI64";
+    case ValueType.F32:
+      return "ValueTypeF32";
+    case ValueType.F64:
+      return "ValueTypeF64";
+    case ValueType.String:
+      return "ValueTypeString";
+    case ValueType.Pack:
+      return "ValueTypePack";
+  }
+  return "undefined";
+}
+
+/** Returns the error of reading the pack as a value of the expected type, or null if the types match. */
+export function checkPack(pd: PackedData, expected: ValueType): string | null {
+  const actual = packType(pd);
+  if (actual == expected) {
+    return null;
+  }
+  return "expected " + valueTypeName(expected) + ", got " + valueTypeName(actual);
+}
+
+function expectPack(pd: PackedData, expected: ValueType): void {
+  const err = checkPack(pd, expected);
+  if (err !== null) {
+    throw new Error(err!);
+  }
+}
+
+// Memory
+
+/** Allocates a zeroed block of size bytes. A zero size still allocates a distinct block. */
+export function malloc(size: u32): usize {
+  const ptr = heap.alloc(size > 0 ? size : 1);
+  memory.fill(ptr, 0, size);
+  return ptr;
+}
+
+/** Frees a block allocated by malloc, 0 is ignored. */
+export function free(ptr: usize): void {
+  if (ptr != 0) {
+    heap.free(ptr);
+  }
+}
+
+/** The allocator export the host calls to pass data to the guest. */
+export function wasify_malloc(size: u32): u32 {
+  return <u32>malloc(size);
+}
+
+/** The export the host calls to free memory allocated by wasify_malloc or by the guest. */
+export function wasify_free(offset: u32): void {
+  free(<usize>offset);
+}
+
+/** The export the host reads the protocol version of the guest from at instantiation. */
+export function wasify_protocol_version(): u32 {
+  return PROTOCOL_VERSION;
+}
+
+/** Frees the memory referenced by the pack. */
+export function freePack(pd: PackedData): void {
+  free(<usize>packOffset(pd));
+}
+
+export function freePacks(pds: PackedData[]): void {
+  for (let i = 0; i < pds.length; i++) {
+    freePack(pds[i]);
+  }
+}
+
+// Writing
+
+/** Copies size bytes at src into a new block and packs it. It logs an error and returns 0 if the data is too big for a pack. */
+function write(valueType: ValueType, src: usize, size: usize): PackedData {
+  if (size > <usize>MAX_PACK_SIZE) {
+    logError("wasify: data exceeds the 24 bits size of a pack");
+    return 0;
+  }
+
+  const ptr = malloc(<u32>size);
+  memory.copy(ptr, src, size);
+
+  return pack(valueType, <u32>ptr, <u32>size);
+}
+
+export function writeBytesPack(data: Uint8Array): PackedData {
+  return write(ValueType.Bytes, data.dataStart, data.byteLength);
+}
+
+export function writeBytePack(data: u8): PackedData {
+  const ptr = malloc(1);
+  store<u8>(ptr, data);
+  return pack(ValueType.Byte, <u32>ptr, 1);
+}
+
+export function writeU32Pack(data: u32): PackedData {
+  const ptr = malloc(4);
+  store<u32>(ptr, data);
+  return pack(ValueType.I32, <u32>ptr, 4);
+}
+
+export function writeU64Pack(data: u64): PackedData {
+  const ptr = malloc(8);
+  store<u64>(ptr, data);
+  return pack(ValueType.I64, <u32>ptr, 8);
+}
+
+export function writeF32Pack(data: f32): PackedData {
+  const ptr = malloc(4);
+  store<f32>(ptr, data);
+  return pack(ValueType.F32, <u32>ptr, 4);
+}
+
+export function writeF64Pack(data: f64): PackedData {
+  const ptr = malloc(8);
+  store<f64>(ptr, data);
+  return pack(ValueType.F64, <u32>ptr, 8);
+}
+
+/** Writes the string encoded as UTF-8. */
+export function writeStringPack(data: string): PackedData {
+  const buf = String.UTF8.encode(data);
+  return write(ValueType.String, changetype<usize>(buf), buf.byteLength);
+}
+
+/**
+ * Writes the packs into an array and returns the pack referencing it, or 0 if there are no packs.
+ * Return it from exported functions, the host reads and frees it.
+ */
+export function writeMultiPack(pds: PackedData[]): MultiPackedData {
+  if (pds.length == 0) {
+    return 0;
+  }
+  return write(ValueType.Pack, pds.dataStart, <usize>pds.length << 3);
+}
+
+// Reading
+//
+// The read functions abort if the pack isn't of the expected type, use checkPack to handle mismatches.
+// Values are copied, so the packs can be freed.
+
+export function readBytesPack(pd: PackedData): Uint8Array {
+  expectPack(pd, ValueType.Bytes);
+  const data = new Uint8Array(packSize(pd));
+  memory.copy(data.dataStart, <usize>packOffset(pd), data.byteLength);
+  return data;
+}
+
+export function readBytePack(pd: PackedData): u8 {
+  expectPack(pd, ValueType.Byte);
+  return load<u8>(<usize>packOffset(pd));
+}
+
+export function readU32Pack(pd: PackedData): u32 {
+  expectPack(pd, ValueType.I32);
+  return load<u32>(<usize>packOffset(pd));
+}
+
+export function readU64Pack(pd: PackedData): u64 {
+  expectPack(pd, ValueType.I64);
+  return load<u64>(<usize>packOffset(pd));
+}
+
+export function readF32Pack(pd: PackedData): f32 {
+  expectPack(pd, ValueType.F32);
+  return load<f32>(<usize>packOffset(pd));
+}
+
+export function readF64Pack(pd: PackedData): f64 {
+  expectPack(pd, ValueType.F64);
+  return load<f64>(<usize>packOffset(pd));
+}
+
+export function readStringPack(pd: PackedData): string {
+  expectPack(pd, ValueType.String);
+  return String.UTF8.decodeUnsafe(<usize>packOffset(pd), packSize(pd));
+}
+
+/**
+ * Reads the packs of the array and frees it, so it must be read once.
+ * The values referenced by the packs aren't freed, see freePacks.
+ */
+export function readPacks(mpd: MultiPackedData): PackedData[] {
+  if (mpd == 0) {
+    return [];
+  }
+  expectPack(mpd, ValueType.Pack);
+
+  const count = packSize(mpd) >> 3;
+  const pds = new Array<PackedData>(count);
+  memory.copy(pds.dataStart, <usize>packOffset(mpd), <usize>count << 3);
+  freePack(mpd);
+
+  return pds;
+}
+
+// Logging
+
+/** Logs the message with the host logger. */
+export function log(level: LogLevel, msg: string): void {
+  const msgPd = writeStringPack(msg);
+  const levelPd = writeBytePack(<u8>level);
+
+  hostLog(msgPd, levelPd);
+
+  freePack(msgPd);
+  freePack(levelPd);
+}
+
+export function logDebug(msg: string): void {
+  log(LogLevel.Debug, msg);
+}
+
+export function logInfo(msg: string): void {
+  log(LogLevel.Info, msg);
+}
+
+export function logWarning(msg: string): void {
+  log(LogLevel.Warning, msg);
+}
+
+export function logError(msg: string): void {
+  log(LogLevel.Error, msg);
+}
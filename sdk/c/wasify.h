@@ -0,0 +1,392 @@
+/*
+ * wasify.h - Module Development Kit for wasify guests written in C.
+ *
+ * It implements the same protocol as the Go mdk, so C guests run on the wasify host:
+ *
+ * - wasify_packed_data packs a value type, an offset and a size into a uint64_t,
+ *   wasify_multi_packed_data references an array of packs.
+ * - The wasify_malloc and wasify_free exports the host allocates guest memory with.
+ * - The wasify_protocol_version custom section and export the host negotiates the protocol version with.
+ * - The log host function of the wasify namespace.
+ *
+ * The library is header-only. Define WASIFY_IMPLEMENTATION in exactly one source file
+ * before including it, so the exports are defined once:
+ *
+ *	#define WASIFY_IMPLEMENTATION
+ *	#include "wasify.h"
+ *
+ *	WASIFY_EXPORT("greet")
+ *	wasify_multi_packed_data greet(wasify_packed_data name) {
+ *		const char *data;
+ *		uint32_t size;
+ *		if (wasify_read_string_pack(name, &data, &size) != WASIFY_OK) {
+ *			return 0;
+ *		}
+ *		wasify_log_info("greeting");
+ *		wasify_packed_data res = wasify_write_string_pack_n(data, size);
+ *		wasify_free_pack(name);
+ *		return wasify_write_multi_pack(&res, 1);
+ *	}
+ *
+ * Guests are built as wasm32 reactors with the wasi-sdk:
+ *
+ *	clang --target=wasm32-wasi -mexec-model=reactor -O2 -o main.wasm main.c
+ *
+ * Offsets are pointers of the wasm32 linear memory, the library isn't meant to run natively.
+ */
+
+#ifndef WASIFY_H
+#define WASIFY_H
+
+#include <stddef.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+/* A value in linear memory: the type in the highest 8 bits, the offset in the next 32 bits and the size in the lowest 24 bits. */
+typedef uint64_t wasify_packed_data;
+
+/* A pack of type WASIFY_VALUE_TYPE_PACK, referencing an array of packs. */
+typedef uint64_t wasify_multi_packed_data;
+
+/* Type tags of the packs, matching the ValueType constants of the host. */
+typedef enum {
+	WASIFY_VALUE_TYPE_BYTES = 0,
+	WASIFY_VALUE_TYPE_BYTE = 1,
+	WASIFY_VALUE_TYPE_I32 = 2,
+	WASIFY_VALUE_TYPE_I64 = 3,
+	WASIFY_VALUE_TYPE_F32 = 4,
+	WASIFY_VALUE_TYPE_F64 = 5,
+	WASIFY_VALUE_TYPE_STRING = 6,
+	WASIFY_VALUE_TYPE_PACK = 255,
+} wasify_value_type;
+
+/* Severity of a log record, as the LogSeverity of the host. */
+typedef enum {
+	WASIFY_LOG_DEBUG = 1,
+	WASIFY_LOG_INFO = 2,
+	WASIFY_LOG_WARNING = 3,
+	WASIFY_LOG_ERROR = 4,
+} wasify_log_level;
+
+/* Results of the read functions. */
+typedef enum {
+	WASIFY_OK = 0,
+	WASIFY_ERR_TYPE_MISMATCH = 1,
+} wasify_result;
+
+/* Version of the wire protocol implemented by the header, see PROTOCOL.md in the repository. */
+#define WASIFY_PROTOCOL_VERSION 1u
+
+/* The largest size a pack can hold, sizes are 24 bits. */
+#define WASIFY_MAX_PACK_SIZE 0xFFFFFFu
+
+#ifdef __wasm__
+#define WASIFY_EXPORT(name) __attribute__((export_name(name)))
+#define WASIFY_IMPORT(module, name) __attribute__((import_module(module), import_name(name)))
+#else
+#define WASIFY_EXPORT(name)
+#define WASIFY_IMPORT(module, name)
+#endif
+
+/*
+ * Host functions
+ */
+
+#ifdef __wasm__
+WASIFY_IMPORT("wasify", "log")
+void wasify_host_log(wasify_packed_data msg, wasify_packed_data level);
+#else
+/* Outside wasm the host functions do nothing, so the header can be compiled natively, e.g. to check it. */
+static inline void wasify_host_log(wasify_packed_data msg, wasify_packed_data level) {
+	(void)msg;
+	(void)level;
+}
+#endif
+
+/*
+ * Packing
+ */
+
+static inline wasify_packed_data wasify_pack(wasify_value_type type, uint32_t offset, uint32_t size) {
+	return ((uint64_t)(uint8_t)type << 56) | ((uint64_t)offset << 24) | (uint64_t)(size & WASIFY_MAX_PACK_SIZE);
+}
+
+static inline wasify_value_type wasify_pack_type(wasify_packed_data pd) {
+	return (wasify_value_type)(pd >> 56);
+}
+
+static inline uint32_t wasify_pack_offset(wasify_packed_data pd) {
+	return (uint32_t)(pd >> 24);
+}
+
+static inline uint32_t wasify_pack_size(wasify_packed_data pd) {
+	return (uint32_t)(pd & WASIFY_MAX_PACK_SIZE);
+}
+
+static inline void *wasify_pack_ptr(wasify_packed_data pd) {
+	return (void *)(uintptr_t)wasify_pack_offset(pd);
+}
+
+/* Returns the name of a value type, the same as the host, so errors read the same on both sides. */
+static inline const char *wasify_value_type_name(wasify_value_type type) {
+	switch (type) {
+	case WASIFY_VALUE_TYPE_BYTES:
+		return "ValueTypeBytes";
+	case WASIFY_VALUE_TYPE_BYTE:
+		return "ValueTypeByte";
+	case WASIFY_VALUE_TYPE_I32:
+		return "ValueTypeI32";
+	case WASIFY_VALUE_TYPE_I64:
+		return "ValueTypeI64";
+	case WASIFY_VALUE_TYPE_F32:
+		return "ValueTypeF32";
+	case WASIFY_VALUE_TYPE_F64:
+		return "ValueTypeF64";
+	case WASIFY_VALUE_TYPE_STRING:
+		return "ValueTypeString";
+	case WASIFY_VALUE_TYPE_PACK:
+		return "ValueTypePack";
+	}
+	return "undefined";
+}
+
+/*
+ * Memory
+ */
+
+/* Allocates a zeroed block of size bytes. A zero size still allocates a distinct block. */
+static inline void *wasify_malloc_block(uint32_t size) {
+	return calloc(size ? size : 1, 1);
+}
+
+/* Frees the memory referenced by a pack, packs of offset 0 are ignored. */
+static inline void wasify_free_pack(wasify_packed_data pd) {
+	free(wasify_pack_ptr(pd));
+}
+
+/* Logs the message with the host logger. */
+static inline void wasify_log_n(wasify_log_level level, const char *msg, uint32_t size);
+
+/* Copies data into a new block and packs it. It logs an error and returns 0 if data is too big for a pack. */
+static inline wasify_packed_data wasify_write_pack(wasify_value_type type, const void *data, size_t size) {
+	if (size > WASIFY_MAX_PACK_SIZE) {
+		static const char msg[] = "wasify: data exceeds the 24 bits size of a pack";
+		wasify_log_n(WASIFY_LOG_ERROR, msg, sizeof(msg) - 1);
+		return 0;
+	}
+
+	void *block = wasify_malloc_block((uint32_t)size);
+	if (block == NULL) {
+		return 0;
+	}
+	if (size > 0) {
+		memcpy(block, data, size);
+	}
+
+	return wasify_pack(type, (uint32_t)(uintptr_t)block, (uint32_t)size);
+}
+
+static inline wasify_packed_data wasify_write_bytes_pack(const void *data, size_t size) {
+	return wasify_write_pack(WASIFY_VALUE_TYPE_BYTES, data, size);
+}
+
+static inline wasify_packed_data wasify_write_byte_pack(uint8_t data) {
+	return wasify_write_pack(WASIFY_VALUE_TYPE_BYTE, &data, sizeof(data));
+}
+
+static inline wasify_packed_data wasify_write_u32_pack(uint32_t data) {
+	return wasify_write_pack(WASIFY_VALUE_TYPE_I32, &data, sizeof(data));
+}
+
+static inline wasify_packed_data wasify_write_u64_pack(uint64_t data) {
+	return wasify_write_pack(WASIFY_VALUE_TYPE_I64, &data, sizeof(data));
+}
+
+static inline wasify_packed_data wasify_write_f32_pack(float data) {
+	return wasify_write_pack(WASIFY_VALUE_TYPE_F32, &data, sizeof(data));
+}
+
+static inline wasify_packed_data wasify_write_f64_pack(double data) {
+	return wasify_write_pack(WASIFY_VALUE_TYPE_F64, &data, sizeof(data));
+}
+
+/* Writes a string of size bytes, which doesn't need to be NUL-terminated. */
+static inline wasify_packed_data wasify_write_string_pack_n(const char *data, size_t size) {
+	return wasify_write_pack(WASIFY_VALUE_TYPE_STRING, data, size);
+}
+
+/* Writes a NUL-terminated string, the terminator isn't part of the pack. */
+static inline wasify_packed_data wasify_write_string_pack(const char *data) {
+	return wasify_write_string_pack_n(data, strlen(data));
+}
+
+/*
+ * Writes the packs into an array and returns the pack referencing it, or 0 if there are no packs.
+ * Return it from exported functions, the host reads and frees it.
+ */
+static inline wasify_multi_packed_data wasify_write_multi_pack(const wasify_packed_data *pds, size_t count) {
+	if (count == 0) {
+		return 0;
+	}
+	return wasify_write_pack(WASIFY_VALUE_TYPE_PACK, pds, count * sizeof(wasify_packed_data));
+}
+
+/* Checks the type of a pack. */
+static inline wasify_result wasify_expect(wasify_packed_data pd, wasify_value_type expected) {
+	return wasify_pack_type(pd) == expected ? WASIFY_OK : WASIFY_ERR_TYPE_MISMATCH;
+}
+
+/*
+ * The read functions check the type of the pack and store its value in out.
+ * Bytes and strings reference the memory of the pack, they're valid until the pack is freed,
+ * and strings aren't NUL-terminated.
+ */
+
+static inline wasify_result wasify_read_bytes_pack(wasify_packed_data pd, const uint8_t **data, uint32_t *size) {
+	wasify_result res = wasify_expect(pd, WASIFY_VALUE_TYPE_BYTES);
+	if (res == WASIFY_OK) {
+		*data = (const uint8_t *)wasify_pack_ptr(pd);
+		*size = wasify_pack_size(pd);
+	}
+	return res;
+}
+
+static inline wasify_result wasify_read_byte_pack(wasify_packed_data pd, uint8_t *out) {
+	wasify_result res = wasify_expect(pd, WASIFY_VALUE_TYPE_BYTE);
+	if (res == WASIFY_OK) {
+		memcpy(out, wasify_pack_ptr(pd), sizeof(*out));
+	}
+	return res;
+}
+
+static inline wasify_result wasify_read_u32_pack(wasify_packed_data pd, uint32_t *out) {
+	wasify_result res = wasify_expect(pd, WASIFY_VALUE_TYPE_I32);
+	if (res == WASIFY_OK) {
+		memcpy(out, wasify_pack_ptr(pd), sizeof(*out));
+	}
+	return res;
+}
+
+static inline wasify_result wasify_read_u64_pack(wasify_packed_data pd, uint64_t *out) {
+	wasify_result res = wasify_expect(pd, WASIFY_VALUE_TYPE_I64);
+	if (res == WASIFY_OK) {
+		memcpy(out, wasify_pack_ptr(pd), sizeof(*out));
+	}
+	return res;
+}
+
+static inline wasify_result wasify_read_f32_pack(wasify_packed_data pd, float *out) {
+	wasify_result res = wasify_expect(pd, WASIFY_VALUE_TYPE_F32);
+	if (res == WASIFY_OK) {
+		memcpy(out, wasify_pack_ptr(pd), sizeof(*out));
+	}
+	return res;
+}
+
+static inline wasify_result wasify_read_f64_pack(wasify_packed_data pd, double *out) {
+	wasify_result res = wasify_expect(pd, WASIFY_VALUE_TYPE_F64);
+	if (res == WASIFY_OK) {
+		memcpy(out, wasify_pack_ptr(pd), sizeof(*out));
+	}
+	return res;
+}
+
+static inline wasify_result wasify_read_string_pack(wasify_packed_data pd, const char **data, uint32_t *size) {
+	wasify_result res = wasify_expect(pd, WASIFY_VALUE_TYPE_STRING);
+	if (res == WASIFY_OK) {
+		*data = (const char *)wasify_pack_ptr(pd);
+		*size = wasify_pack_size(pd);
+	}
+	return res;
+}
+
+/*
+ * Stores the packs of the array in pds and returns their count, 0 if mpd is 0 or isn't an array.
+ * The array is valid until mpd is freed with wasify_free_pack, which doesn't free the values of the packs.
+ */
+static inline size_t wasify_read_packs(wasify_multi_packed_data mpd, const wasify_packed_data **pds) {
+	if (mpd == 0 || wasify_expect(mpd, WASIFY_VALUE_TYPE_PACK) != WASIFY_OK) {
+		*pds = NULL;
+		return 0;
+	}
+	*pds = (const wasify_packed_data *)wasify_pack_ptr(mpd);
+	return wasify_pack_size(mpd) / sizeof(wasify_packed_data);
+}
+
+/*
+ * Logging
+ */
+
+static inline void wasify_log_n(wasify_log_level level, const char *msg, uint32_t size) {
+	wasify_packed_data msg_pd = wasify_write_pack(WASIFY_VALUE_TYPE_STRING, msg, size > WASIFY_MAX_PACK_SIZE ? WASIFY_MAX_PACK_SIZE : size);
+	wasify_packed_data level_pd = wasify_write_byte_pack((uint8_t)level);
+
+	wasify_host_log(msg_pd, level_pd);
+
+	wasify_free_pack(msg_pd);
+	wasify_free_pack(level_pd);
+}
+
+/* Logs a NUL-terminated message with the host logger. */
+static inline void wasify_log(wasify_log_level level, const char *msg) {
+	wasify_log_n(level, msg, (uint32_t)strlen(msg));
+}
+
+static inline void wasify_log_debug(const char *msg) {
+	wasify_log(WASIFY_LOG_DEBUG, msg);
+}
+
+static inline void wasify_log_info(const char *msg) {
+	wasify_log(WASIFY_LOG_INFO, msg);
+}
+
+static inline void wasify_log_warning(const char *msg) {
+	wasify_log(WASIFY_LOG_WARNING, msg);
+}
+
+static inline void wasify_log_error(const char *msg) {
+	wasify_log(WASIFY_LOG_ERROR, msg);
+}
+
+/*
+ * Exports, see WASIFY_IMPLEMENTATION.
+ */
+
+#ifdef WASIFY_IMPLEMENTATION
+
+/* The allocator export the host calls to pass data to the guest. */
+WASIFY_EXPORT("wasify_malloc")
+uint32_t wasify_malloc(uint32_t size) {
+	return (uint32_t)(uintptr_t)wasify_malloc_block(size);
+}
+
+/* The export the host calls to free memory allocated by wasify_malloc or by the guest. */
+WASIFY_EXPORT("wasify_free")
+void wasify_free(uint32_t offset) {
+	free((void *)(uintptr_t)offset);
+}
+
+#ifdef __wasm__
+/* Declares the protocol version in a custom section, so the host refuses a guest it can't decode before running it. */
+__attribute__((section(".custom_section.wasify_protocol_version"), used))
+static const uint8_t wasify_protocol_version_section[4] = {WASIFY_PROTOCOL_VERSION, 0, 0, 0};
+#endif
+
+/* The export the host reads the protocol version of the guest from at instantiation. */
+WASIFY_EXPORT("wasify_protocol_version")
+uint32_t wasify_protocol_version(void) {
+	return WASIFY_PROTOCOL_VERSION;
+}
+
+#endif /* WASIFY_IMPLEMENTATION */
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif /* WASIFY_H */
@@ -0,0 +1,76 @@
+use crate::memory::{free_pack, write_byte_pack, write_string_pack};
+use crate::pack::{MultiPackedData, PackedData};
+
+#[cfg(target_arch = "wasm32")]
+#[link(wasm_import_module = "wasify")]
+extern "C" {
+    #[link_name = "log"]
+    fn host_log(msg: PackedData, level: PackedData);
+
+    #[link_name = "trace_parent"]
+    fn host_trace_parent() -> MultiPackedData;
+}
+
+// The host functions are only imported by wasm guests.
+// Elsewhere they do nothing, so the crate can be built and unit tested natively.
+
+#[cfg(not(target_arch = "wasm32"))]
+unsafe fn host_log(_msg: PackedData, _level: PackedData) {}
+
+#[cfg(not(target_arch = "wasm32"))]
+unsafe fn host_trace_parent() -> MultiPackedData {
+    MultiPackedData(0)
+}
+
+/// Severity of a log record, as the LogSeverity of the host.
+#[derive(Clone, Copy, Debug, PartialEq, Eq)]
+#[repr(u8)]
+pub enum Level {
+    Debug = 1,
+    Info = 2,
+    Warning = 3,
+    Error = 4,
+}
+
+/// Logs the message with the host logger.
+pub fn log(level: Level, msg: &str) {
+    let msg = write_string_pack(msg);
+    let level = write_byte_pack(level as u8);
+
+    unsafe { host_log(msg, level) };
+
+    free_pack(&[msg, level]);
+}
+
+pub fn log_debug(msg: &str) {
+    log(Level::Debug, msg)
+}
+
+pub fn log_info(msg: &str) {
+    log(Level::Info, msg)
+}
+
+pub fn log_warning(msg: &str) {
+    log(Level::Warning, msg)
+}
+
+pub fn log_error(msg: &str) {
+    log(Level::Error, msg)
+}
+
+/// Returns the W3C traceparent of the host span the guest is running in,
+/// or an empty string if the host has no tracer.
+pub fn trace_parent() -> String {
+    let Ok(pds) = unsafe { host_trace_parent() }.read_packs() else {
+        return String::new();
+    };
+
+    let traceparent = pds
+        .first()
+        .and_then(|pd| crate::memory::read_string_pack(*pd).ok())
+        .unwrap_or_default();
+
+    free_pack(&pds);
+
+    traceparent
+}
@@ -0,0 +1,48 @@
+//! Module Development Kit for wasify guests written in Rust.
+//!
+//! It implements the same protocol as the Go mdk, so Rust guests run on the wasify host:
+//!
+//! - [`PackedData`] packs a value type, an offset and a size into a `u64`,
+//!   [`MultiPackedData`] references an array of packs.
+//! - The `wasify_malloc` and `wasify_free` exports the host allocates guest memory with.
+//! - The `wasify_protocol_version` custom section and export the host negotiates the protocol version with.
+//! - The `log` and `trace_parent` host functions of the `wasify` namespace.
+//!
+//! Guests are built for `wasm32-unknown-unknown` or `wasm32-wasip1` as a `cdylib`:
+//!
+//! ```ignore
+//! use wasify_mdk::{MultiPackedData, PackedData};
+//!
+//! #[no_mangle]
+//! pub extern "C" fn greet(name: PackedData) -> MultiPackedData {
+//!     let name = wasify_mdk::read_string_pack(name).unwrap_or_default();
+//!     wasify_mdk::log_info(&format!("greeting {name}"));
+//!     wasify_mdk::write_multi_pack(&[wasify_mdk::write_string_pack(&format!("Hello, {name}"))])
+//! }
+//! ```
+//!
+//! Reading and writing packs dereferences the offsets as pointers, which is only valid in wasm32 linear memory.
+
+mod host;
+mod memory;
+mod pack;
+
+pub use host::*;
+pub use memory::*;
+pub use pack::*;
+
+/// Version of the wire protocol implemented by the crate, see PROTOCOL.md in the repository.
+pub const PROTOCOL_VERSION: u32 = 1;
+
+/// Declares the protocol version in a custom section, so the host refuses a guest it can't decode before running it.
+#[cfg(target_arch = "wasm32")]
+#[link_section = "wasify_protocol_version"]
+#[used]
+static PROTOCOL_VERSION_SECTION: [u8; 4] = PROTOCOL_VERSION.to_le_bytes();
+
+/// The export the host reads the protocol version of the guest from at instantiation.
+#[cfg(target_arch = "wasm32")]
+#[no_mangle]
+pub extern "C" fn wasify_protocol_version() -> u32 {
+    PROTOCOL_VERSION
+}
@@ -0,0 +1,213 @@
+use std::alloc::{alloc_zeroed, dealloc, Layout};
+use std::ptr;
+
+use crate::host::log_error;
+use crate::pack::{Error, MultiPackedData, PackedData, ValueType, MAX_PACK_SIZE};
+
+/// Every block starts with a header holding its size, so `free` only needs the offset.
+/// It also keeps blocks 8-byte aligned.
+const HEADER: usize = 8;
+
+/// Allocates a zeroed block of `size` bytes and returns its address, or null if the allocation fails.
+/// A zero size still allocates a distinct block.
+pub fn malloc(size: usize) -> *mut u8 {
+    let Ok(layout) = Layout::from_size_align(size.saturating_add(HEADER), HEADER) else {
+        return ptr::null_mut();
+    };
+
+    unsafe {
+        let block = alloc_zeroed(layout);
+        if block.is_null() {
+            return block;
+        }
+
+        (block as *mut usize).write(size);
+        block.add(HEADER)
+    }
+}
+
+/// Frees a block allocated by [`malloc`]. Null is ignored.
+///
+/// # Safety
+///
+/// `ptr` must be null or returned by [`malloc`], and not freed yet.
+pub unsafe fn free(ptr: *mut u8) {
+    if ptr.is_null() {
+        return;
+    }
+
+    let block = ptr.sub(HEADER);
+    let size = (block as *const usize).read();
+    dealloc(block, Layout::from_size_align_unchecked(size + HEADER, HEADER));
+}
+
+/// The allocator export the host calls to pass data to the guest.
+#[cfg(target_arch = "wasm32")]
+#[no_mangle]
+pub extern "C" fn wasify_malloc(size: u32) -> u32 {
+    malloc(size as usize) as u32
+}
+
+/// The export the host calls to free memory allocated by `wasify_malloc` or by the guest.
+#[cfg(target_arch = "wasm32")]
+#[no_mangle]
+pub unsafe extern "C" fn wasify_free(offset: u32) {
+    free(offset as usize as *mut u8)
+}
+
+/// Copies `data` into a new block and packs it as a value of type `value_type`.
+/// It logs the error and returns an empty pack if `data` is too big for a pack.
+fn write(value_type: ValueType, data: &[u8]) -> PackedData {
+    if data.len() > MAX_PACK_SIZE as usize {
+        log_error(&Error::SizeOverflow(data.len()).to_string());
+        return PackedData(0);
+    }
+
+    let offset = malloc(data.len());
+    unsafe { ptr::copy_nonoverlapping(data.as_ptr(), offset, data.len()) };
+
+    PackedData::new(value_type, offset as usize as u32, data.len() as u32).unwrap_or_default()
+}
+
+pub fn write_bytes_pack(data: &[u8]) -> PackedData {
+    write(ValueType::Bytes, data)
+}
+
+pub fn write_byte_pack(data: u8) -> PackedData {
+    write(ValueType::Byte, &[data])
+}
+
+pub fn write_u32_pack(data: u32) -> PackedData {
+    write(ValueType::I32, &data.to_le_bytes())
+}
+
+pub fn write_u64_pack(data: u64) -> PackedData {
+    write(ValueType::I64, &data.to_le_bytes())
+}
+
+pub fn write_f32_pack(data: f32) -> PackedData {
+    write(ValueType::F32, &data.to_le_bytes())
+}
+
+pub fn write_f64_pack(data: f64) -> PackedData {
+    write(ValueType::F64, &data.to_le_bytes())
+}
+
+pub fn write_string_pack(data: &str) -> PackedData {
+    write(ValueType::String, data.as_bytes())
+}
+
+/// Writes the packs into an array and returns the pack referencing it, or 0 if there are no packs.
+/// Return it from exported functions, the host reads and frees it.
+pub fn write_multi_pack(pds: &[PackedData]) -> MultiPackedData {
+    if pds.is_empty() {
+        return MultiPackedData(0);
+    }
+
+    let data: Vec<u8> = pds.iter().flat_map(|pd| pd.0.to_le_bytes()).collect();
+
+    MultiPackedData(write(ValueType::Pack, &data).0)
+}
+
+/// Returns a copy of the bytes referenced by a pack of the expected type.
+fn read(pd: PackedData, expected: ValueType) -> Result<Vec<u8>, Error> {
+    let (offset, size) = pd.expect(expected)?;
+
+    let mut data = vec![0; size as usize];
+    unsafe { ptr::copy_nonoverlapping(offset as usize as *const u8, data.as_mut_ptr(), data.len()) };
+
+    Ok(data)
+}
+
+/// Reads a fixed size value, the pack size isn't trusted.
+fn read_array<const N: usize>(pd: PackedData, expected: ValueType) -> Result<[u8; N], Error> {
+    let (offset, _) = pd.expect(expected)?;
+
+    Ok(unsafe { (offset as usize as *const [u8; N]).read_unaligned() })
+}
+
+pub fn read_bytes_pack(pd: PackedData) -> Result<Vec<u8>, Error> {
+    read(pd, ValueType::Bytes)
+}
+
+pub fn read_byte_pack(pd: PackedData) -> Result<u8, Error> {
+    read_array::<1>(pd, ValueType::Byte).map(|b| b[0])
+}
+
+pub fn read_u32_pack(pd: PackedData) -> Result<u32, Error> {
+    read_array(pd, ValueType::I32).map(u32::from_le_bytes)
+}
+
+pub fn read_u64_pack(pd: PackedData) -> Result<u64, Error> {
+    read_array(pd, ValueType::I64).map(u64::from_le_bytes)
+}
+
+pub fn read_f32_pack(pd: PackedData) -> Result<f32, Error> {
+    read_array(pd, ValueType::F32).map(f32::from_le_bytes)
+}
+
+pub fn read_f64_pack(pd: PackedData) -> Result<f64, Error> {
+    read_array(pd, ValueType::F64).map(f64::from_le_bytes)
+}
+
+pub fn read_string_pack(pd: PackedData) -> Result<String, Error> {
+    String::from_utf8(read(pd, ValueType::String)?).map_err(|_| Error::InvalidUtf8)
+}
+
+impl MultiPackedData {
+    /// Reads the packs of the array and frees it, so it must be read once.
+    /// The values referenced by the packs aren't freed, see [`free_pack`].
+    pub fn read_packs(self) -> Result<Vec<PackedData>, Error> {
+        if self.0 == 0 {
+            return Ok(Vec::new());
+        }
+
+        let pack = PackedData(self.0);
+        let data = read(pack, ValueType::Pack)?;
+        free_pack(&[pack]);
+
+        Ok(data
+            .chunks_exact(8)
+            .map(|b| PackedData(u64::from_le_bytes(b.try_into().unwrap())))
+            .collect())
+    }
+}
+
+/// Frees the memory referenced by the packs.
+pub fn free_pack(pds: &[PackedData]) {
+    for pd in pds {
+        let (_, offset, _) = pd.unpack();
+        unsafe { free(offset as usize as *mut u8) };
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn allocator() {
+        let ptr = malloc(12);
+        assert!(!ptr.is_null());
+        assert_eq!(ptr as usize % HEADER, 0, "blocks are 8-byte aligned");
+
+        let block = unsafe { std::slice::from_raw_parts_mut(ptr, 12) };
+        assert_eq!(block, &[0; 12]);
+        block.copy_from_slice(b"dirty memory");
+
+        let empty = malloc(0);
+        assert!(!empty.is_null());
+        assert_ne!(empty, malloc(0), "zero size allocations have distinct addresses");
+
+        unsafe {
+            free(ptr);
+            free(empty);
+            free(ptr::null_mut());
+        }
+    }
+
+    #[test]
+    fn allocation_too_big() {
+        assert!(malloc(usize::MAX).is_null());
+    }
+}
@@ -0,0 +1,183 @@
+use std::fmt;
+
+/// Type tag of a pack, the highest 8 bits of a [`PackedData`].
+///
+/// The values match the ValueType constants of the wasify host.
+#[derive(Clone, Copy, Debug, PartialEq, Eq)]
+#[repr(u8)]
+pub enum ValueType {
+    Bytes = 0,
+    Byte = 1,
+    I32 = 2,
+    I64 = 3,
+    F32 = 4,
+    F64 = 5,
+    String = 6,
+    /// An array of packs, see [`MultiPackedData`].
+    Pack = 255,
+}
+
+impl ValueType {
+    /// Returns the value type of a type tag, or `None` if the tag is unknown.
+    pub fn from_tag(tag: u8) -> Option<ValueType> {
+        match tag {
+            0 => Some(ValueType::Bytes),
+            1 => Some(ValueType::Byte),
+            2 => Some(ValueType::I32),
+            3 => Some(ValueType::I64),
+            4 => Some(ValueType::F32),
+            5 => Some(ValueType::F64),
+            6 => Some(ValueType::String),
+            255 => Some(ValueType::Pack),
+            _ => None,
+        }
+    }
+}
+
+impl fmt::Display for ValueType {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        // Same names as the host, so errors read the same on both sides.
+        let name = match self {
+            ValueType::Bytes => "ValueTypeBytes",
+            ValueType::Byte => "ValueTypeByte",
+            ValueType::I32 => "ValueTypeI32",
+            ValueType::I64 => "ValueTypeI64",
+            ValueType::F32 => "ValueTypeF32",
+            ValueType::F64 => "ValueTypeF64",
+            ValueType::String => "ValueTypeString",
+            ValueType::Pack => "ValueTypePack",
+        };
+        f.write_str(name)
+    }
+}
+
+/// The largest size a pack can hold, sizes are 24 bits.
+pub const MAX_PACK_SIZE: u32 = (1 << 24) - 1;
+
+/// A value in linear memory, packed into a `u64`:
+///
+/// - highest 8 bits: the [`ValueType`] tag
+/// - next 32 bits: the offset of the value
+/// - lowest 24 bits: the size of the value in bytes
+#[derive(Clone, Copy, Debug, Default, PartialEq, Eq, Hash)]
+#[repr(transparent)]
+pub struct PackedData(pub u64);
+
+/// A pack of type [`ValueType::Pack`] referencing an array of [`PackedData`] in linear memory,
+/// used by functions to return several values. `0` means no values.
+#[derive(Clone, Copy, Debug, Default, PartialEq, Eq, Hash)]
+#[repr(transparent)]
+pub struct MultiPackedData(pub u64);
+
+impl PackedData {
+    /// Packs a value type, an offset and a size.
+    pub fn new(value_type: ValueType, offset: u32, size: u32) -> Result<PackedData, Error> {
+        if size > MAX_PACK_SIZE {
+            return Err(Error::SizeOverflow(size as usize));
+        }
+
+        Ok(PackedData(
+            ((value_type as u64) << 56) | ((offset as u64) << 24) | (size as u64 & 0xFF_FFFF),
+        ))
+    }
+
+    /// Returns the type tag, the offset and the size of the pack.
+    pub fn unpack(self) -> (u8, u32, u32) {
+        (
+            (self.0 >> 56) as u8,
+            ((self.0 >> 24) & 0xFFFF_FFFF) as u32,
+            (self.0 & 0xFF_FFFF) as u32,
+        )
+    }
+
+    /// Returns the value type of the pack, or `None` if its tag is unknown.
+    pub fn value_type(self) -> Option<ValueType> {
+        ValueType::from_tag(self.unpack().0)
+    }
+
+    /// Returns the offset and the size of the pack if it has the expected type.
+    pub(crate) fn expect(self, expected: ValueType) -> Result<(u32, u32), Error> {
+        let (tag, offset, size) = self.unpack();
+        if tag != expected as u8 {
+            return Err(Error::TypeMismatch { expected, actual: tag });
+        }
+        Ok((offset, size))
+    }
+}
+
+/// The errors of reading and writing packs.
+#[derive(Clone, Debug, PartialEq, Eq)]
+pub enum Error {
+    /// The pack doesn't have the expected type, `actual` is the tag of the pack.
+    TypeMismatch { expected: ValueType, actual: u8 },
+
+    /// The value doesn't fit in the 24 bits size of a pack.
+    SizeOverflow(usize),
+
+    /// A string pack doesn't hold valid UTF-8.
+    InvalidUtf8,
+}
+
+impl fmt::Display for Error {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Error::TypeMismatch { expected, actual } => match ValueType::from_tag(*actual) {
+                Some(actual) => write!(f, "expected {expected}, got {actual}"),
+                None => write!(f, "expected {expected}, got unknown type {actual}"),
+            },
+            Error::SizeOverflow(size) => {
+                write!(f, "size {size} exceeds the maximum pack size {MAX_PACK_SIZE}")
+            }
+            Error::InvalidUtf8 => f.write_str("string is not valid UTF-8"),
+        }
+    }
+}
+
+impl std::error::Error for Error {}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn pack_layout() {
+        let pd = PackedData::new(ValueType::String, 0x0102_0304, 0x0A0B0C).unwrap();
+        assert_eq!(pd.0, 0x06_01020304_0A0B0C);
+        assert_eq!(pd.unpack(), (6, 0x0102_0304, 0x0A0B0C));
+        assert_eq!(pd.value_type(), Some(ValueType::String));
+
+        let pd = PackedData::new(ValueType::Pack, u32::MAX, MAX_PACK_SIZE).unwrap();
+        assert_eq!(pd.unpack(), (255, u32::MAX, MAX_PACK_SIZE));
+        assert_eq!(pd.value_type(), Some(ValueType::Pack));
+    }
+
+    #[test]
+    fn pack_size_overflow() {
+        assert_eq!(
+            PackedData::new(ValueType::Bytes, 0, MAX_PACK_SIZE + 1),
+            Err(Error::SizeOverflow(1 << 24))
+        );
+    }
+
+    #[test]
+    fn value_type_tags() {
+        for tag in 0..=255u8 {
+            match ValueType::from_tag(tag) {
+                Some(vt) => assert_eq!(vt as u8, tag),
+                None => assert!((7..255).contains(&tag)),
+            }
+        }
+    }
+
+    #[test]
+    fn expect_type() {
+        let pd = PackedData::new(ValueType::I32, 8, 4).unwrap();
+        assert_eq!(pd.expect(ValueType::I32), Ok((8, 4)));
+
+        let err = pd.expect(ValueType::String).unwrap_err();
+        assert_eq!(err.to_string(), "expected ValueTypeString, got ValueTypeI32");
+
+        let err = PackedData(42 << 56).expect(ValueType::Byte).unwrap_err();
+        assert_eq!(err.to_string(), "expected ValueTypeByte, got unknown type 42");
+    }
+}
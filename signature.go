@@ -0,0 +1,119 @@
+package wasify
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/wasify-io/wasify-go/internal/wasmbin"
+)
+
+// SignatureSectionName is the name of the custom section which carries an embedded module signature.
+//
+// The signature in this section covers the wasm binary with the section itself removed.
+const SignatureSectionName = "wasify.signature"
+
+// TrustStore holds the public keys of vendors whose modules are trusted.
+//
+// When RuntimeConfig.TrustStore is set, every module must be signed by one of its keys,
+// either with a detached signature in Wasm.Signature or with a signature embedded
+// into the binary's SignatureSectionName custom section.
+//
+// The zero value is an empty TrustStore. It's safe for concurrent use, so keys can be added
+// while modules are loaded.
+type TrustStore struct {
+	mu   sync.RWMutex
+	keys map[string]ed25519.PublicKey
+}
+
+// NewTrustStore creates an empty TrustStore.
+func NewTrustStore() *TrustStore {
+	return &TrustStore{keys: make(map[string]ed25519.PublicKey)}
+}
+
+// AddEd25519Key trusts modules signed by the private key belonging to the public key.
+// The name identifies the key in logs and errors, e.g. the vendor name.
+func (ts *TrustStore) AddEd25519Key(name string, key ed25519.PublicKey) error {
+
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid ed25519 public key %s, expected %d bytes, got %d", name, ed25519.PublicKeySize, len(key))
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.keys == nil {
+		ts.keys = make(map[string]ed25519.PublicKey)
+	}
+	ts.keys[name] = key
+
+	return nil
+}
+
+// Verify checks the detached signature if provided, otherwise the signature embedded into the binary.
+// It returns the name of the trusted key which produced the signature.
+func (ts *TrustStore) Verify(wasm Wasm) (string, error) {
+
+	message, signature := wasm.Binary, wasm.Signature
+
+	if len(signature) == 0 {
+		embedded, ok, err := wasmbin.CustomSection(wasm.Binary, SignatureSectionName)
+		if err != nil {
+			return "", errors.Join(errors.New("can't read embedded signature"), err)
+		}
+		if !ok {
+			return "", errors.New("module is not signed")
+		}
+
+		message, err = wasmbin.RemoveCustomSection(wasm.Binary, SignatureSectionName)
+		if err != nil {
+			return "", errors.Join(errors.New("can't read embedded signature"), err)
+		}
+		signature = embedded
+	}
+
+	if len(signature) != ed25519.SignatureSize {
+		return "", fmt.Errorf("invalid ed25519 signature size, expected %d bytes, got %d", ed25519.SignatureSize, len(signature))
+	}
+
+	ts.mu.RLock()
+	keys := make(map[string]ed25519.PublicKey, len(ts.keys))
+	for name, key := range ts.keys {
+		keys[name] = key
+	}
+	ts.mu.RUnlock()
+
+	// Iterate keys in a stable order, so the same key is reported if several keys are equal.
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if ed25519.Verify(keys[name], message, signature) {
+			return name, nil
+		}
+	}
+
+	return "", errors.New("module signature is not signed by a trusted key")
+}
+
+// SignWasm creates a detached ed25519 signature of the wasm binary, to be passed in Wasm.Signature.
+func SignWasm(binary []byte, key ed25519.PrivateKey) []byte {
+	return ed25519.Sign(key, binary)
+}
+
+// EmbedSignature signs the wasm binary and returns a copy of it with the signature
+// stored in the SignatureSectionName custom section. An existing signature section is replaced.
+func EmbedSignature(binary []byte, key ed25519.PrivateKey) ([]byte, error) {
+
+	unsigned, err := wasmbin.RemoveCustomSection(binary, SignatureSectionName)
+	if err != nil {
+		return nil, errors.Join(errors.New("can't embed signature"), err)
+	}
+
+	return wasmbin.AppendCustomSection(unsigned, SignatureSectionName, ed25519.Sign(key, unsigned)), nil
+}
@@ -0,0 +1,120 @@
+package wasify_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go"
+)
+
+func TestTrustStore(t *testing.T) {
+
+	vendorPub, vendorKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	_, unknownKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	trustStore := wasify.NewTrustStore()
+	assert.NoError(t, trustStore.AddEd25519Key("vendor", vendorPub))
+	assert.Error(t, trustStore.AddEd25519Key("invalid", []byte("short")))
+
+	binary := wasm_hostAllAvailableTypes
+
+	t.Run("detached signature", func(t *testing.T) {
+		key, err := trustStore.Verify(wasify.Wasm{Binary: binary, Signature: wasify.SignWasm(binary, vendorKey)})
+		assert.NoError(t, err)
+		assert.Equal(t, "vendor", key)
+
+		_, err = trustStore.Verify(wasify.Wasm{Binary: binary, Signature: wasify.SignWasm(binary, unknownKey)})
+		assert.Error(t, err)
+	})
+
+	t.Run("embedded signature", func(t *testing.T) {
+		signed, err := wasify.EmbedSignature(binary, vendorKey)
+		assert.NoError(t, err)
+
+		key, err := trustStore.Verify(wasify.Wasm{Binary: signed})
+		assert.NoError(t, err)
+		assert.Equal(t, "vendor", key)
+
+		// Re-signing replaces the existing signature section.
+		resigned, err := wasify.EmbedSignature(signed, unknownKey)
+		assert.NoError(t, err)
+		assert.Len(t, resigned, len(signed))
+
+		_, err = trustStore.Verify(wasify.Wasm{Binary: resigned})
+		assert.Error(t, err)
+	})
+
+	t.Run("zero value", func(t *testing.T) {
+		var trustStore wasify.TrustStore
+
+		_, err := trustStore.Verify(wasify.Wasm{Binary: binary, Signature: wasify.SignWasm(binary, vendorKey)})
+		assert.Error(t, err)
+
+		assert.NoError(t, trustStore.AddEd25519Key("vendor", vendorPub))
+
+		key, err := trustStore.Verify(wasify.Wasm{Binary: binary, Signature: wasify.SignWasm(binary, vendorKey)})
+		assert.NoError(t, err)
+		assert.Equal(t, "vendor", key)
+	})
+
+	t.Run("concurrent use", func(t *testing.T) {
+		trustStore := wasify.NewTrustStore()
+		signature := wasify.SignWasm(binary, vendorKey)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, trustStore.AddEd25519Key("vendor", vendorPub))
+			}()
+			go func() {
+				defer wg.Done()
+				trustStore.Verify(wasify.Wasm{Binary: binary, Signature: signature})
+			}()
+		}
+		wg.Wait()
+
+		key, err := trustStore.Verify(wasify.Wasm{Binary: binary, Signature: signature})
+		assert.NoError(t, err)
+		assert.Equal(t, "vendor", key)
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		_, err := trustStore.Verify(wasify.Wasm{Binary: binary})
+		assert.ErrorContains(t, err, "not signed")
+	})
+
+	t.Run("runtime requires signature", func(t *testing.T) {
+
+		ctx := context.Background()
+
+		runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+			Runtime:     wasify.RuntimeWazero,
+			LogSeverity: wasify.LogError,
+			TrustStore:  trustStore,
+		})
+		assert.NoError(t, err)
+
+		defer func() {
+			err = runtime.Close(ctx)
+			assert.NoError(t, err)
+		}()
+
+		_, err = runtime.CompileModule(ctx, wasify.Wasm{Binary: binary})
+		assert.Error(t, err)
+
+		signed, err := wasify.EmbedSignature(binary, vendorKey)
+		assert.NoError(t, err)
+
+		compiled, err := runtime.CompileModule(ctx, wasify.Wasm{Binary: signed})
+		assert.NoError(t, err)
+		assert.NoError(t, compiled.Close(ctx))
+	})
+}
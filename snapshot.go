@@ -0,0 +1,195 @@
+package wasify
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/wasify-io/wasify-go/internal/utils"
+)
+
+// snapshotMagic identifies serialized snapshots, followed by snapshotVersion.
+var snapshotMagic = []byte("WASIFYSN")
+
+// Version 2 added the topics, snapshots of version 1 are still read.
+const snapshotVersion = 2
+
+// snapshotGlobalPrefix is the export name prefix of globals exported by wasify, so snapshots can capture them.
+const snapshotGlobalPrefix = "wasify.global."
+
+// Snapshot is the state of a module instance: its linear memory, mutable globals and subscriptions to events.
+// It's taken with Module.Snapshot and restored with ModuleConfig.Snapshot.
+//
+// For fast cold starts, run the module's initialization once, take a snapshot,
+// and instantiate new modules from it, which skips the start functions of the module.
+//
+// Use MarshalBinary and UnmarshalBinary to persist a snapshot, e.g. to disk.
+type Snapshot struct {
+	// ModuleHash is the SHA-256 hash of the wasm binary the snapshot was taken from,
+	// a snapshot can only be restored into a module of the same binary.
+	ModuleHash string
+
+	// Globals are the values of the mutable globals, by export name.
+	Globals []SnapshotGlobal
+
+	// Memory is the content of the linear memory.
+	Memory []byte
+
+	// Topics are the topics of the events the guest subscribed to, see EventBus.
+	Topics []string
+}
+
+// SnapshotGlobal is the value of a mutable global.
+type SnapshotGlobal struct {
+	Name  string
+	Value uint64
+}
+
+// MarshalBinary encodes the snapshot in a versioned binary format.
+func (s *Snapshot) MarshalBinary() ([]byte, error) {
+
+	buf := make([]byte, 0, len(snapshotMagic)+1+len(s.ModuleHash)+len(s.Memory)+len(s.Globals)*16+3*binary.MaxVarintLen64)
+
+	buf = append(buf, snapshotMagic...)
+	buf = append(buf, snapshotVersion)
+
+	buf = appendSnapshotBytes(buf, []byte(s.ModuleHash))
+
+	buf = binary.AppendUvarint(buf, uint64(len(s.Globals)))
+	for _, g := range s.Globals {
+		buf = appendSnapshotBytes(buf, []byte(g.Name))
+		buf = binary.LittleEndian.AppendUint64(buf, g.Value)
+	}
+
+	buf = appendSnapshotBytes(buf, s.Memory)
+
+	buf = binary.AppendUvarint(buf, uint64(len(s.Topics)))
+	for _, topic := range s.Topics {
+		buf = appendSnapshotBytes(buf, []byte(topic))
+	}
+
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a snapshot encoded by MarshalBinary.
+func (s *Snapshot) UnmarshalBinary(data []byte) error {
+
+	if !bytes.HasPrefix(data, snapshotMagic) {
+		return errors.New("invalid snapshot, magic number mismatch")
+	}
+
+	r := bytes.NewReader(data[len(snapshotMagic):])
+
+	version, err := r.ReadByte()
+	if err != nil {
+		return errors.Join(errors.New("invalid snapshot, can't read version"), err)
+	}
+	if version < 1 || version > snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d, expected 1 to %d", version, snapshotVersion)
+	}
+
+	hash, err := readSnapshotBytes(r)
+	if err != nil {
+		return errors.Join(errors.New("invalid snapshot, can't read module hash"), err)
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return errors.Join(errors.New("invalid snapshot, can't read globals"), err)
+	}
+	if count > uint64(r.Len()) {
+		return fmt.Errorf("invalid snapshot, %d globals exceed the snapshot size", count)
+	}
+
+	globals := make([]SnapshotGlobal, count)
+	for i := range globals {
+		name, err := readSnapshotBytes(r)
+		if err != nil {
+			return errors.Join(fmt.Errorf("invalid snapshot, can't read global %d", i), err)
+		}
+
+		var value [8]byte
+		if _, err := io.ReadFull(r, value[:]); err != nil {
+			return errors.Join(fmt.Errorf("invalid snapshot, can't read global %s", name), err)
+		}
+
+		globals[i] = SnapshotGlobal{Name: string(name), Value: binary.LittleEndian.Uint64(value[:])}
+	}
+
+	memory, err := readSnapshotBytes(r)
+	if err != nil {
+		return errors.Join(errors.New("invalid snapshot, can't read memory"), err)
+	}
+
+	var topics []string
+	if version >= 2 {
+		count, err := binary.ReadUvarint(r)
+		if err != nil {
+			return errors.Join(errors.New("invalid snapshot, can't read topics"), err)
+		}
+		if count > uint64(r.Len()) {
+			return fmt.Errorf("invalid snapshot, %d topics exceed the snapshot size", count)
+		}
+
+		for i := uint64(0); i < count; i++ {
+			topic, err := readSnapshotBytes(r)
+			if err != nil {
+				return errors.Join(fmt.Errorf("invalid snapshot, can't read topic %d", i), err)
+			}
+			topics = append(topics, string(topic))
+		}
+	}
+
+	if r.Len() != 0 {
+		return fmt.Errorf("invalid snapshot, %d unexpected trailing bytes", r.Len())
+	}
+
+	*s = Snapshot{
+		ModuleHash: string(hash),
+		Globals:    globals,
+		Memory:     memory,
+		Topics:     topics,
+	}
+
+	return nil
+}
+
+func appendSnapshotBytes(buf []byte, data []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+func readSnapshotBytes(r *bytes.Reader) ([]byte, error) {
+
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if size > uint64(r.Len()) {
+		return nil, fmt.Errorf("size %d exceeds the remaining %d bytes", size, r.Len())
+	}
+
+	data := make([]byte, size)
+	_, err = io.ReadFull(r, data)
+
+	return data, err
+}
+
+// checkSnapshotHash checks the snapshot has been taken from the wasm binary.
+func checkSnapshotHash(snapshot *Snapshot, bin []byte) error {
+
+	hash, err := utils.CalculateHash(bin)
+	if err != nil {
+		return errors.Join(errors.New("can't calculate the hash"), err)
+	}
+
+	err = utils.CompareHashes(snapshot.ModuleHash, hash)
+	if err != nil {
+		return errors.Join(errors.New("snapshot has been taken from a different wasm binary"), err)
+	}
+
+	return nil
+}
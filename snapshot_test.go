@@ -0,0 +1,197 @@
+package wasify_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go"
+)
+
+func TestSnapshot(t *testing.T) {
+
+	ctx := context.Background()
+
+	newModule := func(t *testing.T, snapshot *wasify.Snapshot) (wasify.Module, error) {
+
+		runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+			Runtime:     wasify.RuntimeWazero,
+			LogSeverity: wasify.LogError,
+		})
+		assert.NoError(t, err)
+
+		t.Cleanup(func() {
+			err := runtime.Close(ctx)
+			assert.NoError(t, err)
+		})
+
+		return runtime.NewModule(ctx, &wasify.ModuleConfig{
+			Namespace: "guest_all_available_types",
+			Wasm: wasify.Wasm{
+				Binary: wasm_guestAllAvailableTypes,
+			},
+			EnableSnapshots: true,
+			Snapshot:        snapshot,
+		})
+	}
+
+	module, err := newModule(t, nil)
+	assert.NoError(t, err)
+
+	// Initialize some state in the guest memory.
+	offset, err := module.Memory().Malloc(5)
+	assert.NoError(t, err)
+	err = module.Memory().WriteString(offset, "state")
+	assert.NoError(t, err)
+
+	snapshot, err := module.Snapshot()
+	assert.NoError(t, err)
+	assert.Len(t, snapshot.Memory, int(module.Memory().Size()))
+	assert.NotEmpty(t, snapshot.Globals, "the stack pointer of the guest must be captured")
+
+	data, err := snapshot.MarshalBinary()
+	assert.NoError(t, err)
+
+	t.Run("restore", func(t *testing.T) {
+
+		restored := new(wasify.Snapshot)
+		err := restored.UnmarshalBinary(data)
+		assert.NoError(t, err)
+		assert.Equal(t, snapshot, restored)
+
+		module, err := newModule(t, restored)
+		assert.NoError(t, err)
+
+		state, err := module.Memory().ReadString(offset, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, "state", state)
+
+		// The allocator state has been restored too, so new allocations don't overwrite the state.
+		next, err := module.Memory().Malloc(5)
+		assert.NoError(t, err)
+		assert.NotEqual(t, offset, next)
+
+		_, err = module.GuestFunction(ctx, "guestTest").Invoke(
+			[]byte("bytes!"),
+			byte(1),
+			uint32(32),
+			uint64(64),
+			float32(32.0),
+			float64(64.01),
+			"Wasify",
+		)
+		assert.NoError(t, err)
+	})
+
+	t.Run("different binary", func(t *testing.T) {
+
+		_, err := newModule(t, &wasify.Snapshot{ModuleHash: "0000"})
+		assert.ErrorContains(t, err, "snapshot has been taken from a different wasm binary")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+
+		runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+			Runtime:     wasify.RuntimeWazero,
+			LogSeverity: wasify.LogError,
+		})
+		assert.NoError(t, err)
+		defer runtime.Close(ctx)
+
+		module, err := runtime.NewModule(ctx, &wasify.ModuleConfig{
+			Namespace: "guest_all_available_types",
+			Wasm: wasify.Wasm{
+				Binary: wasm_guestAllAvailableTypes,
+			},
+		})
+		assert.NoError(t, err)
+		defer module.Close(ctx)
+
+		_, err = module.Snapshot()
+		assert.ErrorContains(t, err, "ModuleConfig.EnableSnapshots")
+
+		err = module.Reset(ctx)
+		assert.ErrorContains(t, err, "ModuleConfig.EnableReset")
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+
+		err := new(wasify.Snapshot).UnmarshalBinary([]byte("invalid"))
+		assert.ErrorContains(t, err, "magic number mismatch")
+
+		// The snapshot ends with the memory and the number of topics, which is 0.
+		err = new(wasify.Snapshot).UnmarshalBinary(data[:len(data)-2])
+		assert.ErrorContains(t, err, "can't read memory")
+
+		err = new(wasify.Snapshot).UnmarshalBinary(data[:len(data)-1])
+		assert.ErrorContains(t, err, "can't read topics")
+	})
+
+	t.Run("version 1", func(t *testing.T) {
+
+		// Version 1 has no topics, its version follows the 8 bytes magic number.
+		v1 := bytes.Clone(data[:len(data)-1])
+		v1[8] = 1
+
+		restored := new(wasify.Snapshot)
+		err := restored.UnmarshalBinary(v1)
+		assert.NoError(t, err)
+		assert.Equal(t, snapshot, restored)
+	})
+}
+
+func TestReset(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+		Runtime:     wasify.RuntimeWazero,
+		LogSeverity: wasify.LogError,
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	module, err := runtime.NewModule(ctx, &wasify.ModuleConfig{
+		Namespace: "guest_all_available_types",
+		Wasm: wasify.Wasm{
+			Binary: wasm_guestAllAvailableTypes,
+		},
+		EnableReset: true,
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = module.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	memory := module.Memory()
+
+	offset, err := memory.Malloc(6)
+	assert.NoError(t, err)
+	err = memory.WriteString(offset, "tenant")
+	assert.NoError(t, err)
+
+	// Leak allocations big enough to grow the memory.
+	for i := 0; i < 4; i++ {
+		_, err = memory.Malloc(64 * 1024)
+		assert.NoError(t, err)
+	}
+
+	err = module.Reset(ctx)
+	assert.NoError(t, err)
+
+	state, err := memory.ReadBytes(offset, 6)
+	assert.NoError(t, err)
+	assert.Equal(t, make([]byte, 6), state)
+
+	// The allocator starts over, as right after instantiation.
+	next, err := memory.Malloc(6)
+	assert.NoError(t, err)
+	assert.Equal(t, offset, next)
+}
@@ -0,0 +1,156 @@
+package wasify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/wasify-io/wasify-go/internal/utils"
+)
+
+// wasmPageSize is the size of a page of linear memory.
+const wasmPageSize = 65536
+
+// Snapshot captures the linear memory and the mutable globals of the module, and the topics its guest subscribed to.
+//
+// NOTE: Take snapshots between calls, not while a guest function of the module is running.
+// The module must have been created with ModuleConfig.EnableSnapshots.
+func (m *wazeroModule) Snapshot() (*Snapshot, error) {
+
+	if !m.exportGlobals() {
+		err := errors.New("can't take a snapshot, the module has been created without ModuleConfig.EnableSnapshots")
+		m.log.Error(err.Error())
+		return nil, err
+	}
+
+	_, end := m.events.enter(context.Background())
+	defer end()
+
+	hash, err := utils.CalculateHash(m.Wasm.Binary)
+	if err != nil {
+		return nil, errors.Join(errors.New("can't calculate the hash"), err)
+	}
+
+	snapshot, err := m.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot.ModuleHash = hash
+
+	m.log.Debug("module snapshot has been taken", "memory size", len(snapshot.Memory), "globals", len(snapshot.Globals))
+
+	return snapshot, nil
+}
+
+// Reset restores the linear memory and the globals of the module to their state right after instantiation,
+// so the instance can be reused without leaking the state or the allocations of previous calls.
+// The module isn't recompiled nor re-instantiated, and its start functions don't run again.
+//
+// NOTE: Reset between calls, not while a guest function of the module is running.
+// The subscriptions to events are reset too. Pending futures of async host functions are dropped.
+// Host-side resources, e.g. files opened through WASI, are not reset.
+// The module must have been created with ModuleConfig.EnableReset.
+func (m *wazeroModule) Reset(ctx context.Context) error {
+
+	if m.pristine == nil {
+		err := errors.New("can't reset module, it has been created without ModuleConfig.EnableReset")
+		m.log.ErrorContext(ctx, err.Error())
+		return err
+	}
+
+	_, end := m.events.enter(ctx)
+	defer end()
+
+	err := restoreSnapshot(m.mod, m.pristine)
+	if err != nil {
+		err = errors.Join(errors.New("can't reset module"), err)
+		m.log.ErrorContext(ctx, err.Error())
+		return err
+	}
+
+	// The handles of the futures were in the memory which has just been restored.
+	m.futures.clear()
+
+	// The handlers of the guest are back to the ones registered at instantiation.
+	m.events.setSubscriptions(m.pristine.Topics)
+
+	m.log.DebugContext(ctx, "module has been reset")
+
+	return nil
+}
+
+// snapshot captures the memory, the mutable globals and the subscriptions of the module instance.
+func (m *wazeroModule) snapshot() (*Snapshot, error) {
+
+	snapshot := &Snapshot{
+		Topics: m.events.subscriptions(),
+	}
+
+	for _, name := range m.globals {
+		global, ok := m.mod.ExportedGlobal(name).(api.MutableGlobal)
+		if !ok {
+			// Immutable globals are restored by the instantiation itself.
+			continue
+		}
+
+		snapshot.Globals = append(snapshot.Globals, SnapshotGlobal{Name: name, Value: global.Get()})
+	}
+
+	if memory := m.mod.Memory(); memory != nil {
+		data, ok := memory.Read(0, memory.Size())
+		if !ok {
+			err := errors.New("can't read memory")
+			m.log.Error(err.Error())
+			return nil, err
+		}
+
+		// Read returns a view of the memory, which keeps changing.
+		snapshot.Memory = append([]byte(nil), data...)
+	}
+
+	return snapshot, nil
+}
+
+// restoreSnapshot writes the memory and the globals of the snapshot into the module instance.
+// If the memory of the instance is bigger than the snapshot, the remaining memory is zeroed.
+func restoreSnapshot(mod api.Module, snapshot *Snapshot) error {
+
+	memory := mod.Memory()
+
+	if memory == nil {
+		if len(snapshot.Memory) > 0 {
+			return errors.New("snapshot has memory, but the module doesn't")
+		}
+	} else {
+		size := uint32(len(snapshot.Memory))
+
+		if size > memory.Size() {
+			pages := (size - memory.Size() + wasmPageSize - 1) / wasmPageSize
+			if _, ok := memory.Grow(pages); !ok {
+				return fmt.Errorf("can't grow memory by %d pages to the snapshot size %d", pages, size)
+			}
+		}
+
+		if !memory.Write(0, snapshot.Memory) {
+			return fmt.Errorf("can't write snapshot memory of size %d", size)
+		}
+
+		// Memory can't shrink, clear what has been allocated after the snapshot.
+		if rest, ok := memory.Read(size, memory.Size()-size); ok {
+			clear(rest)
+		}
+	}
+
+	for _, g := range snapshot.Globals {
+		global, ok := mod.ExportedGlobal(g.Name).(api.MutableGlobal)
+		if !ok {
+			return fmt.Errorf("snapshot global %s is not a mutable global of the module", g.Name)
+		}
+
+		global.Set(g.Value)
+	}
+
+	return nil
+}
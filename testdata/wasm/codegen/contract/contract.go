@@ -0,0 +1,21 @@
+// Package contract is the contract of the codegen fixture, the host and guest code is generated from it.
+package contract
+
+import "context"
+
+//go:generate go run github.com/wasify-io/wasify-go/cmd/wasify-gen -namespace codegen -host ../host/wasify_gen.go -guest ../guest/wasify_gen.go contract.go
+
+type Celsius float64
+
+type Host interface {
+	Greet(ctx context.Context, name string, times uint32) (string, error)
+	Record(data []byte) error
+	UnixTime() (uint64, error)
+}
+
+type Guest interface {
+	Fahrenheit(c Celsius) (float64, Celsius, error)
+	Welcome(name string) (string, error)
+	Store(data []byte) error
+	Reverse(data []byte) ([]byte, error)
+}
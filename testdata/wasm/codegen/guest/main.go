@@ -0,0 +1,54 @@
+// Built with Go, without cgo nor TinyGo:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -ldflags="-s -w" -o main.wasm .
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/wasify-io/wasify-go/testdata/wasm/codegen/contract"
+)
+
+func main() {}
+
+func init() {
+	ExportGuest(guest{})
+}
+
+// guest implements contract.Guest, calling the host through HostClient.
+type guest struct {
+	host HostClient
+}
+
+func (guest) Fahrenheit(c contract.Celsius) (float64, contract.Celsius, error) {
+	return float64(c)*9/5 + 32, c, nil
+}
+
+func (g guest) Welcome(name string) (string, error) {
+
+	greeting, err := g.host.Greet(context.Background(), name, 2)
+	if err != nil {
+		return "", err
+	}
+
+	now, err := g.host.UnixTime()
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s(%d)", greeting, now), nil
+}
+
+func (g guest) Store(data []byte) error {
+	return g.host.Record(data)
+}
+
+func (guest) Reverse(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no data")
+	}
+	slices.Reverse(data)
+	return data, nil
+}
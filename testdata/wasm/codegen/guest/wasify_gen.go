@@ -0,0 +1,54 @@
+// Code generated by wasify-gen from contract.go. DO NOT EDIT.
+
+package main
+
+import (
+	"context"
+
+	"github.com/wasify-io/wasify-go/mdk"
+
+	contract "github.com/wasify-io/wasify-go/testdata/wasm/codegen/contract"
+)
+
+//go:wasmimport codegen greet
+func _importGreet(mdk.PackedData, mdk.PackedData) mdk.MultiPackedData
+
+var importGreet = mdk.Import[func(string, uint32) (string, error)](_importGreet)
+
+//go:wasmimport codegen record
+func _importRecord(mdk.PackedData)
+
+var importRecord = mdk.Import[func([]byte) error](_importRecord)
+
+//go:wasmimport codegen unix_time
+func _importUnixTime() mdk.MultiPackedData
+
+var importUnixTime = mdk.Import[func() (uint64, error)](_importUnixTime)
+
+// HostClient calls the host functions of contract.Host.
+type HostClient struct{}
+
+var _ contract.Host = HostClient{}
+
+// Greet calls the greet host function.
+func (HostClient) Greet(_ context.Context, name string, times uint32) (string, error) {
+	return importGreet(name, times)
+}
+
+// Record calls the record host function.
+func (HostClient) Record(data []byte) error {
+	return importRecord(data)
+}
+
+// UnixTime calls the unix_time host function.
+func (HostClient) UnixTime() (uint64, error) {
+	return importUnixTime()
+}
+
+// ExportGuest exports the methods of impl as the guest functions of contract.Guest, call it from an init function.
+func ExportGuest(impl contract.Guest) {
+	mdk.Export("fahrenheit", impl.Fahrenheit)
+	mdk.Export("welcome", impl.Welcome)
+	mdk.Export("store", impl.Store)
+	mdk.Export("reverse", impl.Reverse)
+}
@@ -0,0 +1,171 @@
+// Code generated by wasify-gen from contract.go. DO NOT EDIT.
+
+package host
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/wasify-io/wasify-go"
+
+	contract "github.com/wasify-io/wasify-go/testdata/wasm/codegen/contract"
+)
+
+// Namespace is the namespace the guest imports the host functions from, use it as the ModuleConfig.Namespace of the module.
+const Namespace = "codegen"
+
+// HostFunctions returns the host functions of contract.Host implemented by impl, use them as the ModuleConfig.HostFunctions of the module.
+// An error returned by impl traps the guest which called the host function, see wasify.Trap.
+func HostFunctions(impl contract.Host) []wasify.HostFunction {
+	return []wasify.HostFunction{
+		{
+			Name: "greet",
+			Callback: func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {
+				p0, err := m.Memory.ReadStringPack(params[0])
+				if err != nil {
+					panic(err)
+				}
+				p1, err := m.Memory.ReadUint32Pack(params[1])
+				if err != nil {
+					panic(err)
+				}
+				r0, err := impl.Greet(ctx, p0, p1)
+				if err != nil {
+					panic(err)
+				}
+				return m.Memory.WriteMultiPack(m.Memory.WriteStringPack(r0))
+			},
+			Params:  []wasify.ValueType{wasify.ValueTypeString, wasify.ValueTypeI32},
+			Results: []wasify.ValueType{wasify.ValueTypeString},
+		},
+		{
+			Name: "record",
+			Callback: func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {
+				p0, err := m.Memory.ReadBytesPack(params[0])
+				if err != nil {
+					panic(err)
+				}
+				if err := impl.Record(p0); err != nil {
+					panic(err)
+				}
+				return 0
+			},
+			Params: []wasify.ValueType{wasify.ValueTypeBytes},
+		},
+		{
+			Name: "unix_time",
+			Callback: func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {
+				r0, err := impl.UnixTime()
+				if err != nil {
+					panic(err)
+				}
+				return m.Memory.WriteMultiPack(m.Memory.WriteUint64Pack(r0))
+			},
+			Results: []wasify.ValueType{wasify.ValueTypeI64},
+		},
+	}
+}
+
+// GuestClient invokes the guest functions of contract.Guest exported by Module.
+type GuestClient struct {
+	Module wasify.Module
+}
+
+// Fahrenheit invokes the fahrenheit guest function.
+func (g GuestClient) Fahrenheit(ctx context.Context, c contract.Celsius) (r0 float64, r1 contract.Celsius, err error) {
+	values, err := invokeGuest(ctx, g.Module, "fahrenheit", 2, float64(c))
+	if err != nil {
+		return
+	}
+	v0, err := guestResult[float64](values, 0)
+	if err != nil {
+		return
+	}
+	v1, err := guestResult[float64](values, 1)
+	if err != nil {
+		return
+	}
+	return v0, contract.Celsius(v1), nil
+}
+
+// Welcome invokes the welcome guest function.
+func (g GuestClient) Welcome(ctx context.Context, name string) (r0 string, err error) {
+	values, err := invokeGuest(ctx, g.Module, "welcome", 1, name)
+	if err != nil {
+		return
+	}
+	v0, err := guestResult[string](values, 0)
+	if err != nil {
+		return
+	}
+	return v0, nil
+}
+
+// Store invokes the store guest function.
+func (g GuestClient) Store(ctx context.Context, data []byte) (err error) {
+	_, err = invokeGuest(ctx, g.Module, "store", 0, data)
+	return err
+}
+
+// Reverse invokes the reverse guest function.
+func (g GuestClient) Reverse(ctx context.Context, data []byte) (r0 []byte, err error) {
+	values, err := invokeGuest(ctx, g.Module, "reverse", 1, data)
+	if err != nil {
+		return
+	}
+	v0, err := guestResult[[]byte](values, 0)
+	if err != nil {
+		return
+	}
+	return v0, nil
+}
+
+// invokeGuest invokes a guest function and reads its results.
+func invokeGuest(ctx context.Context, module wasify.Module, name string, results int, params ...any) ([]any, error) {
+
+	res, err := module.GuestFunction(ctx, name).Invoke(params...)
+	if err != nil {
+		return nil, err
+	}
+
+	if results == 0 {
+		return nil, nil
+	}
+
+	pds, err := res.ReadPacks()
+	if err != nil {
+		return nil, err
+	}
+	defer module.Memory().FreePack(pds...)
+
+	if len(pds) != results {
+		return nil, fmt.Errorf("guest function %s returned %d results, expected %d", name, len(pds), results)
+	}
+
+	values := make([]any, len(pds))
+	for i, pd := range pds {
+		values[i], _, _, err = module.Memory().ReadAnyPack(pd)
+		if err != nil {
+			return nil, err
+		}
+
+		// The packs are freed, keep a copy of the bytes.
+		if b, ok := values[i].([]byte); ok {
+			values[i] = bytes.Clone(b)
+		}
+	}
+
+	return values, nil
+}
+
+// guestResult returns the result i of a guest function as a T.
+func guestResult[T any](values []any, i int) (T, error) {
+
+	v, ok := values[i].(T)
+	if !ok {
+		return v, fmt.Errorf("result %d is a %T, expected a %T", i, values[i], v)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,91 @@
+// Conformance fixture of the AssemblyScript SDK, see the Conformance section of PROTOCOL.md.
+// The Go host tests run the prebuilt main.wasm:
+//
+//	npm install
+//	npm run build
+
+import {
+  LogLevel,
+  MultiPackedData,
+  PackedData,
+  ValueType,
+  checkPack,
+  freePack,
+  freePacks,
+  log,
+  logError,
+  readBytePack,
+  readBytesPack,
+  readF32Pack,
+  readF64Pack,
+  readStringPack,
+  readU32Pack,
+  readU64Pack,
+  writeBytePack,
+  writeBytesPack,
+  writeF32Pack,
+  writeF64Pack,
+  writeMultiPack,
+  writeStringPack,
+  writeU32Pack,
+  writeU64Pack,
+} from "wasify-mdk/assembly";
+
+export { wasify_malloc, wasify_free, wasify_protocol_version } from "wasify-mdk/assembly";
+
+/** Reads a value of every type and returns them back. */
+export function echo(
+  bytesPd: PackedData,
+  bytePd: PackedData,
+  i32Pd: PackedData,
+  i64Pd: PackedData,
+  f32Pd: PackedData,
+  f64Pd: PackedData,
+  stringPd: PackedData,
+): MultiPackedData {
+  const v1 = readBytesPack(bytesPd);
+  const v2 = readBytePack(bytePd);
+  const v3 = readU32Pack(i32Pd);
+  const v4 = readU64Pack(i64Pd);
+  const v5 = readF32Pack(f32Pd);
+  const v6 = readF64Pack(f64Pd);
+  const v7 = readStringPack(stringPd);
+
+  freePacks([bytesPd, bytePd, i32Pd, i64Pd, f32Pd, f64Pd, stringPd]);
+
+  return writeMultiPack([
+    writeBytesPack(v1),
+    writeBytePack(v2),
+    writeU32Pack(v3),
+    writeU64Pack(v4),
+    writeF32Pack(v5),
+    writeF64Pack(v6),
+    writeStringPack(v7),
+  ]);
+}
+
+/** Returns the string, or the error of reading the pack as a string. */
+export function read_string(pd: PackedData): MultiPackedData {
+  const err = checkPack(pd, ValueType.String);
+  const result = err !== null ? err! : readStringPack(pd);
+
+  freePack(pd);
+
+  return writeMultiPack([writeStringPack(result)]);
+}
+
+/** Logs the message at the level. */
+export function log_message(msg: PackedData, level: PackedData): void {
+  const msgValue = readStringPack(msg);
+  const levelValue = readBytePack(level);
+
+  freePacks([msg, level]);
+
+  log(<LogLevel>levelValue, msgValue);
+}
+
+/** Logs aborts with the host logger instead of importing env.abort, see asconfig.json. */
+function handleAbort(message: string | null, fileName: string | null, line: u32, column: u32): void {
+  logError(message !== null ? message! : "abort");
+  unreachable();
+}
@@ -0,0 +1,36 @@
+#!/bin/sh
+# Builds the conformance fixtures of the SDKs into their main.wasm, all of them or the ones named:
+#
+#	./build.sh rust
+#
+# Once a fixture's main.wasm is committed, it moves from conformance_sdk_test.go to the untagged fixtures of conformance_test.go.
+set -e
+
+cd "$(dirname "$0")"
+
+if [ $# -eq 0 ]; then
+	set -- go rust c assemblyscript
+fi
+
+for fixture in "$@"; do
+	echo "building $fixture"
+
+	case $fixture in
+	go)
+		(cd go && GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -ldflags="-s -w" -o main.wasm .)
+		;;
+	rust)
+		(cd rust && cargo build --release --target wasm32-unknown-unknown && cp target/wasm32-unknown-unknown/release/conformance.wasm main.wasm)
+		;;
+	c)
+		(cd c && clang --target=wasm32-wasi -mexec-model=reactor -O2 -I../../../../sdk/c -o main.wasm main.c)
+		;;
+	assemblyscript)
+		(cd assemblyscript && npm install && npm run build)
+		;;
+	*)
+		echo "unknown fixture $fixture" >&2
+		exit 1
+		;;
+	esac
+done
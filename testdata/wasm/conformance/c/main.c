@@ -0,0 +1,94 @@
+/*
+ * Conformance fixture of the C SDK, see the Conformance section of PROTOCOL.md.
+ * Built with the wasi-sdk, the Go host tests run the prebuilt main.wasm:
+ *
+ *	clang --target=wasm32-wasi -mexec-model=reactor -O2 -I../../../../sdk/c -o main.wasm main.c
+ */
+
+#include <stdio.h>
+
+#define WASIFY_IMPLEMENTATION
+#include "wasify.h"
+
+/* Reads a value of every type and returns them back. */
+WASIFY_EXPORT("echo")
+wasify_multi_packed_data echo(wasify_packed_data bytes, wasify_packed_data byte, wasify_packed_data i32,
+                              wasify_packed_data i64, wasify_packed_data f32, wasify_packed_data f64,
+                              wasify_packed_data string) {
+	const uint8_t *v1;
+	uint32_t v1_size;
+	uint8_t v2;
+	uint32_t v3;
+	uint64_t v4;
+	float v5;
+	double v6;
+	const char *v7;
+	uint32_t v7_size;
+
+	if (wasify_read_bytes_pack(bytes, &v1, &v1_size) != WASIFY_OK ||
+	    wasify_read_byte_pack(byte, &v2) != WASIFY_OK ||
+	    wasify_read_u32_pack(i32, &v3) != WASIFY_OK ||
+	    wasify_read_u64_pack(i64, &v4) != WASIFY_OK ||
+	    wasify_read_f32_pack(f32, &v5) != WASIFY_OK ||
+	    wasify_read_f64_pack(f64, &v6) != WASIFY_OK ||
+	    wasify_read_string_pack(string, &v7, &v7_size) != WASIFY_OK) {
+		wasify_log_error("can't read params");
+		return 0;
+	}
+
+	wasify_packed_data results[] = {
+		wasify_write_bytes_pack(v1, v1_size),
+		wasify_write_byte_pack(v2),
+		wasify_write_u32_pack(v3),
+		wasify_write_u64_pack(v4),
+		wasify_write_f32_pack(v5),
+		wasify_write_f64_pack(v6),
+		wasify_write_string_pack_n(v7, v7_size),
+	};
+
+	wasify_packed_data params[] = {bytes, byte, i32, i64, f32, f64, string};
+	for (size_t i = 0; i < sizeof(params) / sizeof(params[0]); i++) {
+		wasify_free_pack(params[i]);
+	}
+
+	return wasify_write_multi_pack(results, sizeof(results) / sizeof(results[0]));
+}
+
+/* Returns the string, or the error of reading the pack as a string. */
+WASIFY_EXPORT("read_string")
+wasify_multi_packed_data read_string(wasify_packed_data pd) {
+	const char *data;
+	uint32_t size;
+	wasify_packed_data result;
+
+	if (wasify_read_string_pack(pd, &data, &size) == WASIFY_OK) {
+		result = wasify_write_string_pack_n(data, size);
+	} else {
+		char msg[64];
+		snprintf(msg, sizeof(msg), "expected %s, got %s", wasify_value_type_name(WASIFY_VALUE_TYPE_STRING),
+		         wasify_value_type_name(wasify_pack_type(pd)));
+		result = wasify_write_string_pack(msg);
+	}
+
+	wasify_free_pack(pd);
+
+	return wasify_write_multi_pack(&result, 1);
+}
+
+/* Logs the message at the level. */
+WASIFY_EXPORT("log_message")
+void log_message(wasify_packed_data msg, wasify_packed_data level) {
+	const char *data;
+	uint32_t size;
+	uint8_t level_value;
+
+	if (wasify_read_string_pack(msg, &data, &size) != WASIFY_OK || wasify_read_byte_pack(level, &level_value) != WASIFY_OK) {
+		wasify_log_error("can't read params");
+		return;
+	}
+
+	wasify_log_n((wasify_log_level)level_value, data, size);
+
+	wasify_free_pack(msg);
+	wasify_free_pack(level);
+}
@@ -0,0 +1,67 @@
+// Conformance fixture of the Go mdk, see the Conformance section of PROTOCOL.md.
+// Built with Go, without cgo nor TinyGo:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -ldflags="-s -w" -o main.wasm .
+package main
+
+import (
+	"fmt"
+
+	"github.com/wasify-io/wasify-go/internal/types"
+	"github.com/wasify-io/wasify-go/mdk"
+)
+
+func main() {}
+
+// echo reads a value of every type and returns them back.
+//
+//go:wasmexport echo
+func echo(bytes, byte_, i32, i64, f32, f64, str mdk.PackedData) mdk.MultiPackedData {
+
+	res := mdk.WriteMultiPack(
+		mdk.WriteBytesPack(mdk.ReadBytesPack(bytes)),
+		mdk.WriteBytePack(mdk.ReadBytePack(byte_)),
+		mdk.WriteUint32Pack(mdk.ReadI32Pack(i32)),
+		mdk.WriteUint64Pack(mdk.ReadI64Pack(i64)),
+		mdk.WriteFloat32Pack(mdk.ReadF32Pack(f32)),
+		mdk.WriteFloat64Pack(mdk.ReadF64Pack(f64)),
+		mdk.WriteStringPack(mdk.ReadStringPack(str)),
+	)
+
+	mdk.FreePack(bytes, byte_, i32, i64, f32, f64, str)
+
+	return res
+}
+
+// readString returns the string, or the error of reading the pack as a string.
+//
+//go:wasmexport read_string
+func readString(pd mdk.PackedData) mdk.MultiPackedData {
+
+	defer mdk.FreePack(pd)
+
+	if vt := types.ValueType(uint64(pd) >> 56); vt != types.ValueTypeString {
+		return mdk.WriteMultiPack(mdk.WriteStringPack(fmt.Sprintf("expected %s, got %s", types.ValueTypeString, vt)))
+	}
+
+	return mdk.WriteMultiPack(mdk.WriteStringPack(mdk.ReadStringPack(pd)))
+}
+
+// logMessage logs the message at the level.
+//
+//go:wasmexport log_message
+func logMessage(msg, level mdk.PackedData) {
+
+	defer mdk.FreePack(msg, level)
+
+	switch value := mdk.ReadStringPack(msg); mdk.ReadBytePack(level) {
+	case 1:
+		mdk.LogDebug("%s", value)
+	case 2:
+		mdk.LogInfo("%s", value)
+	case 3:
+		mdk.LogWarning("%s", value)
+	default:
+		mdk.LogError("%s", value)
+	}
+}
@@ -0,0 +1,73 @@
+//! Conformance fixture of the Rust SDK, see the Conformance section of PROTOCOL.md.
+
+use wasify_mdk::*;
+
+/// Reads a value of every type and returns them back.
+#[no_mangle]
+pub extern "C" fn echo(
+    bytes: PackedData,
+    byte: PackedData,
+    i32: PackedData,
+    i64: PackedData,
+    f32: PackedData,
+    f64: PackedData,
+    string: PackedData,
+) -> MultiPackedData {
+    let (Ok(v1), Ok(v2), Ok(v3), Ok(v4), Ok(v5), Ok(v6), Ok(v7)) = (
+        read_bytes_pack(bytes),
+        read_byte_pack(byte),
+        read_u32_pack(i32),
+        read_u64_pack(i64),
+        read_f32_pack(f32),
+        read_f64_pack(f64),
+        read_string_pack(string),
+    ) else {
+        log_error("can't read params");
+        return MultiPackedData(0);
+    };
+
+    free_pack(&[bytes, byte, i32, i64, f32, f64, string]);
+
+    write_multi_pack(&[
+        write_bytes_pack(&v1),
+        write_byte_pack(v2),
+        write_u32_pack(v3),
+        write_u64_pack(v4),
+        write_f32_pack(v5),
+        write_f64_pack(v6),
+        write_string_pack(&v7),
+    ])
+}
+
+/// Returns the string, or the error of reading the pack as a string.
+#[no_mangle]
+pub extern "C" fn read_string(pd: PackedData) -> MultiPackedData {
+    let result = match read_string_pack(pd) {
+        Ok(s) => s,
+        Err(err) => err.to_string(),
+    };
+
+    free_pack(&[pd]);
+
+    write_multi_pack(&[write_string_pack(&result)])
+}
+
+/// Logs the message at the level.
+#[no_mangle]
+pub extern "C" fn log_message(msg: PackedData, level: PackedData) {
+    let (Ok(msg_value), Ok(level_value)) = (read_string_pack(msg), read_byte_pack(level)) else {
+        log_error("can't read params");
+        return;
+    };
+
+    free_pack(&[msg, level]);
+
+    let level = match level_value {
+        1 => Level::Debug,
+        2 => Level::Info,
+        3 => Level::Warning,
+        _ => Level::Error,
+    };
+
+    log(level, &msg_value);
+}
@@ -0,0 +1,16 @@
+// Built with Go, without cgo nor TinyGo:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -ldflags="-s -w" -o main.wasm .
+package main
+
+import "github.com/wasify-io/wasify-go/mdk"
+
+func main() {}
+
+//go:wasmexport greet
+func greet(name mdk.PackedData) mdk.MultiPackedData {
+	return mdk.WriteMultiPack(
+		mdk.WriteStringPack("Hello, "+mdk.ReadStringPack(name)),
+		mdk.WriteUint64Pack(uint64(len(mdk.ReadStringPack(name)))),
+	)
+}
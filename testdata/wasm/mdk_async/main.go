@@ -0,0 +1,84 @@
+// Built with Go, without cgo nor TinyGo:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -ldflags="-s -w" -o main.wasm .
+package main
+
+import (
+	"github.com/wasify-io/wasify-go/mdk"
+)
+
+//go:wasmimport mdk_async fetch
+func _fetch(mdk.PackedData) mdk.MultiPackedData
+
+// fetch starts fetching url on the host, and returns right away.
+func fetch(url string) *mdk.Future {
+	pd := mdk.WriteStringPack(url)
+	defer mdk.FreePack(pd)
+
+	return mdk.NewFuture(_fetch(pd))
+}
+
+// body reads the body a fetch completed with.
+func body(pds []mdk.PackedData) string {
+	defer mdk.FreePack(pds...)
+
+	return mdk.ReadStringPack(pds[0])
+}
+
+func main() {}
+
+func init() {
+	// fetch_all fetches the urls concurrently, and waits for all of them.
+	mdk.Export("fetch_all", func(a, b string) (string, error) {
+		futures := []*mdk.Future{fetch(a), fetch(b)}
+
+		var bodies string
+		for i, f := range futures {
+			pds, err := f.Await()
+			if err != nil {
+				for _, f := range futures[i+1:] {
+					f.Drop()
+				}
+				return "", err
+			}
+			bodies += body(pds) + "\n"
+		}
+
+		return bodies, nil
+	})
+
+	// fetch_poll polls the future until it's completed, and returns the number of polls of a pending future.
+	mdk.Export("fetch_poll", func(url string) (string, uint32, error) {
+		f := fetch(url)
+
+		var pending uint32
+		for {
+			pds, done, err := f.Poll()
+			if err != nil {
+				return "", pending, err
+			}
+			if done {
+				return body(pds), pending, nil
+			}
+			pending++
+		}
+	})
+
+	mdk.Export("fetch_drop", func(url string) {
+		fetch(url).Drop()
+	})
+
+	// await_twice reads the future a second time, which fails.
+	mdk.Export("await_twice", func(url string) error {
+		f := fetch(url)
+
+		pds, err := f.Await()
+		if err != nil {
+			return err
+		}
+		mdk.FreePack(pds...)
+
+		_, err = f.Await()
+		return err
+	})
+}
@@ -0,0 +1,46 @@
+// Built with Go, without cgo nor TinyGo:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -ldflags="-s -w" -o main.wasm .
+package main
+
+import (
+	"errors"
+
+	"github.com/wasify-io/wasify-go/mdk"
+)
+
+//go:wasmimport mdk_events handled
+func _handled(mdk.PackedData, mdk.PackedData)
+
+// handle reports the event to the host, through the handled host function.
+func handle(topic string) func(payload []byte) error {
+	return func(payload []byte) error {
+		if string(payload) == "invalid" {
+			return errors.New("invalid payload")
+		}
+
+		pds := []mdk.PackedData{mdk.WriteStringPack(topic), mdk.WriteBytesPack(payload)}
+		defer mdk.FreePack(pds...)
+
+		_handled(pds[0], pds[1])
+
+		return nil
+	}
+}
+
+func main() {}
+
+func init() {
+	// Modules without an event bus can't subscribe.
+	if err := mdk.Subscribe("greeting", handle("greeting")); err != nil {
+		mdk.LogDebug("can't subscribe: %s", err)
+	}
+
+	mdk.Export("subscribe", func(topic string) error {
+		return mdk.Subscribe(topic, handle(topic))
+	})
+
+	mdk.Export("unsubscribe", func(topic string) {
+		mdk.Unsubscribe(topic)
+	})
+}
@@ -0,0 +1,40 @@
+// Built with Go, without cgo nor TinyGo:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -ldflags="-s -w" -o main.wasm .
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/wasify-io/wasify-go/mdk"
+)
+
+type Celsius float64
+
+func main() {}
+
+func init() {
+	mdk.Export("greet", func(name string, times uint32) (string, error) {
+		if name == "" {
+			return "", errors.New("name is empty")
+		}
+		return strings.Repeat("Hello, "+name+"! ", int(times)), nil
+	})
+
+	mdk.Export("reverse", func(data []byte) []byte {
+		slices.Reverse(data)
+		return data
+	})
+
+	mdk.Export("fahrenheit", func(c Celsius) (float64, byte) {
+		return float64(c)*9/5 + 32, 1
+	})
+
+	mdk.Export("noop", func() {})
+
+	mdk.Export("crash", func() uint64 {
+		panic("boom")
+	})
+}
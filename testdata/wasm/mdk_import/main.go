@@ -0,0 +1,41 @@
+// Built with Go, without cgo nor TinyGo:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -ldflags="-s -w" -o main.wasm .
+package main
+
+import (
+	"github.com/wasify-io/wasify-go/mdk"
+)
+
+type Celsius float64
+
+//go:wasmimport mdk_import greet
+func _greet(mdk.PackedData, mdk.PackedData) mdk.MultiPackedData
+
+//go:wasmimport mdk_import record
+func _record(mdk.PackedData)
+
+//go:wasmimport mdk_import measure
+func _measure() mdk.MultiPackedData
+
+var (
+	greet   = mdk.Import[func(name string, times uint32) (string, error)](_greet)
+	record  = mdk.Import[func(data []byte) error](_record)
+	measure = mdk.Import[func() (Celsius, error)](_measure)
+)
+
+func main() {}
+
+func init() {
+	mdk.Export("greet", greet)
+
+	mdk.Export("record", func(data []byte) error {
+		return record(data)
+	})
+
+	// measure is imported as returning a float64, the host returns a string instead.
+	mdk.Export("measure", func() (float64, error) {
+		c, err := measure()
+		return float64(c), err
+	})
+}
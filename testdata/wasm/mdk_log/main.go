@@ -0,0 +1,22 @@
+// Built with Go, without cgo nor TinyGo:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -ldflags="-s -w" -o main.wasm .
+package main
+
+import (
+	"github.com/wasify-io/wasify-go/mdk"
+)
+
+func main() {}
+
+func init() {
+	// log writes a record through the log host function, with the level of the mdk function.
+	mdk.Export("log", func(msg string) {
+		mdk.LogWarning("guest says %s", msg)
+	})
+
+	// slog writes a structured record through the slog host function.
+	mdk.Export("slog", func(msg string) {
+		mdk.NewLogger("billing").With("tenant", "acme").Error(msg, "items", 3)
+	})
+}
@@ -0,0 +1,92 @@
+package wasify
+
+import (
+	"context"
+
+	"github.com/wasify-io/wasify-go/internal/utils"
+)
+
+// CallKind distinguishes guest function invocations from host function callbacks.
+type CallKind uint8
+
+const (
+	// CallGuestFunction is a call from the host into a function exported by the guest, see GuestFunction.Invoke.
+	CallGuestFunction CallKind = iota
+	// CallHostFunction is a call from the guest into a HostFunction.
+	CallHostFunction
+)
+
+func (k CallKind) String() string {
+	switch k {
+	case CallGuestFunction:
+		return "guest"
+	case CallHostFunction:
+		return "host"
+	}
+
+	return "undefined"
+}
+
+// CallInfo describes a guest or host function call.
+type CallInfo struct {
+	Kind CallKind
+
+	// Namespace of the module the call belongs to.
+	// For host functions, it's the namespace the function is imported from, e.g. WASIFY_NAMESPACE.
+	Namespace string
+
+	// Function is the name of the guest or host function.
+	Function string
+
+	// ArgsSize is the total size in bytes of the data referenced by the arguments.
+	ArgsSize uint64
+}
+
+// Tracer creates a span for every GuestFunction.Invoke and every host function callback.
+// See the github.com/wasify-io/wasify-go/otelwasify module for an OpenTelemetry implementation,
+// it's a module of its own so wasify doesn't depend on OpenTelemetry.
+//
+// The context returned by Start is passed down the call, so a host function called by the guest
+// during an Invoke receives the guest function's span as parent, and so on for nested calls.
+type Tracer interface {
+	// Start starts a span for the call and returns a context carrying it.
+	Start(ctx context.Context, call CallInfo) (context.Context, Span)
+
+	// TraceParent returns the W3C traceparent of the span carried by ctx, or an empty string.
+	// Guests read it through mdk.TraceParent to propagate the trace to their own outgoing requests.
+	TraceParent(ctx context.Context) string
+}
+
+// Span is a traced call started by a Tracer.
+type Span interface {
+	// End finishes the span. err is the error the call failed with, if any.
+	End(err error)
+}
+
+// noopTracer is used when no Tracer is configured.
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ CallInfo) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (noopTracer) TraceParent(context.Context) string {
+	return ""
+}
+
+type noopSpan struct{}
+
+func (noopSpan) End(error) {}
+
+// packedDataSize returns the total size of the data referenced by packed values.
+func packedDataSize(pds ...uint64) uint64 {
+
+	var total uint64
+
+	for _, pd := range pds {
+		_, _, size := utils.UnpackUI64(pd)
+		total += uint64(size)
+	}
+
+	return total
+}
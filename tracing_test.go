@@ -0,0 +1,127 @@
+package wasify_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go"
+)
+
+type testSpanKey struct{}
+
+// testTracer records calls and links every span to the span of its parent context.
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+type testSpan struct {
+	call   wasify.CallInfo
+	parent *testSpan
+	ended  bool
+}
+
+func (t *testTracer) Start(ctx context.Context, call wasify.CallInfo) (context.Context, wasify.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parent, _ := ctx.Value(testSpanKey{}).(*testSpan)
+	span := &testSpan{call: call, parent: parent}
+	t.spans = append(t.spans, span)
+
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func (t *testTracer) TraceParent(ctx context.Context) string {
+	span, _ := ctx.Value(testSpanKey{}).(*testSpan)
+	if span == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%s.%s", span.call.Namespace, span.call.Function)
+}
+
+func (s *testSpan) End(err error) {
+	s.ended = true
+}
+
+func TestTracer(t *testing.T) {
+
+	ctx := context.Background()
+
+	tracer := &testTracer{}
+
+	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+		Runtime: wasify.RuntimeWazero,
+		Tracer:  tracer,
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	var hostSpan any
+
+	module, err := runtime.NewModule(ctx, &wasify.ModuleConfig{
+		Namespace: "host_all_available_types",
+		Wasm: wasify.Wasm{
+			Binary: wasm_hostAllAvailableTypes,
+		},
+		HostFunctions: []wasify.HostFunction{
+			{
+				Name: "hostTest",
+				Callback: func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {
+					hostSpan = ctx.Value(testSpanKey{})
+					return 0
+				},
+				Params: []wasify.ValueType{
+					wasify.ValueTypeBytes,
+					wasify.ValueTypeByte,
+					wasify.ValueTypeI32,
+					wasify.ValueTypeI64,
+					wasify.ValueTypeF32,
+					wasify.ValueTypeF64,
+					wasify.ValueTypeString,
+				},
+				Results: []wasify.ValueType{wasify.ValueTypeString},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = module.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	_, err = module.GuestFunction(ctx, "guestTest").Invoke()
+	assert.NoError(t, err)
+
+	assert.Len(t, tracer.spans, 2)
+
+	guest, host := tracer.spans[0], tracer.spans[1]
+
+	assert.Equal(t, wasify.CallInfo{
+		Kind:      wasify.CallGuestFunction,
+		Namespace: "host_all_available_types",
+		Function:  "guestTest",
+	}, guest.call)
+	assert.Nil(t, guest.parent)
+	assert.True(t, guest.ended)
+
+	assert.Equal(t, wasify.CallHostFunction, host.call.Kind)
+	assert.Equal(t, "host_all_available_types", host.call.Namespace)
+	assert.Equal(t, "hostTest", host.call.Function)
+	// "Guest: Wello Wasify!" + byte + uint32 + uint64 + float32 + float64 + "Guest: Wasify."
+	assert.Equal(t, uint64(20+1+4+8+4+8+14), host.call.ArgsSize)
+	assert.Same(t, guest, host.parent)
+	assert.True(t, host.ended)
+
+	// The host callback runs within its own span.
+	assert.Same(t, host, hostSpan)
+}
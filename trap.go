@@ -0,0 +1,83 @@
+package wasify
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// Trap is the error a host function raises to abort the guest which called it,
+// e.g. when the guest passes invalid params, the host function panics or an Interceptor rejects the call.
+//
+// The guest execution stops immediately and the GuestFunction.Invoke which started it
+// returns an error wrapping the Trap, use errors.As to inspect it:
+//
+//	var trap *wasify.Trap
+//	if errors.As(err, &trap) {
+//		slog.Error("guest trapped", "host function", trap.Function, "cause", trap.Err)
+//	}
+type Trap struct {
+	// Namespace the host function is imported from.
+	Namespace string
+
+	// Function is the name of the host function.
+	Function string
+
+	// Err is the cause of the trap, e.g. a *ParamError or a *PanicError.
+	Err error
+}
+
+func (t *Trap) Error() string {
+	return fmt.Sprintf("host function %s.%s trapped: %v", t.Namespace, t.Function, t.Err)
+}
+
+func (t *Trap) Unwrap() error {
+	return t.Err
+}
+
+// newTrap logs the cause of the trap, with the host stack if the host function panicked, and returns the trap.
+func newTrap(moduleConfig *ModuleConfig, namespace string, function string, err error) *Trap {
+
+	trap := &Trap{
+		Namespace: namespace,
+		Function:  function,
+		Err:       err,
+	}
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		moduleConfig.log.Error(trap.Error(), "func", function, "stack", string(panicErr.Stack))
+	} else {
+		moduleConfig.log.Error(trap.Error(), "func", function)
+	}
+
+	return trap
+}
+
+// PanicError is the cause of a Trap raised by a host function which panicked.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+
+	// Stack is the host goroutine stack at the time of the panic.
+	Stack []byte
+}
+
+// newPanicError must be called from the deferred function which recovered the panic,
+// so the stack still contains the frames which panicked.
+func newPanicError(recovered any) *PanicError {
+	return &PanicError{
+		Value: recovered,
+		Stack: debug.Stack(),
+	}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
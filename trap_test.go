@@ -0,0 +1,93 @@
+package wasify_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go"
+)
+
+func TestHostFunctionPanic(t *testing.T) {
+
+	ctx := context.Background()
+
+	runtime, err := wasify.NewRuntime(ctx, &wasify.RuntimeConfig{
+		Runtime:     wasify.RuntimeWazero,
+		LogSeverity: wasify.LogError,
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = runtime.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	var interceptedErr error
+	shouldPanic := true
+
+	module, err := runtime.NewModule(ctx, &wasify.ModuleConfig{
+		Namespace: "host_all_available_types",
+		Wasm: wasify.Wasm{
+			Binary: wasm_hostAllAvailableTypes,
+		},
+		Interceptors: []wasify.Interceptor{
+			func(ctx context.Context, call *wasify.Call, next wasify.Invoker) (wasify.MultiPackedData, error) {
+				results, err := next(ctx, call)
+				if call.Kind == wasify.CallHostFunction {
+					interceptedErr = err
+				}
+				return results, err
+			},
+		},
+		HostFunctions: []wasify.HostFunction{
+			{
+				Name: "hostTest",
+				Callback: func(ctx context.Context, m *wasify.ModuleProxy, params []wasify.PackedData) wasify.MultiPackedData {
+					if shouldPanic {
+						panic("boom")
+					}
+					return 0
+				},
+				Params: []wasify.ValueType{
+					wasify.ValueTypeBytes,
+					wasify.ValueTypeByte,
+					wasify.ValueTypeI32,
+					wasify.ValueTypeI64,
+					wasify.ValueTypeF32,
+					wasify.ValueTypeF64,
+					wasify.ValueTypeString,
+				},
+				Results: []wasify.ValueType{wasify.ValueTypeString},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	defer func() {
+		err = module.Close(ctx)
+		assert.NoError(t, err)
+	}()
+
+	_, err = module.GuestFunction(ctx, "guestTest").Invoke()
+	assert.Error(t, err)
+
+	var trap *wasify.Trap
+	assert.True(t, errors.As(err, &trap))
+	assert.Equal(t, "host_all_available_types", trap.Namespace)
+	assert.Equal(t, "hostTest", trap.Function)
+
+	var panicErr *wasify.PanicError
+	assert.True(t, errors.As(err, &panicErr))
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.Contains(t, string(panicErr.Stack), "TestHostFunctionPanic")
+
+	// Interceptors see the panic as an error.
+	assert.ErrorAs(t, interceptedErr, &panicErr)
+
+	// The module is still usable.
+	shouldPanic = false
+	_, err = module.GuestFunction(ctx, "guestTest").Invoke()
+	assert.NoError(t, err)
+}
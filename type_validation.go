@@ -0,0 +1,82 @@
+package wasify
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/wasify-io/wasify-go/internal/types"
+)
+
+// TypeValidation sets how the host reacts when the ValueType of a PackedData
+// doesn't match the expected one, e.g. when reading a ValueTypeString pack with Memory.ReadUint32Pack,
+// or when the guest passes a ValueTypeBytes pack for a ValueTypeString param of a HostFunction.
+type TypeValidation uint8
+
+const (
+	// TypeValidationLenient logs mismatched packs and reads the data as the expected type,
+	// like wasify did before packs were validated. The first mismatch of a function and value type is logged as a warning,
+	// the next ones at debug level. It's the default, so loosely declared HostFunction params keep working.
+	TypeValidationLenient TypeValidation = iota
+
+	// TypeValidationStrict rejects mismatched packs with a *TypeMismatchError.
+	// For HostFunction params, the guest is aborted with a Trap.
+	TypeValidationStrict
+)
+
+// String returns the name of the value type, e.g. "ValueTypeString".
+func (v ValueType) String() string {
+	return types.ValueType(v).String()
+}
+
+// TypeMismatchError is returned when the ValueType of a PackedData doesn't match the expected one.
+type TypeMismatchError struct {
+	Expected ValueType
+	Actual   ValueType
+}
+
+func (e *TypeMismatchError) Error() string {
+	return fmt.Sprintf("packed data type mismatch, expected %s, got %s", e.Expected, e.Actual)
+}
+
+// typeWarnings are the mismatches lenient type validation has let through, so a guest passing the wrong type
+// in a loop doesn't flood the log: only the first mismatch of a function and value type is logged as a warning.
+type typeWarnings struct {
+	logged sync.Map
+}
+
+// typeWarning is a mismatch of typeWarnings. function is empty for packs read from Memory.
+type typeWarning struct {
+	function string
+	expected ValueType
+	actual   ValueType
+}
+
+// check checks the actual ValueType of a pack against the expected one, according to validation.
+// function is the host function the pack is a param of, if any.
+func (w *typeWarnings) check(validation TypeValidation, log *slog.Logger, function string, actual ValueType, expected ValueType) error {
+
+	if actual == expected {
+		return nil
+	}
+
+	err := &TypeMismatchError{Expected: expected, Actual: actual}
+
+	if validation != TypeValidationLenient {
+		return err
+	}
+
+	var args []any
+	if function != "" {
+		args = append(args, "function", function)
+	}
+
+	_, logged := w.logged.LoadOrStore(typeWarning{function, expected, actual}, struct{}{})
+	if logged {
+		log.Debug(err.Error(), args...)
+	} else {
+		log.Warn(err.Error()+", the next mismatches of these types are logged at debug level", args...)
+	}
+
+	return nil
+}
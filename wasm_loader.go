@@ -0,0 +1,222 @@
+package wasify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/wasify-io/wasify-go/internal/utils"
+)
+
+// Media types of a wasm layer in an OCI artifact.
+const (
+	OCIMediaTypeWasmLayer       = "application/vnd.wasm.content.layer.v1+wasm"
+	OCIMediaTypeModuleWasmLayer = "application/vnd.module.wasm.content.layer.v1+wasm"
+)
+
+const (
+	ociIndexFile              = "index.json"
+	ociRefNameAnnotation      = "org.opencontainers.image.ref.name"
+	ociMediaTypeImageManifest = "application/vnd.oci.image.manifest.v1+json"
+	ociDigestAlgorithmSHA256  = "sha256"
+)
+
+// LoadWasmFile reads a wasm binary from the file at path.
+//
+// hash is optional. If provided, it's a hex-encoded SHA-256 digest of the binary,
+// optionally prefixed with "sha256:", and the binary is rejected if it doesn't match.
+// The returned Wasm carries the hash, so it's checked again by NewModule.
+func LoadWasmFile(path string, hash string) (Wasm, error) {
+
+	binary, err := os.ReadFile(path)
+	if err != nil {
+		return Wasm{}, errors.Join(fmt.Errorf("can't read wasm file %s", path), err)
+	}
+
+	return newLoadedWasm(binary, hash)
+}
+
+// LoadWasmFS reads a wasm binary named name from the file system fsys.
+// This allows loading modules from a plugin directory (os.DirFS) or an embed.FS.
+//
+// hash is optional, see LoadWasmFile.
+func LoadWasmFS(fsys fs.FS, name string, hash string) (Wasm, error) {
+
+	binary, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return Wasm{}, errors.Join(fmt.Errorf("can't read wasm file %s", name), err)
+	}
+
+	return newLoadedWasm(binary, hash)
+}
+
+// LoadWasmReader reads a wasm binary from r until EOF.
+//
+// hash is optional, see LoadWasmFile.
+func LoadWasmReader(r io.Reader, hash string) (Wasm, error) {
+
+	binary, err := io.ReadAll(r)
+	if err != nil {
+		return Wasm{}, errors.Join(errors.New("can't read wasm"), err)
+	}
+
+	return newLoadedWasm(binary, hash)
+}
+
+// LoadWasmOCI reads a wasm binary from an OCI image layout directory on disk,
+// e.g. created by "oras copy --to-oci-layout" or "skopeo copy oci:".
+//
+// ref selects the manifest by its "org.opencontainers.image.ref.name" annotation (the tag).
+// If ref is empty, the layout must contain exactly one manifest.
+// The wasm layer is the layer with a wasm media type, or the only layer of the manifest.
+//
+// Digests of the manifest and the layer are always verified against their content.
+// hash is optional, see LoadWasmFile.
+func LoadWasmOCI(layoutDir string, ref string, hash string) (Wasm, error) {
+
+	fsys := os.DirFS(layoutDir)
+
+	var index ociIndex
+	if err := readOCIJSON(fsys, ociIndexFile, &index); err != nil {
+		return Wasm{}, err
+	}
+
+	var manifests []ociDescriptor
+	for _, m := range index.Manifests {
+		if ref == "" || m.Annotations[ociRefNameAnnotation] == ref {
+			manifests = append(manifests, m)
+		}
+	}
+
+	if len(manifests) != 1 {
+		return Wasm{}, fmt.Errorf("can't find manifest %q in OCI layout %s, found %d matching manifests", ref, layoutDir, len(manifests))
+	}
+
+	if manifests[0].MediaType != "" && manifests[0].MediaType != ociMediaTypeImageManifest {
+		return Wasm{}, fmt.Errorf("unsupported OCI manifest media type %s", manifests[0].MediaType)
+	}
+
+	manifestData, err := readOCIBlob(fsys, manifests[0])
+	if err != nil {
+		return Wasm{}, err
+	}
+
+	var manifest ociManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return Wasm{}, errors.Join(errors.New("can't decode OCI manifest"), err)
+	}
+
+	layer, err := manifest.wasmLayer()
+	if err != nil {
+		return Wasm{}, err
+	}
+
+	binary, err := readOCIBlob(fsys, layer)
+	if err != nil {
+		return Wasm{}, err
+	}
+
+	return newLoadedWasm(binary, hash)
+}
+
+// newLoadedWasm checks the binary against the optional hash and returns a Wasm pinned to it.
+func newLoadedWasm(binary []byte, hash string) (Wasm, error) {
+
+	hash = strings.TrimPrefix(hash, ociDigestAlgorithmSHA256+":")
+
+	if hash != "" {
+		actualHash, err := utils.CalculateHash(binary)
+		if err != nil {
+			return Wasm{}, errors.Join(errors.New("can't calculate the hash"), err)
+		}
+
+		if err := utils.CompareHashes(hash, actualHash); err != nil {
+			return Wasm{}, err
+		}
+	}
+
+	return Wasm{
+		Binary: binary,
+		Hash:   hash,
+	}, nil
+}
+
+// ociDescriptor references a blob in an OCI layout by its digest.
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociIndex struct {
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+type ociManifest struct {
+	Layers []ociDescriptor `json:"layers"`
+}
+
+// wasmLayer picks the layer holding the wasm binary.
+func (m *ociManifest) wasmLayer() (ociDescriptor, error) {
+
+	for _, l := range m.Layers {
+		if l.MediaType == OCIMediaTypeWasmLayer || l.MediaType == OCIMediaTypeModuleWasmLayer {
+			return l, nil
+		}
+	}
+
+	if len(m.Layers) == 1 {
+		return m.Layers[0], nil
+	}
+
+	return ociDescriptor{}, fmt.Errorf("can't find wasm layer in OCI manifest with %d layers", len(m.Layers))
+}
+
+func readOCIJSON(fsys fs.FS, name string, v any) error {
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return errors.Join(fmt.Errorf("can't read OCI %s", name), err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return errors.Join(fmt.Errorf("can't decode OCI %s", name), err)
+	}
+
+	return nil
+}
+
+// readOCIBlob reads the blob referenced by the descriptor and verifies its size and digest.
+func readOCIBlob(fsys fs.FS, desc ociDescriptor) ([]byte, error) {
+
+	algorithm, digest, ok := strings.Cut(desc.Digest, ":")
+	if !ok || algorithm != ociDigestAlgorithmSHA256 || digest == "" || strings.ContainsAny(digest, "/.") {
+		return nil, fmt.Errorf("unsupported OCI digest %q", desc.Digest)
+	}
+
+	data, err := fs.ReadFile(fsys, path.Join("blobs", algorithm, digest))
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("can't read OCI blob %s", desc.Digest), err)
+	}
+
+	if desc.Size != 0 && desc.Size != int64(len(data)) {
+		return nil, fmt.Errorf("OCI blob %s size mismatch, expected %d, got %d", desc.Digest, desc.Size, len(data))
+	}
+
+	actualDigest, err := utils.CalculateHash(data)
+	if err != nil {
+		return nil, errors.Join(errors.New("can't calculate the hash"), err)
+	}
+
+	if err := utils.CompareHashes(digest, actualDigest); err != nil {
+		return nil, errors.Join(fmt.Errorf("OCI blob %s is corrupted", desc.Digest), err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,107 @@
+package wasify_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wasify-io/wasify-go"
+)
+
+func TestLoadWasm(t *testing.T) {
+
+	binary := wasm_hostAllAvailableTypes
+	sum := sha256.Sum256(binary)
+	hash := hex.EncodeToString(sum[:])
+
+	t.Run("file", func(t *testing.T) {
+		wasm, err := wasify.LoadWasmFile("testdata/wasm/host_all_available_types/main.wasm", hash)
+		assert.NoError(t, err)
+		assert.Equal(t, binary, wasm.Binary)
+		assert.Equal(t, hash, wasm.Hash)
+
+		_, err = wasify.LoadWasmFile("testdata/wasm/host_all_available_types/main.wasm", "invalid")
+		assert.Error(t, err)
+
+		_, err = wasify.LoadWasmFile("testdata/wasm/missing.wasm", "")
+		assert.Error(t, err)
+	})
+
+	t.Run("fs", func(t *testing.T) {
+		fsys := fstest.MapFS{"plugins/example.wasm": {Data: binary}}
+
+		wasm, err := wasify.LoadWasmFS(fsys, "plugins/example.wasm", "sha256:"+hash)
+		assert.NoError(t, err)
+		assert.Equal(t, binary, wasm.Binary)
+		assert.Equal(t, hash, wasm.Hash)
+	})
+
+	t.Run("reader", func(t *testing.T) {
+		wasm, err := wasify.LoadWasmReader(bytes.NewReader(binary), "")
+		assert.NoError(t, err)
+		assert.Equal(t, binary, wasm.Binary)
+		assert.Empty(t, wasm.Hash)
+	})
+
+	t.Run("oci layout", func(t *testing.T) {
+		dir := t.TempDir()
+
+		writeBlob := func(data []byte) (string, int) {
+			sum := sha256.Sum256(data)
+			digest := hex.EncodeToString(sum[:])
+			assert.NoError(t, os.MkdirAll(filepath.Join(dir, "blobs", "sha256"), 0o755))
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "blobs", "sha256", digest), data, 0o644))
+			return "sha256:" + digest, len(data)
+		}
+
+		configDigest, configSize := writeBlob([]byte("{}"))
+		layerDigest, layerSize := writeBlob(binary)
+
+		manifest, err := json.Marshal(map[string]any{
+			"schemaVersion": 2,
+			"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+			"config":        map[string]any{"mediaType": "application/vnd.wasm.config.v0+json", "digest": configDigest, "size": configSize},
+			"layers": []map[string]any{
+				{"mediaType": wasify.OCIMediaTypeWasmLayer, "digest": layerDigest, "size": layerSize},
+			},
+		})
+		assert.NoError(t, err)
+		manifestDigest, manifestSize := writeBlob(manifest)
+
+		index, err := json.Marshal(map[string]any{
+			"schemaVersion": 2,
+			"manifests": []map[string]any{
+				{
+					"mediaType":   "application/vnd.oci.image.manifest.v1+json",
+					"digest":      manifestDigest,
+					"size":        manifestSize,
+					"annotations": map[string]string{"org.opencontainers.image.ref.name": "v1.0.0"},
+				},
+			},
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "index.json"), index, 0o644))
+
+		wasm, err := wasify.LoadWasmOCI(dir, "v1.0.0", hash)
+		assert.NoError(t, err)
+		assert.Equal(t, binary, wasm.Binary)
+
+		wasm, err = wasify.LoadWasmOCI(dir, "", "")
+		assert.NoError(t, err)
+		assert.Equal(t, binary, wasm.Binary)
+
+		_, err = wasify.LoadWasmOCI(dir, "v2.0.0", "")
+		assert.Error(t, err)
+
+		// Corrupt the wasm layer, the digest no longer matches.
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "blobs", "sha256", layerDigest[len("sha256:"):]), append([]byte{0xff}, binary[1:]...), 0o644))
+		_, err = wasify.LoadWasmOCI(dir, "v1.0.0", "")
+		assert.ErrorContains(t, err, "corrupted")
+	})
+}